@@ -0,0 +1,45 @@
+package main
+
+import (
+	"go/build"
+	"path/filepath"
+	"strings"
+)
+
+// buildTags are the extra build tags files are matched against (-tags), so
+// methods declared only in tagged files are generated only when the binary
+// is built with those tags
+var buildTags tagsFlag
+
+// tagsFlag parses a comma-separated tag list, as go build -tags does
+type tagsFlag []string
+
+func (t *tagsFlag) String() string {
+	return strings.Join(*t, ",")
+}
+
+func (t *tagsFlag) Set(value string) error {
+	*t = nil
+	for _, tag := range strings.Split(value, ",") {
+		if tag = strings.TrimSpace(tag); tag != "" {
+			*t = append(*t, tag)
+		}
+	}
+	return nil
+}
+
+// buildContext matches files the way the device build does: Strux devices run
+// Linux, so _linux.go files and //go:build linux constraints are included
+// whatever the host is
+func buildContext() build.Context {
+	ctx := build.Default
+	ctx.GOOS = "linux"
+	ctx.BuildTags = append([]string(nil), buildTags...)
+	return ctx
+}
+
+// matchesBuild reports whether the file at path is part of the build under
+// buildContext, by its //go:build line and _GOOS/_GOARCH name suffixes
+func matchesBuild(ctx *build.Context, path string) (bool, error) {
+	return ctx.MatchFile(filepath.Dir(path), filepath.Base(path))
+}
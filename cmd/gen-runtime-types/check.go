@@ -0,0 +1,32 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+)
+
+// checkOutput compares generated output with the file at path, like gofmt -l.
+// When they differ it prints the path and a unified diff to stdout and reports stale.
+func checkOutput(path string, generated []byte) (stale bool, err error) {
+	existing, err := os.ReadFile(path)
+	if err != nil {
+		return false, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+
+	if bytes.Equal(existing, generated) {
+		return false, nil
+	}
+
+	fmt.Println(path)
+
+	// Show what regeneration would change; fall back silently if diff is unavailable
+	cmd := exec.Command("diff", "-u", path, "-")
+	cmd.Stdin = bytes.NewReader(generated)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	_ = cmd.Run()
+
+	return true, nil
+}
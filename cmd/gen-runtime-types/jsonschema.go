@@ -0,0 +1,172 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"strings"
+
+	"github.com/strux-dev/strux/internal/typestr"
+)
+
+// schemaBuilder converts Go type strings to JSON Schema, collecting
+// the struct definitions it references into $defs
+type schemaBuilder struct {
+	structs map[string]StructDef
+	defs    map[string]any
+}
+
+// outputJSONSchema emits a JSON Schema document describing every extension method.
+// Each method is a property keyed by its full IPC name (e.g. "strux.boot.Reboot")
+// with "params" and "result" sub-schemas; structs are shared through $defs.
+func outputJSONSchema(w io.Writer, extensions []ExtensionInfo, structs map[string]StructDef) {
+	b := &schemaBuilder{
+		structs: structs,
+		defs:    make(map[string]any),
+	}
+
+	methods := make(map[string]any)
+	for _, ext := range extensions {
+		for _, method := range ext.Methods {
+			name := ext.Namespace + "." + ext.SubNamespace + "." + method.Name
+			methods[name] = b.methodSchema(method)
+		}
+	}
+
+	schema := map[string]any{
+		"$schema":    "https://json-schema.org/draft/2020-12/schema",
+		"$id":        "strux-runtime",
+		"title":      "Strux Runtime API",
+		"type":       "object",
+		"properties": methods,
+	}
+	if len(b.defs) > 0 {
+		schema["$defs"] = b.defs
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	encoder.Encode(schema)
+}
+
+// methodSchema describes a method's positional parameters and its result.
+// Optional parameters may be left off, and a variadic accepts any number of
+// trailing values.
+func (b *schemaBuilder) methodSchema(method MethodInfo) map[string]any {
+	items := make([]any, 0, len(method.Params))
+	required := 0
+	var variadic map[string]any
+	for _, p := range method.Params {
+		if p.Variadic {
+			variadic = b.typeSchema(strings.TrimPrefix(p.GoType, "..."))
+			continue
+		}
+		items = append(items, b.typeSchema(p.GoType))
+		if !p.Optional {
+			required = len(items)
+		}
+	}
+
+	params := map[string]any{
+		"type":     "array",
+		"minItems": required,
+	}
+	if len(items) > 0 {
+		params["prefixItems"] = items
+	}
+	if variadic != nil {
+		params["items"] = variadic
+	} else {
+		params["maxItems"] = len(items)
+	}
+
+	result := map[string]any{"type": "null"}
+	if method.ReturnGoType != "" {
+		result = b.typeSchema(method.ReturnGoType)
+		if method.HasError {
+			result = nullable(result)
+		}
+	}
+
+	return map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"params": params,
+			"result": result,
+		},
+	}
+}
+
+// typeSchema maps a Go type string to a JSON Schema fragment,
+// following the same rules as goTypeToTS
+func (b *schemaBuilder) typeSchema(goType string) map[string]any {
+	switch goType {
+	case "string":
+		return map[string]any{"type": "string"}
+	case "int", "int8", "int16", "int32", "int64",
+		"uint", "uint8", "uint16", "uint32", "uint64":
+		return map[string]any{"type": "integer"}
+	case "float32", "float64":
+		return map[string]any{"type": "number"}
+	case "bool":
+		return map[string]any{"type": "boolean"}
+	case "[]byte":
+		// encoding/json marshals byte slices as base64 strings
+		return map[string]any{"type": "string", "contentEncoding": "base64"}
+	}
+
+	if strings.HasPrefix(goType, "[]") {
+		return map[string]any{"type": "array", "items": b.typeSchema(goType[2:])}
+	}
+	if strings.HasPrefix(goType, "map[") {
+		_, valueType := typestr.ParseMap(goType)
+		return map[string]any{"type": "object", "additionalProperties": b.typeSchema(valueType)}
+	}
+	if strings.HasPrefix(goType, "*") {
+		return nullable(b.typeSchema(goType[1:]))
+	}
+	if def, ok := b.structs[goType]; ok {
+		if _, seen := b.defs[goType]; !seen {
+			// Reserve the name first so self-referencing structs terminate
+			b.defs[goType] = nil
+			b.defs[goType] = b.structSchema(def)
+		}
+		return map[string]any{"$ref": "#/$defs/" + goType}
+	}
+	if tsType, ok := typeMap[goType]; ok {
+		return tsTypeSchema(tsType)
+	}
+
+	// interface{}, unknown and external types accept any value
+	return map[string]any{}
+}
+
+// structSchema describes a struct as an object with one property per field
+func (b *schemaBuilder) structSchema(def StructDef) map[string]any {
+	properties := make(map[string]any)
+	for _, field := range def.Fields {
+		schema := b.typeSchema(field.GoType)
+		if field.Readonly {
+			schema["readOnly"] = true
+		}
+		properties[field.Name] = schema
+	}
+	return map[string]any{
+		"type":       "object",
+		"properties": properties,
+	}
+}
+
+// tsTypeSchema maps a -type-map target back to a schema when it is a primitive
+func tsTypeSchema(tsType string) map[string]any {
+	switch tsType {
+	case "string", "number", "boolean":
+		return map[string]any{"type": tsType}
+	default:
+		return map[string]any{}
+	}
+}
+
+// nullable allows null in addition to the given schema
+func nullable(schema map[string]any) map[string]any {
+	return map[string]any{"anyOf": []any{schema, map[string]any{"type": "null"}}}
+}
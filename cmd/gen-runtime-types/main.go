@@ -1,15 +1,21 @@
 package main
 
 import (
+	"bytes"
 	"encoding/json"
 	"flag"
 	"fmt"
 	"go/ast"
 	"go/parser"
 	"go/token"
+	"io"
 	"os"
 	"path/filepath"
+	"reflect"
+	"sort"
 	"strings"
+
+	"github.com/strux-dev/strux/internal/typestr"
 )
 
 // ExtensionInfo holds information about an extension
@@ -21,10 +27,11 @@ type ExtensionInfo struct {
 
 // MethodInfo holds information about a method
 type MethodInfo struct {
-	Name       string     `json:"name"`
-	Params     []ParamDef `json:"params"`
-	ReturnType string     `json:"returnType,omitempty"`
-	HasError   bool       `json:"hasError"`
+	Name         string     `json:"name"`
+	Params       []ParamDef `json:"params"`
+	ReturnType   string     `json:"returnType,omitempty"`
+	ReturnGoType string     `json:"-"`
+	HasError     bool       `json:"hasError"`
 }
 
 // ParamDef describes a method parameter
@@ -32,6 +39,23 @@ type ParamDef struct {
 	Name   string `json:"name"`
 	GoType string `json:"goType"`
 	TSType string `json:"tsType"`
+
+	// Optional marks a trailing pointer or variadic the caller may leave
+	// off, and Variadic a parameter rendered as a rest parameter
+	Optional bool `json:"optional,omitempty"`
+	Variadic bool `json:"variadic,omitempty"`
+}
+
+// FieldDef describes a struct field
+type FieldDef struct {
+	Name     string `json:"name"`
+	GoType   string `json:"goType"`
+	Readonly bool   `json:"readonly,omitempty"`
+}
+
+// StructDef describes a struct declared alongside the extensions
+type StructDef struct {
+	Fields []FieldDef `json:"fields"`
 }
 
 // RuntimeTypes is the output structure
@@ -40,40 +64,83 @@ type RuntimeTypes struct {
 }
 
 func main() {
-	outputFormat := flag.String("format", "ts", "Output format: ts (TypeScript), json")
+	outputFormat := flag.String("format", "ts", "Output format: ts (TypeScript), json, jsonschema")
 	extensionDir := flag.String("dir", "pkg/runtime/extension", "Directory containing extension Go files")
+	flag.Var(typeMap, "type-map", "Map a qualified Go type to a TypeScript type, as pkg.Type=tsType (repeatable)")
+	flag.Var(&buildTags, "tags", "Comma-separated build tags; files whose build constraints exclude them are skipped")
+	flag.Var(&namespaceFilter, "namespaces", "Comma-separated namespaces or sub-namespaces to generate (e.g. boot,display); default all")
+	flag.BoolVar(&brandedNumbers, "branded-numbers", false, "Generate integer Go types as the branded type Int instead of number")
+	outPath := flag.String("out", "", "Write output to this file instead of stdout")
+	check := flag.Bool("check", false, "Compare generated output with -out and exit non-zero with a diff if it is stale")
 	flag.Parse()
 
-	extensions, err := parseExtensions(*extensionDir)
+	if *check && *outPath == "" {
+		fmt.Fprintln(os.Stderr, "Error: -check requires -out")
+		os.Exit(2)
+	}
+
+	extensions, structs, err := parseExtensions(*extensionDir)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 		os.Exit(1)
 	}
+	extensions = filterNamespaces(extensions)
 
+	var out bytes.Buffer
 	switch *outputFormat {
 	case "json":
-		outputJSON(extensions)
+		outputJSON(&out, extensions)
+	case "jsonschema":
+		outputJSONSchema(&out, extensions, structs)
 	case "ts":
-		outputTypeScript(extensions)
+		outputTypeScript(&out, extensions)
 	default:
 		fmt.Fprintf(os.Stderr, "Unknown format: %s\n", *outputFormat)
 		os.Exit(1)
 	}
+
+	switch {
+	case *check:
+		stale, err := checkOutput(*outPath, out.Bytes())
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		if stale {
+			os.Exit(1)
+		}
+	case *outPath != "":
+		if err := os.WriteFile(*outPath, out.Bytes(), 0644); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+	default:
+		os.Stdout.Write(out.Bytes())
+	}
 }
 
-func parseExtensions(dir string) ([]ExtensionInfo, error) {
+func parseExtensions(dir string) ([]ExtensionInfo, map[string]StructDef, error) {
 	var extensions []ExtensionInfo
+	structs := make(map[string]StructDef)
 
-	// Maps to store extension metadata and methods
-	extensionMeta := make(map[string]struct{ namespace, subNamespace string }) // TypeName -> namespace info
-	methodsTypes := make(map[string][]MethodInfo)                              // TypeName -> methods
+	// Extension and Methods halves are keyed by their shared base name so they
+	// pair up regardless of which file or sub-package declares each half
+	extensionMeta := make(map[string]*extensionDecl) // base name -> namespace info
+	methodsTypes := make(map[string][]MethodInfo)    // base name -> methods
+	methodsDecls := make(map[string]string)          // base name -> Methods type name
 
-	// Parse all Go files in the directory
+	// Parse the Go files in the directory tree that the device build compiles
+	ctx := buildContext()
 	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
 		if err != nil {
 			return err
 		}
-		if info.IsDir() || !strings.HasSuffix(path, ".go") {
+		if info.IsDir() || !strings.HasSuffix(path, ".go") || strings.HasSuffix(path, "_test.go") {
+			return nil
+		}
+		if match, err := matchesBuild(&ctx, path); err != nil {
+			return fmt.Errorf("failed to read build constraints of %s: %w", path, err)
+		} else if !match {
 			return nil
 		}
 
@@ -82,8 +149,17 @@ func parseExtensions(dir string) ([]ExtensionInfo, error) {
 		if err != nil {
 			return fmt.Errorf("failed to parse %s: %w", path, err)
 		}
+		pkgName := node.Name.Name
 
 		ast.Inspect(node, func(n ast.Node) bool {
+			// Collect struct declarations for schema $defs
+			if typeSpec, ok := n.(*ast.TypeSpec); ok {
+				if structType, ok := typeSpec.Type.(*ast.StructType); ok && isExported(typeSpec.Name.Name) {
+					structs[typeSpec.Name.Name] = extractStruct(structType)
+				}
+				return true
+			}
+
 			// Look for method declarations
 			if funcDecl, ok := n.(*ast.FuncDecl); ok {
 				if funcDecl.Recv == nil || len(funcDecl.Recv.List) == 0 {
@@ -109,28 +185,30 @@ func parseExtensions(dir string) ([]ExtensionInfo, error) {
 				methodName := funcDecl.Name.Name
 
 				// Check if this is a Namespace() or SubNamespace() method on an Extension type
-				if methodName == "Namespace" && strings.HasSuffix(recvTypeName, "Extension") {
-					if retVal := extractStringReturn(funcDecl); retVal != "" {
-						meta := extensionMeta[recvTypeName]
-						meta.namespace = retVal
-						extensionMeta[recvTypeName] = meta
+				if (methodName == "Namespace" || methodName == "SubNamespace") && strings.HasSuffix(recvTypeName, "Extension") {
+					baseName := extensionBaseName(recvTypeName, "Extension", pkgName)
+					meta := extensionMeta[baseName]
+					if meta == nil {
+						meta = &extensionDecl{typeName: recvTypeName, path: path}
+						extensionMeta[baseName] = meta
 					}
-					return true
-				}
-
-				if methodName == "SubNamespace" && strings.HasSuffix(recvTypeName, "Extension") {
 					if retVal := extractStringReturn(funcDecl); retVal != "" {
-						meta := extensionMeta[recvTypeName]
-						meta.subNamespace = retVal
-						extensionMeta[recvTypeName] = meta
+						if methodName == "Namespace" {
+							meta.namespace = retVal
+						} else {
+							meta.subNamespace = retVal
+						}
 					}
 					return true
 				}
 
-				// Check if this is a method on a Methods type
-				if strings.HasSuffix(recvTypeName, "Methods") && isExported(methodName) {
+				// Check if this is a method on a Methods type. Close is the
+				// extension.Closer shutdown hook and is not exposed to JavaScript.
+				if strings.HasSuffix(recvTypeName, "Methods") && isExported(methodName) && methodName != "Close" {
+					baseName := extensionBaseName(recvTypeName, "Methods", pkgName)
 					method := extractMethod(funcDecl)
-					methodsTypes[recvTypeName] = append(methodsTypes[recvTypeName], method)
+					methodsTypes[baseName] = append(methodsTypes[baseName], method)
+					methodsDecls[baseName] = recvTypeName
 				}
 			}
 
@@ -141,22 +219,21 @@ func parseExtensions(dir string) ([]ExtensionInfo, error) {
 	})
 
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
 	// Match extensions with their methods
 	// Convention: BootExtension pairs with BootMethods
-	for extType, meta := range extensionMeta {
+	for baseName, meta := range extensionMeta {
 		if meta.namespace == "" || meta.subNamespace == "" {
 			continue
 		}
 
-		// Derive methods type name from extension type name
-		// BootExtension -> BootMethods
-		baseName := strings.TrimSuffix(extType, "Extension")
-		methodsTypeName := baseName + "Methods"
-
-		methods := methodsTypes[methodsTypeName]
+		methods, ok := methodsTypes[baseName]
+		if !ok {
+			fmt.Fprintf(os.Stderr, "Warning: %s (%s) has no matching %sMethods type\n",
+				meta.typeName, meta.path, strings.TrimSuffix(meta.typeName, "Extension"))
+		}
 
 		extensions = append(extensions, ExtensionInfo{
 			Namespace:    meta.namespace,
@@ -165,7 +242,82 @@ func parseExtensions(dir string) ([]ExtensionInfo, error) {
 		})
 	}
 
-	return extensions, nil
+	for baseName, typeName := range methodsDecls {
+		if _, ok := extensionMeta[baseName]; !ok {
+			fmt.Fprintf(os.Stderr, "Warning: %s has no matching Extension type\n", typeName)
+		}
+	}
+
+	// Keep output stable across runs
+	sort.Slice(extensions, func(i, j int) bool {
+		if extensions[i].Namespace != extensions[j].Namespace {
+			return extensions[i].Namespace < extensions[j].Namespace
+		}
+		return extensions[i].SubNamespace < extensions[j].SubNamespace
+	})
+
+	return extensions, structs, nil
+}
+
+// extensionDecl records where an Extension type was declared and its namespaces
+type extensionDecl struct {
+	typeName     string
+	path         string
+	namespace    string
+	subNamespace string
+}
+
+// extensionBaseName strips the Extension/Methods suffix from a type name.
+// Sub-packages that name their types plainly (storage.Extension, storage.Methods)
+// use the package name as the base.
+func extensionBaseName(typeName, suffix, pkgName string) string {
+	baseName := strings.TrimSuffix(typeName, suffix)
+	if baseName == "" {
+		return pkgName
+	}
+	return baseName
+}
+
+// extractStruct collects the exported fields of a struct declaration,
+// named the way encoding/json names them
+func extractStruct(structType *ast.StructType) StructDef {
+	def := StructDef{Fields: []FieldDef{}}
+	for _, field := range structType.Fields.List {
+		goType := exprToString(field.Type)
+
+		jsonName := ""
+		readonly := false
+		if field.Tag != nil {
+			tag := reflect.StructTag(strings.Trim(field.Tag.Value, "`"))
+			jsonName, _, _ = strings.Cut(tag.Get("json"), ",")
+			if jsonName == "-" {
+				continue
+			}
+			readonly = hasTagOption(tag.Get("strux"), "readonly")
+		}
+
+		for _, name := range field.Names {
+			if !isExported(name.Name) {
+				continue
+			}
+			fieldName := name.Name
+			if jsonName != "" {
+				fieldName = jsonName
+			}
+			def.Fields = append(def.Fields, FieldDef{Name: fieldName, GoType: goType, Readonly: readonly})
+		}
+	}
+	return def
+}
+
+// hasTagOption reports whether a comma-separated tag value contains option
+func hasTagOption(value, option string) bool {
+	for _, opt := range strings.Split(value, ",") {
+		if opt == option {
+			return true
+		}
+	}
+	return false
 }
 
 // extractStringReturn extracts the string return value from a simple return statement
@@ -223,8 +375,10 @@ func extractMethod(funcDecl *ast.FuncDecl) MethodInfo {
 		}
 	}
 
+	markOptional(params)
+
 	// Extract return type
-	var returnType string
+	var returnType, returnGoType string
 	hasError := false
 
 	if funcDecl.Type.Results != nil && len(funcDecl.Type.Results.List) > 0 {
@@ -238,32 +392,43 @@ func extractMethod(funcDecl *ast.FuncDecl) MethodInfo {
 		firstReturn := exprToString(results[0].Type)
 		if firstReturn != "error" {
 			returnType = goTypeToTS(firstReturn)
+			returnGoType = firstReturn
+
+			soleResult := len(results) == 1 && len(results[0].Names) <= 1 ||
+				len(results) == 2 && hasError && len(results[0].Names) <= 1
+			if soleResult && isBinaryGoType(firstReturn) {
+				returnType = "Uint8Array"
+			}
 		}
 	}
 
 	return MethodInfo{
-		Name:       methodName,
-		Params:     params,
-		ReturnType: returnType,
-		HasError:   hasError,
+		Name:         methodName,
+		Params:       params,
+		ReturnType:   returnType,
+		ReturnGoType: returnGoType,
+		HasError:     hasError,
 	}
 }
 
-func outputJSON(extensions []ExtensionInfo) {
+func outputJSON(w io.Writer, extensions []ExtensionInfo) {
 	output := RuntimeTypes{Extensions: extensions}
-	encoder := json.NewEncoder(os.Stdout)
+	encoder := json.NewEncoder(w)
 	encoder.SetIndent("", "  ")
 	encoder.Encode(output)
 }
 
-func outputTypeScript(extensions []ExtensionInfo) {
-	fmt.Println("// Auto-generated Strux Runtime API types")
-	fmt.Println("// Generated by: go run ./cmd/gen-runtime-types")
-	fmt.Println("// DO NOT EDIT - regenerate with: go run ./cmd/gen-runtime-types -format=ts > src/types/strux-runtime.ts")
-	fmt.Println()
+func outputTypeScript(w io.Writer, extensions []ExtensionInfo) {
+	fmt.Fprintln(w, "// Auto-generated Strux Runtime API types")
+	fmt.Fprintln(w, "// Generated by: go run ./cmd/gen-runtime-types")
+	fmt.Fprintln(w, "// DO NOT EDIT - regenerate with: go run ./cmd/gen-runtime-types -format=ts > src/types/strux-runtime.ts")
+	fmt.Fprintln(w)
 
 	// Build the interface string
 	var sb strings.Builder
+	if brandedNumbers {
+		sb.WriteString(intBrandDecl + "\n")
+	}
 
 	// Group extensions by namespace
 	namespaces := make(map[string][]ExtensionInfo)
@@ -271,8 +436,15 @@ func outputTypeScript(extensions []ExtensionInfo) {
 		namespaces[ext.Namespace] = append(namespaces[ext.Namespace], ext)
 	}
 
-	// Generate interface for each namespace
-	for namespace, exts := range namespaces {
+	// Generate interface for each namespace, in a stable order
+	names := make([]string, 0, len(namespaces))
+	for namespace := range namespaces {
+		names = append(names, namespace)
+	}
+	sort.Strings(names)
+
+	for _, namespace := range names {
+		exts := namespaces[namespace]
 		// Capitalize first letter for interface name
 		interfaceName := strings.ToUpper(namespace[:1]) + namespace[1:]
 
@@ -293,14 +465,21 @@ func outputTypeScript(extensions []ExtensionInfo) {
 		sb.WriteString("}\n")
 	}
 
-	// Output as exportable constant
-	fmt.Printf("export const STRUX_RUNTIME_TYPES = `// Strux Runtime API\n%s`;\n", sb.String())
+	// Output as exportable constant (no trailing semicolon, matching the lint style)
+	fmt.Fprintf(w, "export const STRUX_RUNTIME_TYPES = `// Strux Runtime API\n%s`\n", sb.String())
 }
 
 func formatParams(params []ParamDef) string {
 	var parts []string
 	for _, p := range params {
-		parts = append(parts, fmt.Sprintf("%s: %s", p.Name, p.TSType))
+		switch {
+		case p.Variadic:
+			parts = append(parts, fmt.Sprintf("...%s: %s", p.Name, p.TSType))
+		case p.Optional:
+			parts = append(parts, fmt.Sprintf("%s?: %s", p.Name, p.TSType))
+		default:
+			parts = append(parts, fmt.Sprintf("%s: %s", p.Name, p.TSType))
+		}
 	}
 	return strings.Join(parts, ", ")
 }
@@ -316,6 +495,22 @@ func formatReturnType(method MethodInfo) string {
 	return fmt.Sprintf("Promise<%s>", baseType)
 }
 
+// markOptional flags the parameters a caller may leave off: a trailing
+// variadic and the run of pointer parameters at the end of the list. A
+// pointer followed by a required parameter stays required, since only
+// trailing arguments can be omitted.
+func markOptional(params []ParamDef) {
+	i := len(params)
+	if i > 0 && strings.HasPrefix(params[i-1].GoType, "...") {
+		params[i-1].Optional = true
+		params[i-1].Variadic = true
+		i--
+	}
+	for ; i > 0 && strings.HasPrefix(params[i-1].GoType, "*"); i-- {
+		params[i-1].Optional = true
+	}
+}
+
 func exprToString(expr ast.Expr) string {
 	switch t := expr.(type) {
 	case *ast.Ident:
@@ -330,6 +525,8 @@ func exprToString(expr ast.Expr) string {
 		return exprToString(t.X) + "." + t.Sel.Name
 	case *ast.InterfaceType:
 		return "interface{}"
+	case *ast.Ellipsis:
+		return "..." + exprToString(t.Elt)
 	default:
 		return "unknown"
 	}
@@ -340,11 +537,17 @@ func goTypeToTS(goType string) string {
 	case "string":
 		return "string"
 	case "int", "int8", "int16", "int32", "int64",
-		"uint", "uint8", "uint16", "uint32", "uint64",
-		"float32", "float64":
+		"uint", "uint8", "uint16", "uint32", "uint64":
+		if brandedNumbers {
+			return "Int"
+		}
+		return "number"
+	case "float32", "float64":
 		return "number"
 	case "bool":
 		return "boolean"
+	case "[]byte":
+		return "string" // encoding/json uses base64
 	case "error":
 		return "Error"
 	case "interface{}":
@@ -354,15 +557,44 @@ func goTypeToTS(goType string) string {
 			return goTypeToTS(goType[2:]) + "[]"
 		}
 		if strings.HasPrefix(goType, "map[") {
-			return "Record<string, any>"
+			keyType, valueType := typestr.ParseMap(goType)
+			return fmt.Sprintf("Record<%s, %s>", mapKeyToTS(keyType), goTypeToTS(valueType))
 		}
 		if strings.HasPrefix(goType, "*") {
 			return goTypeToTS(goType[1:])
 		}
+		if strings.HasPrefix(goType, "...") {
+			return goTypeToTS(goType[3:]) + "[]"
+		}
+		if tsType, ok := typeMap[goType]; ok {
+			return tsType
+		}
 		return "any"
 	}
 }
 
+// mapKeyToTS converts a map key type; encoding/json writes integer keys as
+// decimal strings, which TypeScript indexes as numbers
+func mapKeyToTS(keyType string) string {
+	switch keyType {
+	case "int", "int8", "int16", "int32", "int64",
+		"uint", "uint8", "uint16", "uint32", "uint64":
+		return "number"
+	default:
+		return "string"
+	}
+}
+
+// isBinaryGoType reports whether a sole return value of this type is sent
+// through the bridge's binary path and surfaces as a Uint8Array
+func isBinaryGoType(goType string) bool {
+	switch goType {
+	case "[]byte", "io.Reader", "io.ReadCloser":
+		return true
+	}
+	return false
+}
+
 func isExported(name string) bool {
 	if len(name) == 0 {
 		return false
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"fmt"
+	"os"
+)
+
+// namespaceFilter restricts the extensions generated (-namespaces), so a
+// minimal frontend can ship only the types it uses. Each entry names a
+// namespace ("strux"), a sub-namespace ("boot") or both ("strux.boot").
+var namespaceFilter tagsFlag
+
+// filterNamespaces keeps the extensions namespaceFilter names, warning about
+// entries that match none. An empty filter keeps everything.
+func filterNamespaces(extensions []ExtensionInfo) []ExtensionInfo {
+	if len(namespaceFilter) == 0 {
+		return extensions
+	}
+
+	matched := make(map[string]bool, len(namespaceFilter))
+	var kept []ExtensionInfo
+	for _, ext := range extensions {
+		keep := false
+		for _, name := range namespaceFilter {
+			if name == ext.Namespace || name == ext.SubNamespace || name == ext.Namespace+"."+ext.SubNamespace {
+				matched[name] = true
+				keep = true
+			}
+		}
+		if keep {
+			kept = append(kept, ext)
+		}
+	}
+
+	for _, name := range namespaceFilter {
+		if !matched[name] {
+			fmt.Fprintf(os.Stderr, "Warning: namespace filter %q matches no extension\n", name)
+		}
+	}
+	return kept
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// typeMap maps qualified Go types (as rendered by exprToString, e.g. "uuid.UUID")
+// to TypeScript types. It is consulted before falling back to "any".
+var typeMap = typeMapFlag{
+	"time.Time":       "string",
+	"time.Duration":   "number",
+	"json.RawMessage": "any",
+}
+
+// brandedNumbers makes integer Go types generate the branded Int type
+// instead of plain number (-branded-numbers)
+var brandedNumbers bool
+
+// intBrandDecl declares the branded integer type used with -branded-numbers
+const intBrandDecl = "type Int = number & { __int: void };"
+
+// typeMapFlag collects repeated -type-map pkg.Type=tsType flags
+//
+// Example:
+//
+//	go run ./cmd/gen-runtime-types -type-map uuid.UUID=string -type-map decimal.Decimal=string
+type typeMapFlag map[string]string
+
+func (m typeMapFlag) String() string {
+	pairs := make([]string, 0, len(m))
+	for goType, tsType := range m {
+		pairs = append(pairs, goType+"="+tsType)
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, ",")
+}
+
+func (m typeMapFlag) Set(value string) error {
+	goType, tsType, ok := strings.Cut(value, "=")
+	goType = strings.TrimSpace(goType)
+	tsType = strings.TrimSpace(tsType)
+	if !ok || goType == "" || tsType == "" {
+		return fmt.Errorf("expected pkg.Type=tsType, got %q", value)
+	}
+	m[goType] = tsType
+	return nil
+}
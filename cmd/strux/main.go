@@ -2,12 +2,19 @@ package main
 
 import (
 	"encoding/json"
+	"flag"
 	"fmt"
 	"go/ast"
 	"go/parser"
 	"go/token"
 	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
 	"strings"
+	"unicode"
+
+	"github.com/strux-dev/strux/internal/typestr"
 )
 
 // IntrospectionOutput is the top-level JSON structure
@@ -32,9 +39,14 @@ type StructDef struct {
 
 // FieldDef describes a struct field
 type FieldDef struct {
-	Name   string `json:"name"`
-	GoType string `json:"goType"`
-	TSType string `json:"tsType"`
+	Name     string `json:"name"`
+	GoType   string `json:"goType"`
+	TSType   string `json:"tsType"`
+	Readonly bool   `json:"readonly,omitempty"`
+
+	// Default is the value of a strux:"default=..." tag, filled in by the
+	// dispatcher when the caller leaves the field out
+	Default *string `json:"default,omitempty"`
 }
 
 // MethodDef describes a method
@@ -50,6 +62,11 @@ type ParamDef struct {
 	Name   string `json:"name,omitempty"`
 	GoType string `json:"goType"`
 	TSType string `json:"tsType"`
+
+	// Optional marks a trailing pointer or variadic the caller may leave
+	// off, and Variadic a parameter rendered as a rest parameter
+	Optional bool `json:"optional,omitempty"`
+	Variadic bool `json:"variadic,omitempty"`
 }
 
 // TypeDef describes a type
@@ -59,28 +76,65 @@ type TypeDef struct {
 }
 
 func main() {
-	if len(os.Args) < 2 {
-		// Default to main.go in current directory
-		if err := introspect("main.go"); err != nil {
-			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
-			os.Exit(1)
-		}
-		return
+	methodCase := flag.String("case", "go", `method name case: "go" (GetItems) or "camel" (getItems)`)
+	flag.Parse()
+
+	if *methodCase != "go" && *methodCase != "camel" {
+		fmt.Fprintf(os.Stderr, "Error: -case must be \"go\" or \"camel\", got %q\n", *methodCase)
+		os.Exit(1)
 	}
 
-	filePath := os.Args[1]
-	if err := introspect(filePath); err != nil {
+	// Default to main.go in current directory
+	filePath := "main.go"
+	if flag.NArg() > 0 {
+		filePath = flag.Arg(0)
+	}
+	if err := introspect(filePath, *methodCase == "camel"); err != nil {
 		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 		os.Exit(1)
 	}
 }
 
-func introspect(filePath string) error {
+// typeMapFile, when present next to the introspected file, maps Go types
+// (as written in the source, e.g. "sql.NullString") to TypeScript types:
+//
+//	{"typeMap": {"sql.NullString": "string | null"}}
+const typeMapFile = "strux-types.json"
+
+// typeMap holds the mappings from typeMapFile; goTypeToTS consults it first
+var typeMap map[string]string
+
+// loadTypeMap reads typeMapFile from dir, if there is one
+func loadTypeMap(dir string) (map[string]string, error) {
+	data, err := os.ReadFile(filepath.Join(dir, typeMapFile))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var config struct {
+		TypeMap map[string]string `json:"typeMap"`
+	}
+	if err := json.Unmarshal(data, &config); err != nil {
+		return nil, fmt.Errorf("invalid %s: %w", typeMapFile, err)
+	}
+	return config.TypeMap, nil
+}
+
+func introspect(filePath string, camel bool) error {
 	// Check if file exists
 	if _, err := os.Stat(filePath); os.IsNotExist(err) {
 		return fmt.Errorf("%s not found", filePath)
 	}
 
+	mappings, err := loadTypeMap(filepath.Dir(filePath))
+	if err != nil {
+		return err
+	}
+	typeMap = mappings
+
 	// Parse the Go file
 	fset := token.NewFileSet()
 	node, err := parser.ParseFile(fset, filePath, nil, parser.ParseComments)
@@ -94,12 +148,16 @@ func introspect(filePath string) error {
 	// Collect all structs and their fields
 	structFields := make(map[string][]FieldDef)
 	knownStructs := make(map[string]bool)
+	namedTypes := make(map[string]string)
 
-	// First pass: discover all struct types
+	// First pass: discover all struct types, and the underlying types of
+	// other named types (type UserID string) so they resolve like their base
 	ast.Inspect(node, func(n ast.Node) bool {
 		if typeSpec, ok := n.(*ast.TypeSpec); ok {
 			if _, ok := typeSpec.Type.(*ast.StructType); ok {
 				knownStructs[typeSpec.Name.Name] = true
+			} else if typeSpec.TypeParams == nil {
+				namedTypes[typeSpec.Name.Name] = exprToString(typeSpec.Type)
 			}
 		}
 		return true
@@ -108,6 +166,7 @@ func introspect(filePath string) error {
 	// Second pass: extract struct fields and methods
 	var appStructName string
 	var methods []MethodDef
+	var aliases map[string]string
 
 	ast.Inspect(node, func(n ast.Node) bool {
 		// Find type declarations
@@ -124,9 +183,11 @@ func introspect(filePath string) error {
 						if isExported(fieldName) {
 							goType := exprToString(field.Type)
 							fields = append(fields, FieldDef{
-								Name:   fieldName,
-								GoType: goType,
-								TSType: goTypeToTS(goType, knownStructs),
+								Name:     fieldName,
+								GoType:   goType,
+								TSType:   goTypeToTS(goType, knownStructs, namedTypes),
+								Readonly: isReadonlyTag(field.Tag),
+								Default:  defaultTag(field.Tag),
 							})
 						}
 					}
@@ -161,9 +222,11 @@ func introspect(filePath string) error {
 				if recvTypeName == appStructName {
 					methodName := funcDecl.Name.Name
 
-					// Only process exported methods
-					if isExported(methodName) {
-						method := extractMethod(funcDecl, knownStructs)
+					// MethodAliases renames methods rather than being one (see runtime.MethodAliaser)
+					if methodName == "MethodAliases" {
+						aliases = extractStringMapReturn(funcDecl)
+					} else if isExported(methodName) {
+						method := extractMethod(funcDecl, knownStructs, namedTypes)
 						methods = append(methods, method)
 					}
 				}
@@ -178,6 +241,16 @@ func introspect(filePath string) error {
 		appStructName = "App"
 	}
 
+	// Optional params depend on struct fields, which are only all known now
+	for _, method := range methods {
+		markOptional(method.Params, structFields)
+	}
+
+	methods, err = applyAliases(methods, aliases, camel)
+	if err != nil {
+		return err
+	}
+
 	// Build the output
 	output := IntrospectionOutput{
 		App: AppInfo{
@@ -203,7 +276,7 @@ func introspect(filePath string) error {
 	return encoder.Encode(output)
 }
 
-func extractMethod(funcDecl *ast.FuncDecl, knownStructs map[string]bool) MethodDef {
+func extractMethod(funcDecl *ast.FuncDecl, knownStructs map[string]bool, namedTypes map[string]string) MethodDef {
 	methodName := funcDecl.Name.Name
 
 	// Extract parameters - initialize as empty slice, not nil
@@ -212,7 +285,7 @@ func extractMethod(funcDecl *ast.FuncDecl, knownStructs map[string]bool) MethodD
 		paramIndex := 0
 		for _, field := range funcDecl.Type.Params.List {
 			goType := exprToString(field.Type)
-			tsType := goTypeToTS(goType, knownStructs)
+			tsType := goTypeToTS(goType, knownStructs, namedTypes)
 
 			if len(field.Names) == 0 {
 				// Anonymous parameter
@@ -261,18 +334,22 @@ func extractMethod(funcDecl *ast.FuncDecl, knownStructs map[string]bool) MethodD
 				for range result.Names {
 					returnTypes = append(returnTypes, TypeDef{
 						GoType: goType,
-						TSType: goTypeToTS(goType, knownStructs),
+						TSType: goTypeToTS(goType, knownStructs, namedTypes),
 					})
 				}
 			} else {
 				returnTypes = append(returnTypes, TypeDef{
 					GoType: goType,
-					TSType: goTypeToTS(goType, knownStructs),
+					TSType: goTypeToTS(goType, knownStructs, namedTypes),
 				})
 			}
 		}
 	}
 
+	if len(returnTypes) == 1 && isBinaryGoType(returnTypes[0].GoType) {
+		returnTypes[0].TSType = "Uint8Array"
+	}
+
 	return MethodDef{
 		Name:        methodName,
 		Params:      params,
@@ -281,6 +358,39 @@ func extractMethod(funcDecl *ast.FuncDecl, knownStructs map[string]bool) MethodD
 	}
 }
 
+// markOptional flags the parameters a caller may leave off: a trailing
+// variadic and the run of pointer parameters and omittable structs (see
+// omittableStruct) at the end of the list. An optional parameter followed
+// by a required one stays required, since only trailing arguments can be
+// omitted.
+func markOptional(params []ParamDef, structFields map[string][]FieldDef) {
+	i := len(params)
+	if i > 0 && strings.HasPrefix(params[i-1].GoType, "...") {
+		params[i-1].Optional = true
+		params[i-1].Variadic = true
+		i--
+	}
+	for ; i > 0 && (strings.HasPrefix(params[i-1].GoType, "*") || omittableStruct(structFields[params[i-1].GoType])); i-- {
+		params[i-1].Optional = true
+	}
+}
+
+// omittableStruct mirrors extension.OmittableStruct: a params struct with at
+// least one default whose other fields are all pointers can be left off,
+// the dispatcher passing it with its defaults
+func omittableStruct(fields []FieldDef) bool {
+	hasDefault := false
+	for _, field := range fields {
+		switch {
+		case field.Default != nil:
+			hasDefault = true
+		case !strings.HasPrefix(field.GoType, "*"):
+			return false
+		}
+	}
+	return hasDefault
+}
+
 func exprToString(expr ast.Expr) string {
 	switch t := expr.(type) {
 	case *ast.Ident:
@@ -302,7 +412,11 @@ func exprToString(expr ast.Expr) string {
 	}
 }
 
-func goTypeToTS(goType string, knownStructs map[string]bool) string {
+func goTypeToTS(goType string, knownStructs map[string]bool, namedTypes map[string]string) string {
+	if tsType, ok := typeMap[goType]; ok {
+		return tsType
+	}
+
 	switch goType {
 	case "string":
 		return "string"
@@ -312,6 +426,8 @@ func goTypeToTS(goType string, knownStructs map[string]bool) string {
 		return "number"
 	case "bool":
 		return "boolean"
+	case "[]byte":
+		return "string" // encoding/json uses base64
 	case "error":
 		return "Error"
 	case "interface{}":
@@ -319,33 +435,105 @@ func goTypeToTS(goType string, knownStructs map[string]bool) string {
 	default:
 		// Handle arrays
 		if strings.HasPrefix(goType, "[]") {
-			elemType := goTypeToTS(goType[2:], knownStructs)
-			return elemType + "[]"
+			return arrayOf(goTypeToTS(goType[2:], knownStructs, namedTypes))
 		}
 		// Handle maps - parse key and value types
 		if strings.HasPrefix(goType, "map[") {
-			keyType, valueType := parseMapType(goType)
-			tsKey := goTypeToTS(keyType, knownStructs)
-			tsValue := goTypeToTS(valueType, knownStructs)
+			keyType, valueType := typestr.ParseMap(goType)
+			tsKey := mapKeyToTS(keyType, namedTypes)
+			tsValue := goTypeToTS(valueType, knownStructs, namedTypes)
 			return fmt.Sprintf("Record<%s, %s>", tsKey, tsValue)
 		}
 		// Handle pointers
 		if strings.HasPrefix(goType, "*") {
-			return goTypeToTS(goType[1:], knownStructs)
+			return goTypeToTS(goType[1:], knownStructs, namedTypes)
 		}
 		// Handle variadic
 		if strings.HasPrefix(goType, "...") {
-			elemType := goTypeToTS(goType[3:], knownStructs)
-			return elemType + "[]"
+			return arrayOf(goTypeToTS(goType[3:], knownStructs, namedTypes))
 		}
 		// Check if it's a known struct type
 		if knownStructs != nil && knownStructs[goType] {
 			return goType
 		}
+		// Named non-struct types are sent as their underlying type
+		if underlying, ok := namedTypes[goType]; ok {
+			return goTypeToTS(underlying, knownStructs, withoutType(namedTypes, goType))
+		}
 		return "any"
 	}
 }
 
+// arrayOf returns the array type of elemType, parenthesizing unions
+func arrayOf(elemType string) string {
+	if strings.Contains(elemType, " | ") {
+		elemType = "(" + elemType + ")"
+	}
+	return elemType + "[]"
+}
+
+// mapKeyToTS maps a Go map key type to a TypeScript Record key. encoding/json
+// writes integer keys as decimal strings and everything else (strings,
+// TextMarshalers) as strings, so the key is either number or string.
+func mapKeyToTS(keyType string, namedTypes map[string]string) string {
+	for {
+		underlying, ok := namedTypes[keyType]
+		if !ok {
+			break
+		}
+		namedTypes = withoutType(namedTypes, keyType)
+		keyType = underlying
+	}
+
+	switch keyType {
+	case "int", "int8", "int16", "int32", "int64",
+		"uint", "uint8", "uint16", "uint32", "uint64":
+		return "number"
+	default:
+		return "string"
+	}
+}
+
+// withoutType returns namedTypes minus name, so resolving a recursive type
+// (type List []List) terminates
+func withoutType(namedTypes map[string]string, name string) map[string]string {
+	rest := make(map[string]string, len(namedTypes))
+	for k, v := range namedTypes {
+		if k != name {
+			rest[k] = v
+		}
+	}
+	return rest
+}
+
+// isBinaryGoType reports whether a sole return value of this type is sent
+// through the bridge's binary path and surfaces as a Uint8Array
+func isBinaryGoType(goType string) bool {
+	switch goType {
+	case "[]byte", "io.Reader", "io.ReadCloser":
+		return true
+	}
+	return false
+}
+
+// camelCase lowercases the first word of a Go identifier, matching the
+// runtime's UseCamelCase: GetURL becomes getURL, URLPath urlPath and ID id
+func camelCase(name string) string {
+	runes := []rune(name)
+	upper := 0
+	for upper < len(runes) && unicode.IsUpper(runes[upper]) {
+		upper++
+	}
+	// In URLPath the P starts the next word, so it keeps its capital
+	if upper > 1 && upper < len(runes) && unicode.IsLower(runes[upper]) {
+		upper--
+	}
+	for i := 0; i < upper; i++ {
+		runes[i] = unicode.ToLower(runes[i])
+	}
+	return string(runes)
+}
+
 func isExported(name string) bool {
 	if len(name) == 0 {
 		return false
@@ -353,40 +541,95 @@ func isExported(name string) bool {
 	return name[0] >= 'A' && name[0] <= 'Z'
 }
 
-// parseMapType extracts key and value types from a map type string
-// e.g., "map[string]int" returns ("string", "int")
-// e.g., "map[string]map[string]int" returns ("string", "map[string]int")
-func parseMapType(mapType string) (keyType, valueType string) {
-	// Remove "map[" prefix
-	if !strings.HasPrefix(mapType, "map[") {
-		return "string", "any"
-	}
-
-	inner := mapType[4:] // Remove "map["
-
-	// Find the matching ] for the key type
-	// Need to handle nested brackets like map[string]map[string]int
-	bracketCount := 1
-	keyEnd := 0
-
-	for i, ch := range inner {
-		if ch == '[' {
-			bracketCount++
-		} else if ch == ']' {
-			bracketCount--
-			if bracketCount == 0 {
-				keyEnd = i
-				break
-			}
+// isReadonlyTag reports whether a struct field tag contains strux:"readonly"
+func isReadonlyTag(tag *ast.BasicLit) bool {
+	if tag == nil {
+		return false
+	}
+	value := reflect.StructTag(strings.Trim(tag.Value, "`")).Get("strux")
+	for _, opt := range strings.Split(value, ",") {
+		if opt == "readonly" {
+			return true
 		}
 	}
+	return false
+}
+
+// defaultTag returns the value of a strux:"default=..." tag option, which
+// runs to the end of the tag, or nil if the field has none
+func defaultTag(tag *ast.BasicLit) *string {
+	if tag == nil {
+		return nil
+	}
+	value := reflect.StructTag(strings.Trim(tag.Value, "`")).Get("strux")
+	for {
+		if def, ok := strings.CutPrefix(value, "default="); ok {
+			return &def
+		}
+		var found bool
+		if _, value, found = strings.Cut(value, ","); !found {
+			return nil
+		}
+	}
+}
+
+// extractStringMapReturn reads the map[string]string literal returned by a
+// method such as MethodAliases. Entries that are not string literals are skipped.
+func extractStringMapReturn(funcDecl *ast.FuncDecl) map[string]string {
+	result := make(map[string]string)
+	if funcDecl.Body == nil {
+		return result
+	}
 
-	if keyEnd == 0 {
-		return "string", "any"
+	for _, stmt := range funcDecl.Body.List {
+		retStmt, ok := stmt.(*ast.ReturnStmt)
+		if !ok || len(retStmt.Results) != 1 {
+			continue
+		}
+		lit, ok := retStmt.Results[0].(*ast.CompositeLit)
+		if !ok {
+			continue
+		}
+		for _, elt := range lit.Elts {
+			kv, ok := elt.(*ast.KeyValueExpr)
+			if !ok {
+				continue
+			}
+			key, keyOK := stringLiteral(kv.Key)
+			value, valueOK := stringLiteral(kv.Value)
+			if keyOK && valueOK {
+				result[key] = value
+			}
+		}
 	}
+	return result
+}
 
-	keyType = inner[:keyEnd]
-	valueType = inner[keyEnd+1:]
+// stringLiteral returns the value of a string literal expression
+func stringLiteral(expr ast.Expr) (string, bool) {
+	lit, ok := expr.(*ast.BasicLit)
+	if !ok || lit.Kind != token.STRING {
+		return "", false
+	}
+	value, err := strconv.Unquote(lit.Value)
+	return value, err == nil
+}
 
-	return keyType, valueType
+// applyAliases renames methods to their exposed names, in camelCase unless
+// aliased when camel is set, failing if two methods end up with the same name
+func applyAliases(methods []MethodDef, aliases map[string]string, camel bool) ([]MethodDef, error) {
+	exposedBy := make(map[string]string)
+	for i, method := range methods {
+		goName := method.Name
+		if alias, ok := aliases[goName]; ok {
+			methods[i].Name = alias
+		} else if camel {
+			methods[i].Name = camelCase(goName)
+		}
+		if other, taken := exposedBy[methods[i].Name]; taken {
+			return nil, fmt.Errorf("methods %s and %s are both exposed as %q", other, goName, methods[i].Name)
+		}
+		exposedBy[methods[i].Name] = goName
+	}
+	return methods, nil
 }
@@ -0,0 +1,39 @@
+// Package typestr parses Go type expressions as printed from source, for the
+// generators in cmd that work from the AST rather than reflect.
+package typestr
+
+import "strings"
+
+// ParseMap extracts key and value types from a map type string
+// e.g., "map[string]int" returns ("string", "int")
+// e.g., "map[string]map[string]int" returns ("string", "map[string]int")
+// Anything else returns ("string", "any").
+func ParseMap(mapType string) (keyType, valueType string) {
+	if !strings.HasPrefix(mapType, "map[") {
+		return "string", "any"
+	}
+
+	inner := mapType[4:] // Remove "map["
+
+	// Find the ] matching "map[", skipping nested brackets in the key type
+	bracketCount := 1
+	keyEnd := 0
+
+	for i, ch := range inner {
+		if ch == '[' {
+			bracketCount++
+		} else if ch == ']' {
+			bracketCount--
+			if bracketCount == 0 {
+				keyEnd = i
+				break
+			}
+		}
+	}
+
+	if keyEnd == 0 {
+		return "string", "any"
+	}
+
+	return inner[:keyEnd], inner[keyEnd+1:]
+}
@@ -0,0 +1,24 @@
+package typestr
+
+import "testing"
+
+func TestParseMap(t *testing.T) {
+	tests := []struct {
+		in         string
+		key, value string
+	}{
+		{"map[string]int", "string", "int"},
+		{"map[string]map[string]int", "string", "map[string]int"},
+		{"map[[2]int][]string", "[2]int", "[]string"},
+		{"map[int]*User", "int", "*User"},
+		{"[]string", "string", "any"},
+		{"map[]int", "string", "any"},
+		{"map[string", "string", "any"},
+	}
+	for _, tt := range tests {
+		key, value := ParseMap(tt.in)
+		if key != tt.key || value != tt.value {
+			t.Errorf("ParseMap(%q) = %q, %q; want %q, %q", tt.in, key, value, tt.key, tt.value)
+		}
+	}
+}
@@ -0,0 +1,267 @@
+package runtime
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+)
+
+// invalidRequestError is the error for a message without a method
+const invalidRequestError = "invalid request"
+
+// parseError is the error for a frame that could not be decoded
+const parseError = "parse error"
+
+// codec maps the frames of one IPC connection onto Messages and back.
+// The strux framing is the default; JSON-RPC 2.0 is accepted per connection
+// when enabled with EnableJSONRPC.
+type codec interface {
+	// decode splits one frame into messages. batch is true for a JSON-RPC
+	// array, whose responses must be written back as an array.
+	decode(frame json.RawMessage) (msgs []Message, batch bool, err error)
+
+	// response returns the frame to write for a decoded frame's responses,
+	// or nil if nothing should be written
+	response(resps []Response, batch bool) interface{}
+
+	// event returns the frame for an emitted event
+	event(ev Event) interface{}
+
+	// invocation returns the frame for a call from Go into the frontend
+	invocation(inv Invocation) interface{}
+
+	// rejected returns the frame answering a frame that was not decoded,
+	// with the raw id recovered from it if any, or nil if the client could
+	// not match it to a call
+	rejected(id json.RawMessage, message string) interface{}
+
+	// parseError returns the frame answering a frame that was read whole
+	// but could not be decoded, with the raw id recovered from it if any
+	parseError(id json.RawMessage) interface{}
+}
+
+// struxCodec is the native framing: one Message in, one Response out
+type struxCodec struct{}
+
+func (struxCodec) decode(frame json.RawMessage) ([]Message, bool, error) {
+	var msg Message
+	if err := json.Unmarshal(frame, &msg); err != nil {
+		return nil, false, err
+	}
+	return []Message{msg}, false, nil
+}
+
+func (struxCodec) response(resps []Response, batch bool) interface{} {
+	return resps[0]
+}
+
+func (struxCodec) event(ev Event) interface{} {
+	return ev
+}
+
+func (struxCodec) invocation(inv Invocation) interface{} {
+	return inv
+}
+
+func (struxCodec) rejected(id json.RawMessage, message string) interface{} {
+	var call string
+	if json.Unmarshal(id, &call) != nil || call == "" {
+		return nil
+	}
+	return Response{ID: call, Error: message}
+}
+
+// parseError answers with the recovered id, or an empty one, since the
+// stdio host should hear about the frame even if it can't match it to a call
+func (struxCodec) parseError(id json.RawMessage) interface{} {
+	var call string
+	json.Unmarshal(id, &call)
+	return Response{ID: call, Error: parseError}
+}
+
+// JSON-RPC 2.0 error codes
+const (
+	jsonRPCParseError     = -32700
+	jsonRPCInvalidRequest = -32600
+	jsonRPCMethodNotFound = -32601
+	jsonRPCInvalidParams  = -32602
+	jsonRPCServerError    = -32000
+)
+
+// jsonRPCRequest is a JSON-RPC 2.0 request or notification
+type jsonRPCRequest struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id,omitempty"`
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params,omitempty"`
+
+	// Set instead of Method when the client answers an invocation
+	Result json.RawMessage `json:"result,omitempty"`
+	Error  *jsonRPCError   `json:"error,omitempty"`
+}
+
+// jsonRPCError is the error member of a JSON-RPC 2.0 response
+type jsonRPCError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+// jsonRPCCodec speaks JSON-RPC 2.0. The raw request id is carried through
+// Message.ID so responses echo it unchanged; requests without an id are
+// notifications and get no response. Batches are dispatched in order.
+type jsonRPCCodec struct{}
+
+func (jsonRPCCodec) decode(frame json.RawMessage) ([]Message, bool, error) {
+	if !isJSONArray(frame) {
+		return []Message{jsonRPCMessage(frame)}, false, nil
+	}
+
+	var entries []json.RawMessage
+	if err := json.Unmarshal(frame, &entries); err != nil {
+		return nil, false, err
+	}
+	if len(entries) == 0 {
+		// An empty batch is answered with a single error, not an array
+		return []Message{{ID: "null"}}, false, nil
+	}
+
+	msgs := make([]Message, len(entries))
+	for i, entry := range entries {
+		msgs[i] = jsonRPCMessage(entry)
+	}
+	return msgs, true, nil
+}
+
+// jsonRPCMessage converts one request. Invalid requests become a Message
+// without a method, which dispatch answers with invalidRequestError. Responses
+// to invocations become replyMethod messages.
+func jsonRPCMessage(raw json.RawMessage) Message {
+	var req jsonRPCRequest
+	err := json.Unmarshal(raw, &req)
+	if err == nil && req.JSONRPC == "2.0" && req.Method == "" && (req.Result != nil || req.Error != nil) {
+		var id string
+		if json.Unmarshal(req.ID, &id) != nil {
+			id = string(req.ID)
+		}
+		message := ""
+		if req.Error != nil {
+			message = req.Error.Message
+		}
+		params, _ := json.Marshal([]interface{}{req.Result, message})
+		return Message{ID: id, Method: replyMethod, Params: params}
+	}
+	if err != nil || req.JSONRPC != "2.0" {
+		id := "null"
+		if len(req.ID) > 0 {
+			id = string(req.ID)
+		}
+		return Message{ID: id}
+	}
+	return Message{ID: string(req.ID), Method: req.Method, Params: req.Params}
+}
+
+func (jsonRPCCodec) response(resps []Response, batch bool) interface{} {
+	out := make([]interface{}, 0, len(resps))
+	for _, resp := range resps {
+		if resp.ID == "" {
+			continue // notification
+		}
+		out = append(out, jsonRPCResponse(resp))
+	}
+
+	if len(out) == 0 {
+		return nil
+	}
+	if !batch {
+		return out[0]
+	}
+	return out
+}
+
+// jsonRPCResponse converts a Response; exactly one of result and error is set.
+// Binary results are sent as their base64 string.
+func jsonRPCResponse(resp Response) map[string]interface{} {
+	out := map[string]interface{}{
+		"jsonrpc": "2.0",
+		"id":      json.RawMessage(resp.ID),
+	}
+	if resp.Error != "" {
+		out["error"] = jsonRPCError{Code: jsonRPCErrorCode(resp.Error), Message: resp.Error}
+	} else {
+		out["result"] = resp.Result
+	}
+	return out
+}
+
+// jsonRPCErrorCode picks the JSON-RPC error code for a dispatch error
+func jsonRPCErrorCode(message string) int {
+	switch {
+	case message == invalidRequestError:
+		return jsonRPCInvalidRequest
+	case strings.HasPrefix(message, "method ") && strings.HasSuffix(message, " not found"):
+		return jsonRPCMethodNotFound
+	case strings.HasPrefix(message, "invalid parameters"):
+		return jsonRPCInvalidParams
+	default:
+		return jsonRPCServerError
+	}
+}
+
+// event sends an emitted event as an "event" notification
+func (jsonRPCCodec) event(ev Event) interface{} {
+	return map[string]interface{}{
+		"jsonrpc": "2.0",
+		"method":  "event",
+		"params":  ev,
+	}
+}
+
+// invocation sends a call from Go as a JSON-RPC request; the client's
+// response carries the same id
+func (jsonRPCCodec) invocation(inv Invocation) interface{} {
+	return map[string]interface{}{
+		"jsonrpc": "2.0",
+		"id":      inv.Call,
+		"method":  inv.Method,
+		"params":  inv.Params,
+	}
+}
+
+// rejected answers with an Invalid Request error, using a null id when the
+// request's could not be recovered, as the spec requires
+func (jsonRPCCodec) rejected(id json.RawMessage, message string) interface{} {
+	if len(id) == 0 {
+		id = json.RawMessage("null")
+	}
+	return map[string]interface{}{
+		"jsonrpc": "2.0",
+		"id":      id,
+		"error":   jsonRPCError{Code: jsonRPCInvalidRequest, Message: message},
+	}
+}
+
+// parseError answers with a Parse error, whose id is always null
+func (jsonRPCCodec) parseError(id json.RawMessage) interface{} {
+	return map[string]interface{}{
+		"jsonrpc": "2.0",
+		"id":      nil,
+		"error":   jsonRPCError{Code: jsonRPCParseError, Message: parseError},
+	}
+}
+
+// isJSONRPCFrame reports whether a frame is a JSON-RPC 2.0 request or batch
+func isJSONRPCFrame(frame json.RawMessage) bool {
+	if isJSONArray(frame) {
+		return true
+	}
+	var probe struct {
+		JSONRPC *string `json:"jsonrpc"`
+	}
+	return json.Unmarshal(frame, &probe) == nil && probe.JSONRPC != nil
+}
+
+// isJSONArray reports whether a JSON value is an array
+func isJSONArray(raw json.RawMessage) bool {
+	trimmed := bytes.TrimSpace(raw)
+	return len(trimmed) > 0 && trimmed[0] == '['
+}
@@ -0,0 +1,123 @@
+package runtime
+
+import (
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"sort"
+
+	"github.com/strux-dev/strux/pkg/runtime/extension"
+)
+
+// APIDescription is the API surface the runtime exposes to the frontend.
+// Types are Go type names, e.g. "[]main.Item".
+type APIDescription struct {
+	Package    string                 `json:"package"`
+	App        string                 `json:"app"`
+	Methods    []MethodDescription    `json:"methods"`
+	Fields     []FieldInfo            `json:"fields"`
+	Extensions []ExtensionDescription `json:"extensions"`
+	Events     map[string]string      `json:"events"` // event name -> payload type ("" if none)
+}
+
+// MethodDescription describes one callable method
+type MethodDescription struct {
+	Name        string   `json:"name"`
+	ParamTypes  []string `json:"paramTypes"`
+	ReturnTypes []string `json:"returnTypes"` // excluding a trailing error
+	HasError    bool     `json:"hasError"`
+}
+
+// ExtensionDescription describes the methods of one extension
+type ExtensionDescription struct {
+	Namespace    string              `json:"namespace"`
+	SubNamespace string              `json:"subNamespace"`
+	Methods      []MethodDescription `json:"methods"`
+}
+
+// Describe returns the app methods, fields, extensions and events as data,
+// for debug panels and tooling. It is also served over the bridge as __describe.
+func (rt *Runtime) Describe() APIDescription {
+	rt.mu.RLock()
+	desc := APIDescription{
+		Package: rt.pkgName,
+		App:     rt.structName,
+		Methods: make([]MethodDescription, 0, len(rt.methods)),
+		Events:  make(map[string]string, len(rt.events)),
+	}
+	for name, method := range rt.methods {
+		desc.Methods = append(desc.Methods, describeMethod(name, method.Type()))
+	}
+	for name, payloadType := range rt.events {
+		desc.Events[name] = ""
+		if payloadType != nil {
+			desc.Events[name] = payloadType.String()
+		}
+	}
+	rt.mu.RUnlock()
+
+	sort.Slice(desc.Methods, func(i, j int) bool { return desc.Methods[i].Name < desc.Methods[j].Name })
+
+	desc.Fields = rt.GetFieldInfo()
+	sort.Slice(desc.Fields, func(i, j int) bool { return desc.Fields[i].Name < desc.Fields[j].Name })
+
+	for _, entry := range rt.extensions.List() {
+		ext := ExtensionDescription{
+			Namespace:    entry.Namespace,
+			SubNamespace: entry.SubNamespace,
+			Methods:      []MethodDescription{},
+		}
+		for _, method := range extension.ExposedMethods(entry.Instance) {
+			// Use the bound method so the receiver is not listed as a parameter
+			ext.Methods = append(ext.Methods, describeMethod(method.Name, reflect.ValueOf(entry.Instance).Method(method.Index).Type()))
+		}
+		desc.Extensions = append(desc.Extensions, ext)
+	}
+
+	return desc
+}
+
+// apiPath is where StartWithOptions mounts APIHandler
+const apiPath = "/__strux/api"
+
+// APIHandler serves Describe as JSON to GET requests, for API explorers in
+// the frontend. It is mounted at /__strux/api by StartWithOptions when
+// ServerOptions.APIExplorer is set.
+func (rt *Runtime) APIHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			w.Header().Set("Allow", "GET, HEAD")
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Cache-Control", "no-store")
+		json.NewEncoder(w).Encode(rt.Describe())
+	})
+}
+
+// errorType is the reflect.Type of the error interface
+var errorType = reflect.TypeOf((*error)(nil)).Elem()
+
+// describeMethod describes a bound method's signature
+func describeMethod(name string, methodType reflect.Type) MethodDescription {
+	desc := MethodDescription{
+		Name:        name,
+		ParamTypes:  make([]string, methodType.NumIn()),
+		ReturnTypes: []string{},
+	}
+	for i := 0; i < methodType.NumIn(); i++ {
+		desc.ParamTypes[i] = methodType.In(i).String()
+	}
+
+	numOut := methodType.NumOut()
+	if numOut > 0 && methodType.Out(numOut-1) == errorType {
+		desc.HasError = true
+		numOut--
+	}
+	for i := 0; i < numOut; i++ {
+		desc.ReturnTypes = append(desc.ReturnTypes, methodType.Out(i).String())
+	}
+	return desc
+}
@@ -0,0 +1,114 @@
+package runtime
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"sync"
+)
+
+// Event is pushed to subscribed IPC clients when Go emits an event
+type Event struct {
+	Event   string      `json:"event"`
+	Payload interface{} `json:"payload,omitempty"`
+}
+
+// ipcClient is one IPC connection. Writes are serialized because responses
+// and emitted events are sent from different goroutines.
+type ipcClient struct {
+	encoder frameEncoder
+	codec   codec
+	mu      sync.Mutex
+}
+
+// Encode writes a single message to the client
+func (c *ipcClient) Encode(v interface{}) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.encoder.Encode(v)
+}
+
+// RegisterEvent declares an event the app emits and the type of its payload.
+// The declaration is what GenerateTypeScript uses to type strux.on, so payload
+// only needs to have the right type, e.g. RegisterEvent("deviceState", DeviceState{}).
+// A nil payload declares an event without one.
+func (rt *Runtime) RegisterEvent(name string, payload interface{}) error {
+	if name == "" {
+		return fmt.Errorf("event name cannot be empty")
+	}
+
+	rt.mu.Lock()
+	defer rt.mu.Unlock()
+
+	if _, exists := rt.events[name]; exists {
+		return fmt.Errorf("event %s already registered", name)
+	}
+	rt.events[name] = reflect.TypeOf(payload)
+	return nil
+}
+
+// Emit sends an event to every subscribed frontend. The event must have been
+// declared with RegisterEvent and the payload must match its declared type.
+func (rt *Runtime) Emit(name string, payload interface{}) error {
+	rt.mu.RLock()
+	payloadType, exists := rt.events[name]
+	clients := make([]*ipcClient, 0, len(rt.subscribers))
+	for client := range rt.subscribers {
+		clients = append(clients, client)
+	}
+	rt.mu.RUnlock()
+
+	if !exists {
+		return fmt.Errorf("event %s not registered", name)
+	}
+	if reflect.TypeOf(payload) != payloadType {
+		return fmt.Errorf("event %s expects payload of type %v, got %T", name, payloadType, payload)
+	}
+
+	rt.metrics.events.Add(1)
+	var sendErr error
+	for _, client := range clients {
+		err := rt.send(client, client.codec.event(Event{Event: name, Payload: payload}))
+		var tooLarge *errMessageTooLarge
+		if errors.As(err, &tooLarge) {
+			sendErr = fmt.Errorf("event %s not sent: %w", name, err)
+			continue
+		}
+		if err != nil {
+			rt.unsubscribe(client)
+		}
+	}
+	return sendErr
+}
+
+// subscribe registers a client to receive emitted events
+func (rt *Runtime) subscribe(client *ipcClient) {
+	rt.mu.Lock()
+	defer rt.mu.Unlock()
+	rt.subscribers[client] = true
+}
+
+// unsubscribe stops sending events to a client
+func (rt *Runtime) unsubscribe(client *ipcClient) {
+	rt.mu.Lock()
+	defer rt.mu.Unlock()
+	delete(rt.subscribers, client)
+}
+
+// eventNames returns the registered event names in sorted order
+func (rt *Runtime) eventNames() []string {
+	rt.mu.RLock()
+	defer rt.mu.RUnlock()
+	return rt.eventNamesLocked()
+}
+
+// eventNamesLocked is eventNames for callers already holding rt.mu
+func (rt *Runtime) eventNamesLocked() []string {
+	names := make([]string, 0, len(rt.events))
+	for name := range rt.events {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -11,6 +11,12 @@ import (
 	"time"
 )
 
+func init() {
+	Register(func() (Extension, interface{}) {
+		return &BootExtension{}, &BootMethods{}
+	})
+}
+
 // BootExtension provides boot and system management functions
 type BootExtension struct{}
 
@@ -27,19 +33,31 @@ func (b *BootExtension) SubNamespace() string {
 // BootMethods provides the boot management methods
 type BootMethods struct{}
 
+// cageControlSocket is the control socket served by the Strux build of Cage,
+// with or without a splash image
+var cageControlSocket = "/tmp/strux-cage-control.sock"
+
+// struxClientPath exists only on Strux images, where the client launches
+// Cage. Without it the app is running in dev mode on a workstation.
+var struxClientPath = "/strux/client"
+
 // HideSplash communicates with Cage to hide the splash screen
 func (b *BootMethods) HideSplash() error {
-	// Connect to Cage's control socket
-	socketPath := "/tmp/strux-cage-control.sock"
+	fmt.Printf("Strux Boot: HideSplash() called\n")
+	return sendCageCommand("HIDE_SPLASH")
+}
 
-	fmt.Printf("Strux Boot: HideSplash() called, connecting to %s\n", socketPath)
+// sendCageCommand sends a single command over Cage's control socket.
+// A missing socket is only ignored in dev mode, where there is no Cage; on a
+// Strux image it means Cage isn't running, and the command fails.
+func sendCageCommand(command string) error {
+	fmt.Printf("Strux Boot: Connecting to %s\n", cageControlSocket)
 
-	conn, err := net.Dial("unix", socketPath)
+	conn, err := net.Dial("unix", cageControlSocket)
 	if err != nil {
 		fmt.Printf("Strux Boot: Failed to connect: %v\n", err)
-		// Socket doesn't exist (dev mode or splash not shown), silently succeed
-		if os.IsNotExist(err) || isConnectionRefused(err) {
-			fmt.Printf("Strux Boot: Socket not found or refused, returning nil (dev mode?)\n")
+		if (os.IsNotExist(err) || isConnectionRefused(err)) && !onStruxImage() {
+			fmt.Printf("Strux Boot: No Cage in dev mode, ignoring %s\n", command)
 			return nil
 		}
 		return fmt.Errorf("failed to connect to Cage control socket: %w", err)
@@ -50,13 +68,12 @@ func (b *BootMethods) HideSplash() error {
 		_ = uc.SetDeadline(time.Now().Add(2 * time.Second))
 	}
 
-	fmt.Printf("Strux Boot: Connected, sending HIDE_SPLASH command\n")
+	fmt.Printf("Strux Boot: Connected, sending %s command\n", command)
 
-	// Send HIDE_SPLASH command
-	_, err = conn.Write([]byte("HIDE_SPLASH"))
+	_, err = conn.Write([]byte(command))
 	if err != nil {
 		fmt.Printf("Strux Boot: Failed to send: %v\n", err)
-		return fmt.Errorf("failed to send hide splash command: %w", err)
+		return fmt.Errorf("failed to send %s command: %w", command, err)
 	}
 
 	// Gracefully close write side to signal EOF to the server
@@ -64,10 +81,17 @@ func (b *BootMethods) HideSplash() error {
 		_ = uc.CloseWrite()
 	}
 
-	fmt.Printf("Strux Boot: HIDE_SPLASH command sent successfully\n")
+	fmt.Printf("Strux Boot: %s command sent successfully\n", command)
 	return nil
 }
 
+// onStruxImage reports whether the app is running on a Strux image rather
+// than in dev mode
+func onStruxImage() bool {
+	_, err := os.Stat(struxClientPath)
+	return err == nil
+}
+
 // isConnectionRefused checks if the error is a connection refused error
 func isConnectionRefused(err error) bool {
 	if err == nil {
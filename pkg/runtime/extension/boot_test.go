@@ -0,0 +1,87 @@
+package extension
+
+import (
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// fakeCage points the Cage commands at a temporary control socket and
+// image marker, restoring them when the test ends
+func fakeCage(t *testing.T, onImage bool) (socket string) {
+	t.Helper()
+	dir := t.TempDir()
+	oldSocket, oldClient := cageControlSocket, struxClientPath
+	t.Cleanup(func() { cageControlSocket, struxClientPath = oldSocket, oldClient })
+
+	cageControlSocket = filepath.Join(dir, "control.sock")
+	struxClientPath = filepath.Join(dir, "client")
+	if onImage {
+		if err := os.WriteFile(struxClientPath, nil, 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return cageControlSocket
+}
+
+// listenCage serves the control socket and returns the commands it receives
+func listenCage(t *testing.T, socket string) <-chan string {
+	t.Helper()
+	listener, err := net.Listen("unix", socket)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { listener.Close() })
+
+	commands := make(chan string, 1)
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		command, _ := io.ReadAll(conn)
+		commands <- string(command)
+	}()
+	return commands
+}
+
+func TestSendCageCommandWithoutCage(t *testing.T) {
+	fakeCage(t, false)
+	if err := sendCageCommand("WAKE"); err != nil {
+		t.Errorf("dev mode: got %v, want nil", err)
+	}
+
+	fakeCage(t, true)
+	if err := sendCageCommand("WAKE"); err == nil {
+		t.Error("on a Strux image without Cage: got nil, want an error")
+	}
+}
+
+func TestDisplayCommands(t *testing.T) {
+	display := &DisplayMethods{}
+	tests := []struct {
+		name string
+		call func() error
+		want string
+	}{
+		{"SetBlankTimeout", func() error { return display.SetBlankTimeout(300) }, "BLANK_TIMEOUT 300"},
+		{"Blank", display.Blank, "BLANK"},
+		{"Wake", display.Wake, "WAKE"},
+		{"hide cursor", func() error { return display.SetCursorVisible(false) }, "HIDE_CURSOR"},
+		{"show cursor", func() error { return display.SetCursorVisible(true) }, "SHOW_CURSOR"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			commands := listenCage(t, fakeCage(t, true))
+			if err := tt.call(); err != nil {
+				t.Fatal(err)
+			}
+			if got := <-commands; got != tt.want {
+				t.Errorf("sent %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,118 @@
+package extension
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// DefaultTag returns the default a struct field declares with
+// strux:"default=<value>". The value runs to the end of the tag, so default
+// must be the last option and its value may contain commas. Strings are
+// written bare (default=dark); other types as JSON (default=10, default=[1,2]).
+func DefaultTag(field reflect.StructField) (string, bool) {
+	tag := field.Tag.Get("strux")
+	for {
+		if value, ok := strings.CutPrefix(tag, "default="); ok {
+			return value, true
+		}
+		var found bool
+		if _, tag, found = strings.Cut(tag, ","); !found {
+			return "", false
+		}
+	}
+}
+
+// NewDefault returns a pointer to a new value of type t whose struct fields,
+// including those of nested structs, hold their tagged defaults. Decoding
+// JSON into it then replaces only the fields the caller sent. For a pointer
+// to a struct, the struct is allocated with its defaults.
+func NewDefault(t reflect.Type) (reflect.Value, error) {
+	ptr := reflect.New(t)
+	if t.Kind() == reflect.Ptr && t.Elem().Kind() == reflect.Struct {
+		inner := reflect.New(t.Elem())
+		if err := setDefaults(inner.Elem()); err != nil {
+			return reflect.Value{}, err
+		}
+		ptr.Elem().Set(inner)
+		return ptr, nil
+	}
+	return ptr, setDefaults(ptr.Elem())
+}
+
+// setDefaults fills the tagged defaults of struct v, recursing into fields
+// that are themselves structs
+func setDefaults(v reflect.Value) error {
+	if v.Kind() != reflect.Struct {
+		return nil
+	}
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() && !field.Anonymous {
+			continue
+		}
+		if text, ok := DefaultTag(field); ok {
+			value, err := parseDefault(field.Type, text)
+			if err != nil {
+				return fmt.Errorf("field %s: %w", field.Name, err)
+			}
+			v.Field(i).Set(value)
+			continue
+		}
+		if field.Type.Kind() == reflect.Struct {
+			if err := setDefaults(v.Field(i)); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// parseDefault converts the text of a default tag to a value of type t
+func parseDefault(t reflect.Type, text string) (reflect.Value, error) {
+	data := []byte(text)
+	base := t
+	for base.Kind() == reflect.Ptr {
+		base = base.Elem()
+	}
+	if base.Kind() == reflect.String {
+		data, _ = json.Marshal(text)
+	}
+
+	value := reflect.New(t)
+	if err := json.Unmarshal(data, value.Interface()); err != nil {
+		return reflect.Value{}, fmt.Errorf("invalid default %q for %s: %w", text, t, err)
+	}
+	return value.Elem(), nil
+}
+
+// OmittableStruct reports whether t is a struct that can be left off as a
+// whole: it has at least one tagged default, and every other field is a
+// pointer or ignored by encoding/json
+func OmittableStruct(t reflect.Type) bool {
+	if t.Kind() != reflect.Struct {
+		return false
+	}
+	hasDefault := false
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() && !field.Anonymous {
+			continue
+		}
+		if _, ok := DefaultTag(field); ok {
+			hasDefault = true
+			continue
+		}
+		if field.Tag.Get("json") == "-" || field.Type.Kind() == reflect.Ptr {
+			continue
+		}
+		if field.Anonymous && field.Type.Kind() == reflect.Struct && OmittableStruct(field.Type) {
+			hasDefault = true
+			continue
+		}
+		return false
+	}
+	return hasDefault
+}
@@ -0,0 +1,62 @@
+package extension
+
+import "fmt"
+
+func init() {
+	Register(func() (Extension, interface{}) {
+		return &DisplayExtension{}, &DisplayMethods{}
+	})
+}
+
+// DisplayExtension provides display and compositor controls
+type DisplayExtension struct{}
+
+// Namespace returns "strux"
+func (d *DisplayExtension) Namespace() string {
+	return "strux"
+}
+
+// SubNamespace returns "display"
+func (d *DisplayExtension) SubNamespace() string {
+	return "display"
+}
+
+// DisplayMethods provides the display control methods
+type DisplayMethods struct{}
+
+// SetCursorVisible shows or hides the compositor cursor, with or without a
+// splash image. It has no visible effect when no pointer device is attached
+// (Cage never draws a cursor then), fails if Cage isn't running and is a
+// no-op in dev mode.
+func (d *DisplayMethods) SetCursorVisible(visible bool) error {
+	fmt.Printf("Strux Display: SetCursorVisible(%v) called\n", visible)
+	if visible {
+		return sendCageCommand("SHOW_CURSOR")
+	}
+	return sendCageCommand("HIDE_CURSOR")
+}
+
+// SetBlankTimeout turns the display off after seconds without input; 0 keeps
+// it on permanently. Input or Wake turns it back on. Like the other display
+// controls it fails if Cage isn't running, and is a no-op in dev mode.
+func (d *DisplayMethods) SetBlankTimeout(seconds int) error {
+	fmt.Printf("Strux Display: SetBlankTimeout(%d) called\n", seconds)
+	if seconds < 0 {
+		return fmt.Errorf("blank timeout must not be negative, got %d", seconds)
+	}
+	return sendCageCommand(fmt.Sprintf("BLANK_TIMEOUT %d", seconds))
+}
+
+// Blank turns the display off now. It stays off until input arrives or Wake
+// is called.
+func (d *DisplayMethods) Blank() error {
+	fmt.Printf("Strux Display: Blank() called\n")
+	return sendCageCommand("BLANK")
+}
+
+// Wake turns the display back on, for example when the backend has
+// something to show, and restarts the blanking timer.
+func (d *DisplayMethods) Wake() error {
+	fmt.Printf("Strux Display: Wake() called\n")
+	return sendCageCommand("WAKE")
+}
@@ -3,6 +3,7 @@ package extension
 import (
 	"fmt"
 	"reflect"
+	"sort"
 	"sync"
 )
 
@@ -16,16 +17,43 @@ type Extension interface {
 	SubNamespace() string
 }
 
+// Closer may be implemented by an extension instance (or the Extension itself)
+// that holds resources. Close is called when the runtime stops and is never
+// exposed to the frontend.
+type Closer interface {
+	Close() error
+}
+
+// registration records one Register call, in order
+type registration struct {
+	ext      Extension
+	instance interface{}
+}
+
 // MethodInfo describes a bound method for the frontend
 type MethodInfo struct {
 	Name       string   `json:"name"`
 	ParamCount int      `json:"paramCount"`
 	ParamTypes []string `json:"paramTypes"`
+
+	// Required is how many leading parameters must be passed; the rest are
+	// trailing pointers or, when Variadic, the variadic parameter
+	Required int  `json:"required"`
+	Variadic bool `json:"variadic,omitempty"`
+
+	// ReturnTypes are the kinds of the non-error results, and HasError
+	// reports whether the last result is an error
+	ReturnTypes []string `json:"returnTypes"`
+	HasError    bool     `json:"hasError"`
 }
 
+// errorType is the reflect type of the error interface
+var errorType = reflect.TypeOf((*error)(nil)).Elem()
+
 // Registry manages all registered extensions
 type Registry struct {
 	extensions map[string]map[string]interface{} // namespace -> subnamespace -> extension instance
+	order      []registration                    // registration order, for Close
 	mu         sync.RWMutex
 }
 
@@ -59,18 +87,171 @@ func (r *Registry) Register(ext Extension, instance interface{}) error {
 	}
 
 	r.extensions[namespace][subNamespace] = instance
+	r.order = append(r.order, registration{ext: ext, instance: instance})
 	return nil
 }
 
-// GetAllBindings returns all extension bindings in the format expected by the IPC protocol
-func (r *Registry) GetAllBindings() map[string]interface{} {
+// Close closes every extension that implements Closer, in reverse registration
+// order. All extensions are closed even if one fails; the first error is returned.
+func (r *Registry) Close() error {
 	r.mu.RLock()
-	defer r.mu.RUnlock()
+	order := append([]registration(nil), r.order...)
+	r.mu.RUnlock()
 
-	bindings := make(map[string]interface{})
+	var firstErr error
+	for i := len(order) - 1; i >= 0; i-- {
+		reg := order[i]
+		closer, ok := reg.instance.(Closer)
+		if !ok {
+			closer, ok = reg.ext.(Closer)
+		}
+		if !ok {
+			continue
+		}
+		if err := closer.Close(); err != nil && firstErr == nil {
+			firstErr = fmt.Errorf("failed to close extension %s.%s: %w", reg.ext.Namespace(), reg.ext.SubNamespace(), err)
+		}
+	}
+	return firstErr
+}
+
+// isCloseMethod reports whether methodName is the instance's Closer hook,
+// which is lifecycle plumbing rather than part of its JavaScript API
+func isCloseMethod(instance interface{}, methodName string) bool {
+	_, ok := instance.(Closer)
+	return ok && methodName == "Close"
+}
 
+// RequiredParams returns how many leading arguments a caller must pass to a
+// method of this type. A trailing run of pointer parameters and structs whose
+// fields all have defaults (see OmittableStruct), and a variadic parameter,
+// may be left off: missing pointers are nil, missing structs hold their
+// defaults and a missing variadic gets no values.
+func RequiredParams(methodType reflect.Type) int {
+	i := methodType.NumIn()
+	if methodType.IsVariadic() {
+		i--
+	}
+	for i > 0 && (methodType.In(i-1).Kind() == reflect.Ptr || OmittableStruct(methodType.In(i-1))) {
+		i--
+	}
+	return i
+}
+
+// CheckParamCount reports an error if n arguments cannot be passed to a
+// method of this type
+func CheckParamCount(methodType reflect.Type, n int) error {
+	numParams := methodType.NumIn()
+	required := RequiredParams(methodType)
+	switch {
+	case methodType.IsVariadic():
+		if n < required {
+			return fmt.Errorf("expected at least %d parameters, got %d", required, n)
+		}
+	case required == numParams:
+		if n != numParams {
+			return fmt.Errorf("expected %d parameters, got %d", numParams, n)
+		}
+	case n < required || n > numParams:
+		return fmt.Errorf("expected %d to %d parameters, got %d", required, numParams, n)
+	}
+	return nil
+}
+
+// ParamType returns the type the i'th argument to a method of this type is
+// converted to. Arguments from the variadic parameter on are its elements.
+func ParamType(methodType reflect.Type, i int) reflect.Type {
+	last := methodType.NumIn() - 1
+	if methodType.IsVariadic() && i >= last {
+		return methodType.In(last).Elem()
+	}
+	return methodType.In(i)
+}
+
+// FillOptional appends values for the optional parameters args leaves off,
+// so the method can be called with them: structs get their tagged defaults
+// and everything else its zero value
+func FillOptional(methodType reflect.Type, args []reflect.Value) ([]reflect.Value, error) {
+	fixed := methodType.NumIn()
+	if methodType.IsVariadic() {
+		fixed--
+	}
+	for i := len(args); i < fixed; i++ {
+		paramType := methodType.In(i)
+		if paramType.Kind() != reflect.Struct {
+			args = append(args, reflect.Zero(paramType))
+			continue
+		}
+		value, err := NewDefault(paramType)
+		if err != nil {
+			return nil, fmt.Errorf("parameter %d: %w", i, err)
+		}
+		args = append(args, value.Elem())
+	}
+	return args, nil
+}
+
+// Entry is one registered extension instance
+type Entry struct {
+	Namespace    string
+	SubNamespace string
+	Instance     interface{}
+}
+
+// List returns every registered extension, sorted by namespace and sub-namespace
+func (r *Registry) List() []Entry {
+	r.mu.RLock()
+	entries := make([]Entry, 0, len(r.order))
 	for namespace, subNamespaces := range r.extensions {
-		namespaceBindings := make(map[string]interface{})
+		for subNamespace, instance := range subNamespaces {
+			entries = append(entries, Entry{Namespace: namespace, SubNamespace: subNamespace, Instance: instance})
+		}
+	}
+	r.mu.RUnlock()
+
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].Namespace != entries[j].Namespace {
+			return entries[i].Namespace < entries[j].Namespace
+		}
+		return entries[i].SubNamespace < entries[j].SubNamespace
+	})
+	return entries
+}
+
+// ExposedMethods returns the methods of an extension instance that are callable
+// from the frontend, in name order
+func ExposedMethods(instance interface{}) []reflect.Method {
+	typ := reflect.TypeOf(instance)
+	var methods []reflect.Method
+	for i := 0; i < typ.NumMethod(); i++ {
+		method := typ.Method(i)
+		if method.IsExported() && !isCloseMethod(instance, method.Name) {
+			methods = append(methods, method)
+		}
+	}
+	return methods
+}
+
+// GetAllBindings returns all extension bindings in the format expected by the IPC protocol.
+// The result is a snapshot: it shares no maps or slices with the registry, so callers
+// may iterate or modify it while extensions are registered concurrently.
+func (r *Registry) GetAllBindings() map[string]interface{} {
+	// Copy the instances under the lock, then reflect over them without holding it
+	r.mu.RLock()
+	snapshot := make(map[string]map[string]interface{}, len(r.extensions))
+	for namespace, subNamespaces := range r.extensions {
+		copied := make(map[string]interface{}, len(subNamespaces))
+		for subNamespace, instance := range subNamespaces {
+			copied[subNamespace] = instance
+		}
+		snapshot[namespace] = copied
+	}
+	r.mu.RUnlock()
+
+	bindings := make(map[string]interface{}, len(snapshot))
+
+	for namespace, subNamespaces := range snapshot {
+		namespaceBindings := make(map[string]interface{}, len(subNamespaces))
 
 		for subNamespace, instance := range subNamespaces {
 			methods := r.extractMethods(instance)
@@ -98,16 +279,30 @@ func (r *Registry) extractMethods(instance interface{}) []MethodInfo {
 		methodName := typ.Method(i).Name
 
 		// Only include exported methods
-		if methodName[0] >= 'A' && methodName[0] <= 'Z' {
+		if methodName[0] >= 'A' && methodName[0] <= 'Z' && !isCloseMethod(instance, methodName) {
 			paramTypes := make([]string, methodType.NumIn())
 			for j := 0; j < methodType.NumIn(); j++ {
 				paramTypes[j] = methodType.In(j).Kind().String()
 			}
 
+			numOut := methodType.NumOut()
+			hasError := numOut > 0 && methodType.Out(numOut-1).Implements(errorType)
+			if hasError {
+				numOut--
+			}
+			returnTypes := make([]string, numOut)
+			for j := 0; j < numOut; j++ {
+				returnTypes[j] = methodType.Out(j).Kind().String()
+			}
+
 			methods = append(methods, MethodInfo{
-				Name:       methodName,
-				ParamCount: methodType.NumIn(),
-				ParamTypes: paramTypes,
+				Name:        methodName,
+				ParamCount:  methodType.NumIn(),
+				ParamTypes:  paramTypes,
+				Required:    RequiredParams(methodType),
+				Variadic:    methodType.IsVariadic(),
+				ReturnTypes: returnTypes,
+				HasError:    hasError,
 			})
 		}
 	}
@@ -134,21 +329,19 @@ func (r *Registry) ExecuteMethod(namespace, subNamespace, methodName string, par
 	// Get method
 	val := reflect.ValueOf(instance)
 	method := val.MethodByName(methodName)
-	if !method.IsValid() {
+	if !method.IsValid() || isCloseMethod(instance, methodName) {
 		return nil, fmt.Errorf("method %s not found on %s.%s", methodName, namespace, subNamespace)
 	}
 
 	methodType := method.Type()
-	numParams := methodType.NumIn()
-
-	if len(params) != numParams {
-		return nil, fmt.Errorf("expected %d parameters, got %d", numParams, len(params))
+	if err := CheckParamCount(methodType, len(params)); err != nil {
+		return nil, err
 	}
 
 	// Convert parameters to the correct types
-	args := make([]reflect.Value, numParams)
-	for i := 0; i < numParams; i++ {
-		expectedType := methodType.In(i)
+	args := make([]reflect.Value, len(params))
+	for i := range params {
+		expectedType := ParamType(methodType, i)
 
 		// Try to convert the parameter
 		if params[i] != nil {
@@ -163,7 +356,11 @@ func (r *Registry) ExecuteMethod(namespace, subNamespace, methodName string, par
 		}
 	}
 
-	// Call the method
+	// Call the method, filling in omitted optional parameters
+	args, err := FillOptional(methodType, args)
+	if err != nil {
+		return nil, err
+	}
 	results := method.Call(args)
 
 	// Handle return values
@@ -173,7 +370,7 @@ func (r *Registry) ExecuteMethod(namespace, subNamespace, methodName string, par
 
 	// If last return value is error, check it
 	lastResult := results[len(results)-1]
-	if lastResult.Type().Implements(reflect.TypeOf((*error)(nil)).Elem()) {
+	if lastResult.Type().Implements(errorType) {
 		if !lastResult.IsNil() {
 			return nil, lastResult.Interface().(error)
 		}
@@ -198,3 +395,61 @@ func (r *Registry) ExecuteMethod(namespace, subNamespace, methodName string, par
 	}
 	return resultArray, nil
 }
+
+// Factory creates an extension and the instance whose methods it exposes.
+// It is called once per runtime, so each runtime gets its own instance.
+type Factory func() (Extension, interface{})
+
+// factories holds the extensions added with Register, for every runtime
+var (
+	factories   []Factory
+	factoriesMu sync.Mutex
+)
+
+// Register adds an extension to every runtime created afterwards. It is meant
+// to be called from an init function, so a package contributes its extensions
+// just by being imported:
+//
+//	func init() {
+//		extension.Register(func() (extension.Extension, interface{}) {
+//			return &StorageExtension{}, &StorageMethods{}
+//		})
+//	}
+func Register(factory Factory) {
+	if factory == nil {
+		panic("extension: Register factory is nil")
+	}
+
+	factoriesMu.Lock()
+	defer factoriesMu.Unlock()
+	factories = append(factories, factory)
+}
+
+// Registered is one extension created from the Register factories
+type Registered struct {
+	Extension Extension
+	Instance  interface{}
+}
+
+// RegisteredExtensions creates an extension from each Register factory, sorted by
+// namespace and sub-namespace so the result doesn't depend on init order
+func RegisteredExtensions() []Registered {
+	factoriesMu.Lock()
+	all := append([]Factory(nil), factories...)
+	factoriesMu.Unlock()
+
+	created := make([]Registered, 0, len(all))
+	for _, factory := range all {
+		ext, instance := factory()
+		created = append(created, Registered{Extension: ext, Instance: instance})
+	}
+
+	sort.SliceStable(created, func(i, j int) bool {
+		a, b := created[i].Extension, created[j].Extension
+		if a.Namespace() != b.Namespace() {
+			return a.Namespace() < b.Namespace()
+		}
+		return a.SubNamespace() < b.SubNamespace()
+	})
+	return created
+}
@@ -0,0 +1,133 @@
+package runtime
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/strux-dev/strux/pkg/runtime/extension"
+)
+
+// testExtension registers its methods under acme.<sub>
+type testExtension struct{ sub string }
+
+func (e testExtension) Namespace() string    { return "acme" }
+func (e testExtension) SubNamespace() string { return e.sub }
+
+type testExtensionMethods struct{}
+
+func (testExtensionMethods) Ping(n int) int { return n }
+
+// Run with -race: GetAllBindings must not read the registry's maps while
+// Register writes them
+func TestGenerateTypeScriptWhileRegistering(t *testing.T) {
+	rt := New(struct{}{})
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 100; i++ {
+			if err := rt.registerExtension(testExtension{sub: fmt.Sprintf("ext%d", i)}, testExtensionMethods{}); err != nil {
+				t.Error(err)
+				return
+			}
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 50; i++ {
+			if err := rt.GenerateTypeScriptTo(io.Discard); err != nil {
+				t.Error(err)
+				return
+			}
+		}
+	}()
+	wg.Wait()
+
+	namespace, _ := rt.extensions.GetAllBindings()["acme"].(map[string]interface{})
+	if len(namespace) != 100 {
+		t.Fatalf("got %d extensions, want 100", len(namespace))
+	}
+}
+
+func TestGetAllBindingsIsASnapshot(t *testing.T) {
+	rt := New(struct{}{})
+	if err := rt.registerExtension(testExtension{sub: "one"}, testExtensionMethods{}); err != nil {
+		t.Fatal(err)
+	}
+
+	bindings := rt.extensions.GetAllBindings()
+	namespace := bindings["acme"].(map[string]interface{})
+	delete(namespace, "one")
+	namespace["injected"] = nil
+	delete(bindings, "strux")
+
+	again := rt.extensions.GetAllBindings()
+	namespace = again["acme"].(map[string]interface{})
+	if _, ok := namespace["one"]; !ok {
+		t.Error("deleting from a snapshot removed the extension")
+	}
+	if _, ok := namespace["injected"]; ok {
+		t.Error("adding to a snapshot added an extension")
+	}
+	if _, ok := again["strux"]; !ok {
+		t.Error("deleting a snapshot namespace removed it")
+	}
+}
+
+type returnsMethods struct{}
+
+func (returnsMethods) Name() string                   { return "" }
+func (returnsMethods) Lookup(key string) (int, error) { return 0, nil }
+func (returnsMethods) Save(data string) error         { return nil }
+func (returnsMethods) Pair() (string, bool)           { return "", false }
+func (returnsMethods) Reset()                         {}
+
+func TestExtensionReturnTypes(t *testing.T) {
+	rt := New(struct{}{})
+	if err := rt.registerExtension(testExtension{sub: "returns"}, returnsMethods{}); err != nil {
+		t.Fatal(err)
+	}
+
+	methods := rt.extensions.GetAllBindings()["acme"].(map[string]interface{})["returns"].(map[string]interface{})["methods"].([]extension.MethodInfo)
+	infos := make(map[string]extension.MethodInfo, len(methods))
+	for _, method := range methods {
+		infos[method.Name] = method
+	}
+	checks := []struct {
+		name     string
+		returns  []string
+		hasError bool
+	}{
+		{"Name", []string{"string"}, false},
+		{"Lookup", []string{"int"}, true},
+		{"Save", []string{}, true},
+		{"Pair", []string{"string", "bool"}, false},
+		{"Reset", []string{}, false},
+	}
+	for _, check := range checks {
+		info := infos[check.name]
+		if fmt.Sprint(info.ReturnTypes) != fmt.Sprint(check.returns) || info.HasError != check.hasError {
+			t.Errorf("%s: ReturnTypes %v HasError %v, want %v %v", check.name, info.ReturnTypes, info.HasError, check.returns, check.hasError)
+		}
+	}
+
+	var out strings.Builder
+	if err := rt.GenerateTypeScriptTo(&out); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"export function Name(): Promise<string>;",
+		"export function Lookup(arg0: string): Promise<number | null>;",
+		"export function Save(arg0: string): Promise<void>;",
+		"export function Pair(): Promise<[string, boolean]>;",
+		"export function Reset(): Promise<void>;",
+	} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("generated TypeScript lacks %q:\n%s", want, out.String())
+		}
+	}
+}
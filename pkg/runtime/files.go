@@ -0,0 +1,93 @@
+package runtime
+
+import (
+	"errors"
+	"mime"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// filesPrefix is the URL prefix the file download handler is mounted at
+const filesPrefix = "/files/"
+
+// filesHandler streams files below baseDir as downloads. http.ServeContent
+// supplies Content-Type, Last-Modified and Range handling. Requests whose
+// path, after cleaning and resolving symlinks, leaves baseDir are rejected.
+// authorize must approve every request; without it every request is
+// refused, so a FilesDir set on its own exposes nothing.
+func filesHandler(baseDir string, authorize func(*http.Request) bool) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			w.Header().Set("Allow", "GET, HEAD")
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		if authorize == nil {
+			http.Error(w, "forbidden", http.StatusForbidden)
+			return
+		}
+		if !authorize(r) {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		name, err := resolveFilePath(baseDir, strings.TrimPrefix(r.URL.Path, filesPrefix))
+		if err != nil {
+			http.Error(w, "forbidden", http.StatusForbidden)
+			return
+		}
+
+		file, err := os.Open(name)
+		if err != nil {
+			http.NotFound(w, r)
+			return
+		}
+		defer file.Close()
+
+		info, err := file.Stat()
+		if err != nil || !info.Mode().IsRegular() {
+			http.NotFound(w, r)
+			return
+		}
+
+		w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": info.Name()}))
+		http.ServeContent(w, r, info.Name(), info.ModTime(), file)
+	})
+}
+
+// errOutsideBase is returned for paths that escape the files base directory
+var errOutsideBase = errors.New("path is outside the files directory")
+
+// resolveFilePath maps a request path onto baseDir, following symlinks, and
+// fails if the result is not inside baseDir
+func resolveFilePath(baseDir, requestPath string) (string, error) {
+	base, err := filepath.EvalSymlinks(baseDir)
+	if err != nil {
+		return "", err
+	}
+	base, err = filepath.Abs(base)
+	if err != nil {
+		return "", err
+	}
+
+	// Cleaning against "/" drops any leading ".." before joining
+	joined := filepath.Join(base, filepath.FromSlash(path.Clean("/"+requestPath)))
+
+	resolved, err := filepath.EvalSymlinks(joined)
+	if err != nil {
+		if os.IsNotExist(err) {
+			// Let the caller answer 404; joined is already inside base
+			return joined, nil
+		}
+		return "", err
+	}
+
+	rel, err := filepath.Rel(base, resolved)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", errOutsideBase
+	}
+	return resolved, nil
+}
@@ -0,0 +1,70 @@
+package runtime
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestFilesHandler(t *testing.T) {
+	base := t.TempDir()
+	if err := os.WriteFile(filepath.Join(base, "app.log"), []byte("hello"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	allow := func(*http.Request) bool { return true }
+	deny := func(*http.Request) bool { return false }
+
+	tests := []struct {
+		name      string
+		authorize func(*http.Request) bool
+		path      string
+		want      int
+	}{
+		{"no authorizer", nil, "/files/app.log", http.StatusForbidden},
+		{"refused", deny, "/files/app.log", http.StatusUnauthorized},
+		{"approved", allow, "/files/app.log", http.StatusOK},
+		{"missing", allow, "/files/other.log", http.StatusNotFound},
+		{"directory", allow, "/files/", http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			filesHandler(base, tt.authorize).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, tt.path, nil))
+			if rec.Code != tt.want {
+				t.Fatalf("GET %s = %d, want %d", tt.path, rec.Code, tt.want)
+			}
+			if tt.want == http.StatusOK {
+				if body := rec.Body.String(); body != "hello" {
+					t.Errorf("body = %q", body)
+				}
+				if got := rec.Header().Get("Content-Disposition"); got != `attachment; filename=app.log` {
+					t.Errorf("Content-Disposition = %q", got)
+				}
+			}
+		})
+	}
+}
+
+func TestResolveFilePathStaysInBase(t *testing.T) {
+	base := t.TempDir()
+	outside := t.TempDir()
+	if err := os.Symlink(outside, filepath.Join(base, "escape")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(outside, "secret"), nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := resolveFilePath(base, "escape/secret"); err != errOutsideBase {
+		t.Errorf("symlink out of base: got %v, want errOutsideBase", err)
+	}
+	name, err := resolveFilePath(base, "../../etc/passwd")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rel, _ := filepath.Rel(base, name); rel != filepath.Join("etc", "passwd") {
+		t.Errorf("../ resolved to %s, outside %s", name, base)
+	}
+}
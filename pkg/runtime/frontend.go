@@ -0,0 +1,254 @@
+package runtime
+
+import (
+	"errors"
+	"fmt"
+	"html"
+	"io/fs"
+	"mime"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// overlayFS serves files from several directories, earlier ones shadowing later ones
+type overlayFS []http.Dir
+
+// Open returns the file from the first directory that has it
+func (o overlayFS) Open(name string) (http.File, error) {
+	var firstErr error
+	for _, dir := range o {
+		file, err := dir.Open(name)
+		if err == nil {
+			return file, nil
+		}
+		if firstErr == nil || !errors.Is(err, fs.ErrNotExist) {
+			firstErr = err
+		}
+	}
+	if firstErr == nil {
+		firstErr = fs.ErrNotExist
+	}
+	return nil, firstErr
+}
+
+// exists reports whether name is present in any directory
+func (o overlayFS) exists(name string) bool {
+	file, err := o.Open(name)
+	if err != nil {
+		return false
+	}
+	file.Close()
+	return true
+}
+
+// spaFallback serves the root index.html for unknown extensionless paths so
+// client-side routes survive a reload. Requests for missing assets still 404.
+func spaFallback(root overlayFS, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		name := path.Clean("/" + r.URL.Path)
+		if path.Ext(name) == "" && !root.exists(name) && root.exists("/index.html") {
+			r2 := r.Clone(r.Context())
+			r2.URL.Path = "/"
+			r2.URL.RawPath = ""
+			next.ServeHTTP(w, r2)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// precompressedEncodings are the sibling files looked for next to a requested
+// file, in order of preference
+var precompressedEncodings = []struct {
+	encoding string // Content-Encoding and Accept-Encoding token
+	suffix   string
+}{
+	{"br", ".br"},
+	{"gzip", ".gz"},
+}
+
+// servePrecompressed serves a pre-compressed sibling of the requested file
+// (app.js.br or app.js.gz for app.js) when the client accepts its encoding,
+// preferring brotli. Siblings are only taken from the directory that serves
+// the file itself, and ranged requests always get the uncompressed file.
+func servePrecompressed(root overlayFS, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Range") != "" || (r.Method != http.MethodGet && r.Method != http.MethodHead) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		// http.FileServer redirects /dir to /dir/ and /index.html to ./;
+		// leave those to it
+		name := path.Clean("/" + r.URL.Path)
+		if strings.HasSuffix(r.URL.Path, "/index.html") {
+			next.ServeHTTP(w, r)
+			return
+		}
+		if root.isDir(name) {
+			if !strings.HasSuffix(r.URL.Path, "/") {
+				next.ServeHTTP(w, r)
+				return
+			}
+			name = path.Join(name, "index.html")
+		}
+		dir, ok := root.dirOf(name)
+		contentType := mime.TypeByExtension(path.Ext(name))
+		if !ok || contentType == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		accepted := acceptedEncodings(r.Header.Get("Accept-Encoding"))
+		varied := false
+		for _, candidate := range precompressedEncodings {
+			file, err := dir.Open(name + candidate.suffix)
+			if err != nil {
+				continue
+			}
+			info, err := file.Stat()
+			if err != nil || info.IsDir() {
+				file.Close()
+				continue
+			}
+
+			// The response now depends on Accept-Encoding, whichever file is sent
+			if !varied {
+				w.Header().Add("Vary", "Accept-Encoding")
+				varied = true
+			}
+			if !accepted[candidate.encoding] {
+				file.Close()
+				continue
+			}
+			defer file.Close()
+			w.Header().Set("Content-Type", contentType)
+			w.Header().Set("Content-Encoding", candidate.encoding)
+			http.ServeContent(w, r, name, info.ModTime(), file)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// acceptedEncodings parses an Accept-Encoding header into the set of
+// encodings it allows, leaving out those refused with q=0
+func acceptedEncodings(header string) map[string]bool {
+	accepted := make(map[string]bool)
+	for _, part := range strings.Split(header, ",") {
+		encoding, params, _ := strings.Cut(part, ";")
+		encoding = strings.ToLower(strings.TrimSpace(encoding))
+		if encoding == "" {
+			continue
+		}
+		if q, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			if weight, err := strconv.ParseFloat(q, 64); err == nil && weight == 0 {
+				continue
+			}
+		}
+		accepted[encoding] = true
+	}
+	return accepted
+}
+
+// dirOf returns the first directory containing the regular file name
+func (o overlayFS) dirOf(name string) (http.Dir, bool) {
+	for _, dir := range o {
+		file, err := dir.Open(name)
+		if err != nil {
+			continue
+		}
+		info, err := file.Stat()
+		file.Close()
+		if err == nil && !info.IsDir() {
+			return dir, true
+		}
+		// A directory shadows the same name further down, as in Open
+		return "", false
+	}
+	return "", false
+}
+
+// isDir reports whether name is a directory in the first directory that has it
+func (o overlayFS) isDir(name string) bool {
+	file, err := o.Open(name)
+	if err != nil {
+		return false
+	}
+	defer file.Close()
+	info, err := file.Stat()
+	return err == nil && info.IsDir()
+}
+
+// hideDirListings answers 404 for directories without an index.html instead
+// of letting http.FileServer list their contents
+func hideDirListings(root overlayFS, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		name := path.Clean("/" + r.URL.Path)
+		if root.isDir(name) && !root.exists(path.Join(name, "index.html")) {
+			http.NotFound(w, r)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// missingFrontendPage is served in place of the app while no frontend
+// directory has an index.html, typically because the frontend was not built
+const missingFrontendPage = `<!DOCTYPE html>
+<html>
+<head><meta charset="utf-8"><title>Strux: frontend not found</title></head>
+<body style="font-family: sans-serif; max-width: 40em; margin: 4em auto">
+<h1>Frontend not found</h1>
+<p>No index.html was found in %s.</p>
+<p>Build the frontend into that directory, or point ServerOptions.FrontendDir
+at the build output. This page is replaced as soon as index.html exists.</p>
+</body>
+</html>
+`
+
+// frontendPlaceholder answers page requests with missingFrontendPage while
+// no directory has an index.html, instead of 404s or a directory listing.
+// The check runs per request so a build finishing later is picked up.
+func frontendPlaceholder(root overlayFS, dirs []string, next http.Handler) http.Handler {
+	page := fmt.Sprintf(missingFrontendPage, html.EscapeString(strings.Join(dirs, ", ")))
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if path.Ext(r.URL.Path) == "" && !root.exists("/index.html") {
+			w.Header().Set("Content-Type", "text/html; charset=utf-8")
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(page))
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// checkFrontendDirs describes what is wrong with the frontend directories:
+// ones that do not exist, and a missing index.html. It returns nil when the
+// frontend can be served.
+func checkFrontendDirs(dirs []string) []string {
+	var problems []string
+	hasIndex := false
+	for _, dir := range dirs {
+		info, err := os.Stat(dir)
+		if err != nil {
+			problems = append(problems, fmt.Sprintf("frontend directory %s does not exist", dir))
+			continue
+		}
+		if !info.IsDir() {
+			problems = append(problems, fmt.Sprintf("frontend path %s is not a directory", dir))
+			continue
+		}
+		if _, err := os.Stat(filepath.Join(dir, "index.html")); err == nil {
+			hasIndex = true
+		}
+	}
+	if hasIndex {
+		return nil
+	}
+	return append(problems, fmt.Sprintf("no index.html in %s; serving a placeholder page", strings.Join(dirs, ", ")))
+}
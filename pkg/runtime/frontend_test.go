@@ -0,0 +1,48 @@
+package runtime
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestFrontendServesRangeRequests(t *testing.T) {
+	dir := t.TempDir()
+	video := make([]byte, 4<<20)
+	for i := range video {
+		video[i] = byte(i % 251)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "clip.mp4"), video, 0644); err != nil {
+		t.Fatal(err)
+	}
+	// A precompressed sibling must not be sent for a ranged request
+	if err := os.WriteFile(filepath.Join(dir, "clip.mp4.gz"), []byte("not the clip"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	handler := frontendHandler(ServerOptions{FrontendDirs: []string{dir}})
+
+	req := httptest.NewRequest(http.MethodGet, "/clip.mp4", nil)
+	req.Header.Set("Range", "bytes=1048576-1049599")
+	req.Header.Set("Accept-Encoding", "gzip, br")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusPartialContent {
+		t.Fatalf("status = %d, want 206", rec.Code)
+	}
+	if got, want := rec.Header().Get("Content-Range"), fmt.Sprintf("bytes 1048576-1049599/%d", len(video)); got != want {
+		t.Errorf("Content-Range = %q, want %q", got, want)
+	}
+	if got := rec.Header().Get("Accept-Ranges"); got != "bytes" {
+		t.Errorf("Accept-Ranges = %q, want bytes", got)
+	}
+	if got := rec.Header().Get("Content-Encoding"); got != "" {
+		t.Errorf("Content-Encoding = %q on a ranged response", got)
+	}
+	if body := rec.Body.Bytes(); string(body) != string(video[1048576:1049600]) {
+		t.Errorf("got %d bytes that don't match the requested range", len(body))
+	}
+}
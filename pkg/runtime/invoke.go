@@ -0,0 +1,131 @@
+package runtime
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+	"sync/atomic"
+)
+
+// replyMethod is the method a frontend uses to answer an Invocation. The
+// message's id is the invocation's call ID and its params are [result, error].
+const replyMethod = "__reply"
+
+// invokeIDPrefix keeps backend call IDs apart from the frontend's request IDs
+const invokeIDPrefix = "go-"
+
+// ErrNoFrontend is returned by Invoke when no subscribed frontend is connected
+var ErrNoFrontend = errors.New("no frontend subscribed")
+
+// Invocation is sent to subscribed IPC clients when Go calls into the
+// frontend with Invoke. Call is the correlation ID to answer with.
+type Invocation struct {
+	Call   string        `json:"call"`
+	Method string        `json:"method"`
+	Params []interface{} `json:"params"`
+}
+
+// invokeReply is a frontend's answer to an Invocation
+type invokeReply struct {
+	result json.RawMessage
+	err    error
+}
+
+// invocations tracks backend calls awaiting a reply
+type invocations struct {
+	mu      sync.Mutex
+	nextID  atomic.Uint64
+	pending map[string]chan invokeReply
+}
+
+// Invoke calls method on the frontend with args and waits for its reply or
+// for ctx to end. The request goes to every subscribed client (see
+// __subscribe) and the first reply wins. The result is returned as raw JSON
+// for the caller to unmarshal.
+func (rt *Runtime) Invoke(ctx context.Context, method string, args ...interface{}) (json.RawMessage, error) {
+	if args == nil {
+		args = []interface{}{}
+	}
+
+	rt.mu.RLock()
+	clients := make([]*ipcClient, 0, len(rt.subscribers))
+	for client := range rt.subscribers {
+		clients = append(clients, client)
+	}
+	rt.mu.RUnlock()
+
+	if len(clients) == 0 {
+		return nil, ErrNoFrontend
+	}
+
+	id := invokeIDPrefix + strconv.FormatUint(rt.invokes.nextID.Add(1), 10)
+	reply := make(chan invokeReply, 1)
+
+	rt.invokes.mu.Lock()
+	if rt.invokes.pending == nil {
+		rt.invokes.pending = make(map[string]chan invokeReply)
+	}
+	rt.invokes.pending[id] = reply
+	rt.invokes.mu.Unlock()
+
+	defer func() {
+		rt.invokes.mu.Lock()
+		delete(rt.invokes.pending, id)
+		rt.invokes.mu.Unlock()
+	}()
+
+	sent := 0
+	var sendErr error
+	for _, client := range clients {
+		if err := rt.send(client, client.codec.invocation(Invocation{Call: id, Method: method, Params: args})); err != nil {
+			sendErr = err
+			continue
+		}
+		sent++
+	}
+	if sent == 0 {
+		return nil, fmt.Errorf("failed to invoke %s: %w", method, sendErr)
+	}
+
+	select {
+	case r := <-reply:
+		return r.result, r.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// resolveInvoke delivers a __reply message to the waiting Invoke.
+// Replies to unknown or already answered calls are dropped.
+func (rt *Runtime) resolveInvoke(msg Message) {
+	var params []json.RawMessage
+	if len(msg.Params) > 0 {
+		if err := json.Unmarshal(msg.Params, &params); err != nil {
+			fmt.Printf("Strux Runtime: malformed reply to %s: %v\n", msg.ID, err)
+			return
+		}
+	}
+
+	var r invokeReply
+	if len(params) > 0 {
+		r.result = params[0]
+	}
+	if len(params) > 1 {
+		var message string
+		if err := json.Unmarshal(params[1], &message); err == nil && message != "" {
+			r.err = errors.New(message)
+		}
+	}
+
+	rt.invokes.mu.Lock()
+	reply, ok := rt.invokes.pending[msg.ID]
+	delete(rt.invokes.pending, msg.ID)
+	rt.invokes.mu.Unlock()
+
+	if ok {
+		reply <- r
+	}
+}
@@ -0,0 +1,146 @@
+package runtime
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// DefaultMaxMessageBytes is the default limit on a single inbound or outbound
+// IPC frame
+const DefaultMaxMessageBytes = 16 << 20
+
+// messageTooLargeError is the error returned to the caller for a frame over
+// the limit, in either direction
+const messageTooLargeError = "message_too_large"
+
+// frameHeadBytes is how much of a skipped frame is kept to recover its id
+const frameHeadBytes = 4096
+
+// errMessageTooLarge is returned by frame decoders for an inbound frame over
+// the limit. skipped is true when the frame was consumed and the connection
+// can carry on; otherwise its framing is lost and it must be closed.
+type errMessageTooLarge struct {
+	size    int64 // frame size, or -1 if the frame was cut off before its end
+	skipped bool
+	head    []byte // the start of a skipped frame, for frameHead
+}
+
+func (e *errMessageTooLarge) Error() string {
+	if e.size < 0 {
+		return "message exceeds size limit"
+	}
+	return fmt.Sprintf("message of %d bytes exceeds size limit", e.size)
+}
+
+// SetMaxMessageBytes limits the size of every IPC frame read or written.
+// Oversized requests and results are answered with a "message_too_large"
+// error instead of being decoded or sent; n <= 0 restores the default.
+func (rt *Runtime) SetMaxMessageBytes(n int64) {
+	if n <= 0 {
+		n = DefaultMaxMessageBytes
+	}
+	rt.mu.Lock()
+	defer rt.mu.Unlock()
+	rt.maxMessageBytes = n
+}
+
+// messageLimit returns the current frame size limit
+func (rt *Runtime) messageLimit() int64 {
+	rt.mu.RLock()
+	defer rt.mu.RUnlock()
+	return rt.maxMessageBytes
+}
+
+// frameLimitReader fails once more than limit bytes are read between resets.
+// It sits under a json.Decoder, which reads ahead, so the count can include
+// the start of the next frame; the limit is a bound, not an exact size.
+type frameLimitReader struct {
+	r     io.Reader
+	limit func() int64
+	n     int64
+}
+
+func (l *frameLimitReader) Read(p []byte) (int, error) {
+	if l.n > l.limit() {
+		return 0, &errMessageTooLarge{size: -1}
+	}
+	n, err := l.r.Read(p)
+	l.n += int64(n)
+	return n, err
+}
+
+// reset starts counting a new frame
+func (l *frameLimitReader) reset() {
+	l.n = 0
+}
+
+// limitedDecoder is a json.Decoder whose frames are bounded by a frameLimitReader
+type limitedDecoder struct {
+	*json.Decoder
+	limiter *frameLimitReader
+}
+
+func newLimitedDecoder(r io.Reader, limit func() int64) *limitedDecoder {
+	limiter := &frameLimitReader{r: r, limit: limit}
+	return &limitedDecoder{Decoder: json.NewDecoder(limiter), limiter: limiter}
+}
+
+func (d *limitedDecoder) Decode(v interface{}) error {
+	d.limiter.reset()
+	return d.Decoder.Decode(v)
+}
+
+// frameHead recovers what it can from the start of an oversized frame: the
+// raw top-level "id" value, if one appears in head, and whether the frame
+// looks like JSON-RPC
+func frameHead(head []byte) (id json.RawMessage, jsonRPC bool) {
+	if isJSONArray(head) {
+		return nil, true // a JSON-RPC batch, answered with a null id
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(head))
+	if tok, err := decoder.Token(); err != nil || tok != json.Delim('{') {
+		return nil, false
+	}
+	for decoder.More() {
+		tok, err := decoder.Token()
+		if err != nil {
+			break
+		}
+		var value json.RawMessage
+		if err := decoder.Decode(&value); err != nil {
+			break // the head ended inside this value
+		}
+		switch tok {
+		case "id":
+			id = value
+		case "jsonrpc":
+			jsonRPC = true
+		}
+	}
+	return id, jsonRPC
+}
+
+// send encodes v to client unless it exceeds the frame limit, in which case
+// it returns errMessageTooLarge without writing anything
+func (rt *Runtime) send(client *ipcClient, v interface{}) error {
+	frame, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	if size := int64(len(frame)); size > rt.messageLimit() {
+		return &errMessageTooLarge{size: size}
+	}
+	return client.Encode(json.RawMessage(frame))
+}
+
+// tooLargeResponses replaces every response with a message_too_large error
+func tooLargeResponses(resps []Response) []Response {
+	out := make([]Response, len(resps))
+	for i, resp := range resps {
+		out[i] = Response{ID: resp.ID, Error: messageTooLargeError}
+	}
+	return out
+}
@@ -0,0 +1,178 @@
+package runtime
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"io"
+	"strings"
+	"testing"
+)
+
+type limitApp struct{}
+
+func (limitApp) Echo(s string) string { return s }
+
+func (limitApp) Big(n int) string { return strings.Repeat("x", n) }
+
+// stdioConn is the host end of a bridge served over length-prefixed frames
+type stdioConn struct {
+	t     *testing.T
+	enc   *lengthPrefixedEncoder
+	dec   *lengthPrefixedDecoder
+	ended chan struct{} // closed when serveFrames returns
+}
+
+func serveStdioTest(t *testing.T, rt *Runtime) *stdioConn {
+	t.Helper()
+	inR, inW := io.Pipe()
+	outR, outW := io.Pipe()
+	c := &stdioConn{
+		t:     t,
+		enc:   &lengthPrefixedEncoder{w: inW},
+		dec:   &lengthPrefixedDecoder{r: outR, limit: func() int64 { return DefaultMaxMessageBytes }},
+		ended: make(chan struct{}),
+	}
+	go func() {
+		defer close(c.ended)
+		rt.serveFrames(&lengthPrefixedDecoder{r: inR, limit: rt.messageLimit}, &lengthPrefixedEncoder{w: outW})
+	}()
+	t.Cleanup(func() {
+		inW.Close()
+		outR.Close()
+	})
+	return c
+}
+
+// sendRaw writes body as one frame, whether or not it is valid JSON
+func (c *stdioConn) sendRaw(body string) {
+	c.t.Helper()
+	frame := binary.BigEndian.AppendUint32(nil, uint32(len(body)))
+	if _, err := c.enc.w.Write(append(frame, body...)); err != nil {
+		c.t.Fatalf("send: %v", err)
+	}
+}
+
+func (c *stdioConn) call(id, method string, params ...interface{}) {
+	c.t.Helper()
+	raw, _ := json.Marshal(params)
+	if params == nil {
+		raw = []byte("[]")
+	}
+	if err := c.enc.Encode(Message{ID: id, Method: method, Params: raw}); err != nil {
+		c.t.Fatalf("send: %v", err)
+	}
+}
+
+func (c *stdioConn) read(v interface{}) {
+	c.t.Helper()
+	if err := c.dec.Decode(v); err != nil {
+		c.t.Fatalf("read: %v", err)
+	}
+}
+
+func TestOversizedRequestIsAnsweredAndConnectionStaysUsable(t *testing.T) {
+	rt := New(limitApp{})
+	rt.SetMaxMessageBytes(1024)
+	c := serveStdioTest(t, rt)
+
+	c.call("big", "Echo", strings.Repeat("x", 4096))
+	var resp Response
+	c.read(&resp)
+	if resp.ID != "big" || resp.Error != messageTooLargeError {
+		t.Fatalf("got %+v, want message_too_large for big", resp)
+	}
+
+	c.call("small", "Echo", "hi")
+	resp = Response{}
+	c.read(&resp)
+	if resp.ID != "small" || resp.Result != "hi" {
+		t.Fatalf("got %+v after an oversized frame", resp)
+	}
+}
+
+func TestOversizedRequestWithoutIDGetsNoReply(t *testing.T) {
+	rt := New(limitApp{})
+	rt.SetMaxMessageBytes(1024)
+	c := serveStdioTest(t, rt)
+
+	// The id comes after the part of the frame that is kept, so the client
+	// could not match an error to the call; nothing is sent for it
+	c.sendRaw(`{"method":"Echo","params":["` + strings.Repeat("x", 2*frameHeadBytes) + `"],"id":"lost"}`)
+	c.call("next", "Echo", "hi")
+
+	var resp Response
+	c.read(&resp)
+	if resp.ID != "next" || resp.Result != "hi" {
+		t.Fatalf("got %+v, want only the answer to next", resp)
+	}
+}
+
+func TestOversizedResultIsReplacedWithError(t *testing.T) {
+	rt := New(limitApp{})
+	rt.SetMaxMessageBytes(1024)
+	c := serveStdioTest(t, rt)
+
+	c.call("1", "Big", 4096)
+	var resp Response
+	c.read(&resp)
+	if resp.ID != "1" || resp.Error != messageTooLargeError {
+		t.Fatalf("got %+v, want message_too_large", resp)
+	}
+
+	c.call("2", "Big", 10)
+	resp = Response{}
+	c.read(&resp)
+	if resp.ID != "2" || resp.Result != strings.Repeat("x", 10) {
+		t.Fatalf("got %+v after an oversized result", resp)
+	}
+}
+
+func TestOversizedJSONRPCRequestOnSocket(t *testing.T) {
+	rt := New(limitApp{})
+	rt.EnableJSONRPC()
+	rt.SetMaxMessageBytes(1024)
+	c := dialTest(t, rt)
+
+	c.send(map[string]interface{}{"jsonrpc": "2.0", "id": 1, "method": "Echo", "params": []string{"hi"}})
+	var ok map[string]interface{}
+	if err := c.dec.Decode(&ok); err != nil || ok["result"] != "hi" {
+		t.Fatalf("got %v, %v", ok, err)
+	}
+
+	// The socket's framing is lost after an oversized frame, so the client
+	// gets an error with a null id and the connection is closed. The write
+	// goes in a goroutine because net.Pipe blocks it once reading stops.
+	go c.enc.Encode(map[string]interface{}{"jsonrpc": "2.0", "id": 2, "method": "Echo", "params": []string{strings.Repeat("x", 4096)}})
+	var resp struct {
+		ID    json.RawMessage `json:"id"`
+		Error *jsonRPCError   `json:"error"`
+	}
+	if err := c.dec.Decode(&resp); err != nil {
+		t.Fatalf("read: %v", err)
+	}
+	if string(resp.ID) != "null" || resp.Error == nil || resp.Error.Code != jsonRPCInvalidRequest {
+		t.Fatalf("got id %s error %+v, want -32600 with a null id", resp.ID, resp.Error)
+	}
+	c.conn.Close()
+	<-c.done
+}
+
+func TestFrameHead(t *testing.T) {
+	tests := []struct {
+		head    string
+		id      string
+		jsonRPC bool
+	}{
+		{`{"id":"a","method":"Echo","params":["xxx`, `"a"`, false},
+		{`{"jsonrpc":"2.0","id":7,"method":"Echo","params":["xx`, `7`, true},
+		{`{"method":"Echo","params":["xxxxxxxx`, ``, false},
+		{`[{"jsonrpc":"2.0","id":1`, ``, true},
+		{`not json`, ``, false},
+	}
+	for _, tt := range tests {
+		id, jsonRPC := frameHead([]byte(tt.head))
+		if string(id) != tt.id || jsonRPC != tt.jsonRPC {
+			t.Errorf("frameHead(%q) = %s, %v; want %s, %v", tt.head, id, jsonRPC, tt.id, tt.jsonRPC)
+		}
+	}
+}
@@ -0,0 +1,63 @@
+package runtime
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"sync/atomic"
+	"time"
+)
+
+// runtimeMetrics counts IPC activity for the /metrics endpoint.
+// The zero value is ready to use.
+type runtimeMetrics struct {
+	calls       atomic.Uint64
+	callErrors  atomic.Uint64
+	connections atomic.Int64
+	bytesSent   atomic.Uint64
+	events      atomic.Uint64
+}
+
+// recordCall counts a dispatched call and whether it failed
+func (m *runtimeMetrics) recordCall(resp Response) {
+	m.calls.Add(1)
+	if resp.Error != "" {
+		m.callErrors.Add(1)
+	}
+}
+
+// countingWriter counts the bytes written through it
+type countingWriter struct {
+	w     io.Writer
+	count *atomic.Uint64
+}
+
+func (c countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.count.Add(uint64(n))
+	return n, err
+}
+
+// MetricsHandler serves runtime metrics in the Prometheus text format.
+// It is mounted at /metrics by StartWithOptions when ServerOptions.Metrics is set.
+func (rt *Runtime) MetricsHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rt.stopMu.Lock()
+		inFlight := rt.inFlight
+		rt.stopMu.Unlock()
+
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		writeMetric(w, "strux_uptime_seconds", "gauge", "Seconds since the runtime was created", time.Since(rt.startTime).Seconds())
+		writeMetric(w, "strux_ipc_connections", "gauge", "Open IPC connections", float64(rt.metrics.connections.Load()))
+		writeMetric(w, "strux_ipc_calls_in_flight", "gauge", "IPC calls currently executing", float64(inFlight))
+		writeMetric(w, "strux_ipc_calls_total", "counter", "IPC calls dispatched", float64(rt.metrics.calls.Load()))
+		writeMetric(w, "strux_ipc_call_errors_total", "counter", "IPC calls that returned an error", float64(rt.metrics.callErrors.Load()))
+		writeMetric(w, "strux_ipc_sent_bytes_total", "counter", "Bytes written to IPC clients", float64(rt.metrics.bytesSent.Load()))
+		writeMetric(w, "strux_events_emitted_total", "counter", "Events emitted with Emit", float64(rt.metrics.events.Load()))
+	})
+}
+
+// writeMetric writes one unlabelled metric with its HELP and TYPE lines
+func writeMetric(w io.Writer, name, kind, help string, value float64) {
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n%s %g\n", name, help, name, kind, name, value)
+}
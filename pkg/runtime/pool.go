@@ -0,0 +1,167 @@
+package runtime
+
+import "sync"
+
+// Defaults for the worker pool that runs IPC calls, sized for embedded targets
+const (
+	DefaultWorkers    = 8
+	DefaultQueueDepth = 64
+)
+
+// connectionBacklog is how many frames one connection may have queued or
+// running before its read loop stops reading more
+const connectionBacklog = 32
+
+// overloadedError is returned for calls that arrive while every worker is
+// busy and the queue is full
+const overloadedError = "overloaded"
+
+// workerPool runs jobs on a fixed set of goroutines. Jobs are submitted to a
+// serialQueue, normally one per connection: each queue runs one job at a
+// time in submission order, while different queues run in parallel. Up to
+// queueDepth idle queues may wait for a free worker; submit refuses jobs for
+// any more. A job behind an earlier one on its own queue is always accepted,
+// so the caller bounds how many it submits (see connectionBacklog).
+type workerPool struct {
+	mu         sync.Mutex
+	cond       *sync.Cond
+	ready      []*serialQueue // queues with a job to run and no worker on them
+	idle       int            // workers not running a job
+	queueDepth int
+	stopped    bool
+}
+
+// serialQueue holds one connection's jobs; see workerPool
+type serialQueue struct {
+	jobs   []func()
+	active bool // in the pool's ready list or being run by a worker
+}
+
+func newWorkerPool(workers, queueDepth int) *workerPool {
+	p := &workerPool{idle: workers, queueDepth: queueDepth}
+	p.cond = sync.NewCond(&p.mu)
+	for i := 0; i < workers; i++ {
+		go p.run()
+	}
+	return p
+}
+
+func (p *workerPool) run() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for {
+		for len(p.ready) == 0 {
+			// Once stopped, workers exit only when nothing is left to run
+			if p.stopped {
+				return
+			}
+			p.cond.Wait()
+		}
+
+		q := p.ready[0]
+		p.ready = p.ready[1:]
+		job := q.jobs[0]
+		q.jobs = q.jobs[1:]
+		p.idle--
+
+		p.mu.Unlock()
+		job()
+		p.mu.Lock()
+		p.idle++
+
+		// Requeue at the back so a busy connection doesn't starve the others
+		if len(q.jobs) > 0 {
+			p.ready = append(p.ready, q)
+		} else {
+			q.active = false
+		}
+	}
+}
+
+// submit adds job to q, reporting false if q would have to wait for a worker
+// while queueDepth queues already are, or if the pool has stopped. It never
+// blocks.
+func (p *workerPool) submit(q *serialQueue, job func()) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.stopped {
+		return false
+	}
+	if !q.active && p.waitingLocked() >= p.queueDepth {
+		return false
+	}
+
+	q.jobs = append(q.jobs, job)
+	if !q.active {
+		q.active = true
+		p.ready = append(p.ready, q)
+		p.cond.Signal()
+	}
+	return true
+}
+
+// waitingLocked returns how many ready queues no free worker will take; p.mu
+// must be held
+func (p *workerPool) waitingLocked() int {
+	return len(p.ready) - p.idle
+}
+
+// stop refuses further jobs. Jobs already accepted still run, so whoever
+// submitted them is answered; the workers exit once none are left.
+func (p *workerPool) stop() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.stopped = true
+	p.cond.Broadcast()
+}
+
+// SetWorkerPool sizes the pool that runs IPC calls: up to workers calls
+// execute at once, and calls from up to queueDepth more connections wait for
+// a worker. Calls from further connections are answered with an "overloaded"
+// error. A connection's calls run one frame at a time in the order they
+// arrived, so a call never overtakes one sent before it. workers <= 0 or
+// queueDepth 0 keeps the default; a negative queueDepth disables queueing.
+// Call before Start.
+func (rt *Runtime) SetWorkerPool(workers, queueDepth int) {
+	if workers <= 0 {
+		workers = DefaultWorkers
+	}
+	if queueDepth == 0 {
+		queueDepth = DefaultQueueDepth
+	} else if queueDepth < 0 {
+		queueDepth = 0
+	}
+	rt.mu.Lock()
+	defer rt.mu.Unlock()
+	rt.workers = workers
+	rt.queueDepth = queueDepth
+}
+
+// workerPool returns the call pool, starting it on first use. It returns
+// nil once Stop has run.
+func (rt *Runtime) workerPool() *workerPool {
+	rt.poolOnce.Do(func() {
+		rt.mu.RLock()
+		defer rt.mu.RUnlock()
+		rt.pool = newWorkerPool(rt.workers, rt.queueDepth)
+	})
+	return rt.pool
+}
+
+// stopWorkerPool stops the call pool if one was started, and keeps a later
+// workerPool call from starting one
+func (rt *Runtime) stopWorkerPool() {
+	rt.poolOnce.Do(func() {})
+	if rt.pool != nil {
+		rt.pool.stop()
+	}
+}
+
+// overloadedResponses answers every call in msgs with an overloaded error
+func overloadedResponses(msgs []Message) []Response {
+	out := make([]Response, len(msgs))
+	for i, msg := range msgs {
+		out[i] = Response{ID: msg.ID, Error: overloadedError}
+	}
+	return out
+}
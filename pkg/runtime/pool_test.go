@@ -0,0 +1,278 @@
+package runtime
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// poolApp has a call that holds a worker until released, and a value for
+// checking that one connection's calls run in order
+type poolApp struct {
+	started chan struct{}
+	release chan struct{}
+
+	mu    sync.Mutex
+	value int
+}
+
+func newPoolApp() *poolApp {
+	return &poolApp{started: make(chan struct{}, 16), release: make(chan struct{})}
+}
+
+func (a *poolApp) Block() {
+	a.started <- struct{}{}
+	<-a.release
+}
+
+func (a *poolApp) Set(v int) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.value = v
+}
+
+func (a *poolApp) Get() int {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.value
+}
+
+// testConn is the client end of an IPC connection served by handleConnection
+type testConn struct {
+	t    *testing.T
+	conn net.Conn
+	enc  *json.Encoder
+	dec  *json.Decoder
+	done chan struct{} // closed when handleConnection returns
+}
+
+func dialTest(t *testing.T, rt *Runtime) *testConn {
+	t.Helper()
+	client, server := net.Pipe()
+	c := &testConn{t: t, conn: client, enc: json.NewEncoder(client), dec: json.NewDecoder(client), done: make(chan struct{})}
+	go func() {
+		defer close(c.done)
+		rt.handleConnection(server)
+	}()
+	t.Cleanup(func() { client.Close() })
+	return c
+}
+
+func (c *testConn) send(v interface{}) {
+	c.t.Helper()
+	if err := c.enc.Encode(v); err != nil {
+		c.t.Fatalf("send: %v", err)
+	}
+}
+
+func (c *testConn) call(id, method string, params ...interface{}) {
+	c.t.Helper()
+	if params == nil {
+		params = []interface{}{}
+	}
+	raw, _ := json.Marshal(params)
+	c.send(Message{ID: id, Method: method, Params: raw})
+}
+
+func (c *testConn) read() Response {
+	c.t.Helper()
+	c.conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	var resp Response
+	if err := c.dec.Decode(&resp); err != nil {
+		c.t.Fatalf("read: %v", err)
+	}
+	return resp
+}
+
+// waitFor polls cond until it holds or a few seconds pass
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func (p *workerPool) waiting() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.waitingLocked()
+}
+
+func TestConnectionCallsRunInOrder(t *testing.T) {
+	rt := New(newPoolApp())
+	c := dialTest(t, rt)
+
+	// Without ordering a Get could overtake the Set sent just before it. A
+	// client pipelining far past the queue depth is slowed, not refused.
+	const rounds = 4 * DefaultQueueDepth
+	go func() {
+		for i := 1; i <= rounds; i++ {
+			c.call(fmt.Sprintf("set%d", i), "Set", i)
+			c.call(fmt.Sprintf("get%d", i), "Get")
+		}
+	}()
+	for i := 1; i <= rounds; i++ {
+		if resp := c.read(); resp.ID != fmt.Sprintf("set%d", i) || resp.Error != "" {
+			t.Fatalf("round %d: got %+v, want set response", i, resp)
+		}
+		resp := c.read()
+		if resp.ID != fmt.Sprintf("get%d", i) || resp.Result != float64(i) {
+			t.Fatalf("round %d: got %+v, want %d", i, resp, i)
+		}
+	}
+}
+
+func TestConnectionsRunInParallel(t *testing.T) {
+	app := newPoolApp()
+	rt := New(app)
+	rt.SetWorkerPool(2, -1)
+
+	blocked := dialTest(t, rt)
+	blocked.call("1", "Block")
+	<-app.started
+
+	// A blocked call holds up its own connection, not the others
+	other := dialTest(t, rt)
+	other.call("2", "Get")
+	if resp := other.read(); resp.ID != "2" || resp.Error != "" {
+		t.Fatalf("got %+v", resp)
+	}
+
+	close(app.release)
+	if resp := blocked.read(); resp.ID != "1" || resp.Error != "" {
+		t.Fatalf("got %+v", resp)
+	}
+}
+
+func TestSaturatedPoolAnswersOverloaded(t *testing.T) {
+	app := newPoolApp()
+	rt := New(app)
+	rt.SetWorkerPool(1, 1)
+
+	busy := dialTest(t, rt)
+	busy.call("1", "Block")
+	<-app.started
+
+	// The only worker is busy, so this call takes the only queue slot
+	queued := dialTest(t, rt)
+	queued.call("2", "Get")
+	waitFor(t, "a queued call", func() bool { return rt.workerPool().waiting() == 1 })
+
+	refused := dialTest(t, rt)
+	refused.call("3", "Get")
+	if resp := refused.read(); resp.ID != "3" || resp.Error != overloadedError {
+		t.Fatalf("got %+v, want %q", resp, overloadedError)
+	}
+
+	close(app.release)
+	if resp := busy.read(); resp.ID != "1" || resp.Error != "" {
+		t.Fatalf("got %+v", resp)
+	}
+	if resp := queued.read(); resp.ID != "2" || resp.Error != "" {
+		t.Fatalf("got %+v", resp)
+	}
+	if got := rt.metrics.callErrors.Load(); got != 1 {
+		t.Fatalf("errors metric = %d, want 1", got)
+	}
+}
+
+func TestStopAnswersQueuedCalls(t *testing.T) {
+	app := newPoolApp()
+	rt := New(app)
+	rt.SetWorkerPool(1, 4)
+
+	busy := dialTest(t, rt)
+	busy.call("1", "Block")
+	<-app.started
+
+	queued := dialTest(t, rt)
+	queued.call("2", "Get")
+	waitFor(t, "a queued call", func() bool { return rt.workerPool().waiting() == 1 })
+
+	stopped := make(chan error, 1)
+	go func() { stopped <- rt.Stop() }()
+	waitFor(t, "Stop to begin", func() bool {
+		rt.stopMu.Lock()
+		defer rt.stopMu.Unlock()
+		return rt.stopping
+	})
+	close(app.release)
+
+	if resp := busy.read(); resp.ID != "1" || resp.Error != "" {
+		t.Fatalf("got %+v", resp)
+	}
+	if resp := queued.read(); resp.ID != "2" || resp.Error != "runtime is stopping" {
+		t.Fatalf("got %+v, want a stopping error", resp)
+	}
+	if err := <-stopped; err != nil {
+		t.Fatalf("Stop: %v", err)
+	}
+
+	// Both connections close once their calls are answered
+	for _, c := range []*testConn{busy, queued} {
+		c.conn.Close()
+		select {
+		case <-c.done:
+		case <-time.After(5 * time.Second):
+			t.Fatal("connection still open after Stop")
+		}
+	}
+}
+
+func TestStopDoesNotStartPool(t *testing.T) {
+	rt := New(newPoolApp())
+	if err := rt.Stop(); err != nil {
+		t.Fatal(err)
+	}
+	if rt.pool != nil {
+		t.Fatal("Stop started a worker pool")
+	}
+
+	// Calls after Stop are refused rather than left waiting on a pool
+	c := dialTest(t, rt)
+	c.call("1", "Get")
+	if resp := c.read(); resp.Error != "runtime is stopping" {
+		t.Fatalf("got %+v", resp)
+	}
+}
+
+func TestWorkerPoolStopRunsQueuedJobs(t *testing.T) {
+	p := newWorkerPool(1, 4)
+	release := make(chan struct{})
+	started := make(chan struct{})
+	if !p.submit(&serialQueue{}, func() { close(started); <-release }) {
+		t.Fatal("first job refused")
+	}
+	<-started
+
+	var ran atomic.Int32
+	var wg sync.WaitGroup
+	for i := 0; i < 3; i++ {
+		wg.Add(1)
+		if !p.submit(&serialQueue{}, func() { defer wg.Done(); ran.Add(1) }) {
+			t.Fatalf("job %d refused", i)
+		}
+	}
+
+	p.stop()
+	if p.submit(&serialQueue{}, func() {}) {
+		t.Fatal("job accepted after stop")
+	}
+	close(release)
+
+	done := make(chan struct{})
+	go func() { wg.Wait(); close(done) }()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("only %d of 3 queued jobs ran after stop", ran.Load())
+	}
+}
@@ -1,19 +1,40 @@
 package runtime
 
 import (
+	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"net"
 	"os"
 	"reflect"
 	"strings"
 	"sync"
+	"time"
+	"unicode"
 
 	"github.com/strux-dev/strux/pkg/runtime/extension"
 )
 
 const socketPath = "/tmp/strux-ipc.sock"
 
+// drainTimeout bounds how long Stop waits for in-flight calls to finish
+const drainTimeout = 5 * time.Second
+
+// MethodAliaser may be implemented by the app to expose methods to the
+// frontend under different names, keyed by Go method name:
+//
+//	func (a *App) MethodAliases() map[string]string {
+//		return map[string]string{"GetItems": "items"}
+//	}
+//
+// Return a map literal so strux types can read the aliases without running
+// the app. MethodAliases itself is not exposed.
+type MethodAliaser interface {
+	MethodAliases() map[string]string
+}
+
 // Runtime manages the IPC bridge between Go and JavaScript
 type Runtime struct {
 	app        interface{}
@@ -25,6 +46,34 @@ type Runtime struct {
 	structName string
 	pkgName    string
 	extensions *extension.Registry
+	jsonRPC    bool              // accept JSON-RPC 2.0 connections
+	aliases    map[string]string // Go method name -> exposed name
+	appMethods map[string]string // exposed name -> Go method name, for app methods
+	objects    map[string]bool   // namespaces added with BindObject
+	camelCase  bool              // expose unaliased methods in camelCase, see UseCamelCase
+	bindErr    error             // invalid app or aliases, reported by Start
+
+	events      map[string]reflect.Type // event name -> payload type
+	subscribers map[*ipcClient]bool     // connections receiving emitted events
+
+	stopMu   sync.Mutex
+	stopping bool           // set once Stop begins; new calls are refused
+	calls    sync.WaitGroup // in-flight method calls
+	inFlight int            // number of calls in calls, for metrics
+
+	startTime time.Time
+	metrics   runtimeMetrics
+
+	maxMessageBytes int64 // frame size limit, see SetMaxMessageBytes
+
+	invokes invocations // backend-to-frontend calls awaiting a reply
+
+	workers    int         // see SetWorkerPool
+	queueDepth int         // see SetWorkerPool
+	pool       *workerPool // runs IPC calls, started by the first connection
+	poolOnce   sync.Once
+
+	typeMappers []TypeMapper // consulted first by GenerateTypeScript, see AddTypeMapper
 }
 
 // Message represents a JSON-RPC style message
@@ -39,6 +88,10 @@ type Response struct {
 	ID     string      `json:"id"`
 	Result interface{} `json:"result,omitempty"`
 	Error  string      `json:"error,omitempty"`
+
+	// Encoding is "base64" when Result carries binary data the frontend
+	// should receive as a Uint8Array rather than a string
+	Encoding string `json:"encoding,omitempty"`
 }
 
 // MethodInfo describes a bound method for the frontend
@@ -46,22 +99,48 @@ type MethodInfo struct {
 	Name       string   `json:"name"`
 	ParamCount int      `json:"paramCount"`
 	ParamTypes []string `json:"paramTypes"`
+
+	// Required is how many leading parameters must be passed; the rest are
+	// trailing pointers or, when Variadic, the variadic parameter
+	Required int  `json:"required"`
+	Variadic bool `json:"variadic,omitempty"`
+
+	// Call is the method name to send when it differs from Name, as for the
+	// "fs.ReadFile" methods of an object added with BindObject
+	Call string `json:"call,omitempty"`
 }
 
 // FieldInfo describes a bound field for the frontend
 type FieldInfo struct {
-	Name string `json:"name"`
-	Type string `json:"type"`
+	Name     string `json:"name"`
+	Type     string `json:"type"`
+	Readonly bool   `json:"readonly,omitempty"`
 }
 
-// New creates a new Runtime instance
+// New creates a new Runtime instance. An app that is nil or not a struct (or
+// pointer to one) is reported by Start and GenerateTypeScript.
 func New(app interface{}) *Runtime {
 	rt := &Runtime{
 		app:        app,
 		methods:    make(map[string]reflect.Value),
 		fields:     make(map[string]int),
+		objects:    make(map[string]bool),
 		stopChan:   make(chan struct{}),
 		extensions: extension.NewRegistry(),
+
+		events:      make(map[string]reflect.Type),
+		subscribers: make(map[*ipcClient]bool),
+
+		startTime: time.Now(),
+
+		maxMessageBytes: DefaultMaxMessageBytes,
+
+		workers:    DefaultWorkers,
+		queueDepth: DefaultQueueDepth,
+	}
+	if err := validateApp(app); err != nil {
+		rt.bindErr = err
+		return rt
 	}
 	rt.discoverMethods()
 	rt.discoverFields()
@@ -73,32 +152,320 @@ func New(app interface{}) *Runtime {
 	return rt
 }
 
-// registerBuiltinExtensions registers all built-in Strux framework extensions
-// Add new framework features here as extensions for clean organization
-func (rt *Runtime) registerBuiltinExtensions() {
-	// Boot management (strux.boot)
-	rt.registerExtension(&extension.BootExtension{}, &extension.BootMethods{})
-
-	// Add more built-in extensions here:
-	// rt.registerExtension(&StorageExtension{}, &StorageMethods{})
-	// rt.registerExtension(&NetworkExtension{}, &NetworkMethods{})
+// validateApp checks that app is a struct or a non-nil pointer to one, so the
+// reflection New does on it cannot panic
+func validateApp(app interface{}) error {
+	if app == nil {
+		return fmt.Errorf("app is nil; pass a pointer to your app struct")
+	}
+	val := reflect.ValueOf(app)
+	if val.Kind() == reflect.Ptr {
+		if val.IsNil() {
+			return fmt.Errorf("app is a nil %T; pass a pointer to your app struct", app)
+		}
+		val = val.Elem()
+	}
+	if val.Kind() != reflect.Struct {
+		return fmt.Errorf("app must be a struct or a pointer to one, got %T", app)
+	}
+	return nil
+}
 
+// registerBuiltinExtensions registers every extension added with
+// extension.Register. Built-in framework features (strux.boot, strux.display)
+// register themselves from the extension package's init; other packages can
+// contribute extensions the same way by being imported.
+func (rt *Runtime) registerBuiltinExtensions() {
+	for _, registered := range extension.RegisteredExtensions() {
+		if err := rt.registerExtension(registered.Extension, registered.Instance); err != nil {
+			fmt.Printf("Strux Runtime: warning: %v\n", err)
+		}
+	}
 }
 
-// discoverMethods uses reflection to find all exported methods
+// discoverMethods uses reflection to find all exported methods,
+// binding each under its alias if the app declares one
 func (rt *Runtime) discoverMethods() {
 	val := reflect.ValueOf(rt.app)
 	typ := val.Type()
 
+	aliaser, hasAliases := rt.app.(MethodAliaser)
+	if hasAliases {
+		rt.aliases = aliaser.MethodAliases()
+	}
+
+	exposedBy := make(map[string]string) // exposed name -> Go method name
 	for i := 0; i < val.NumMethod(); i++ {
 		method := val.Method(i)
 		methodName := typ.Method(i).Name
 
 		// Only bind exported methods (start with uppercase)
-		if methodName[0] >= 'A' && methodName[0] <= 'Z' {
-			rt.methods[methodName] = method
+		if methodName[0] < 'A' || methodName[0] > 'Z' {
+			continue
+		}
+		if hasAliases && methodName == "MethodAliases" {
+			continue
 		}
+
+		name := rt.exposedName(methodName)
+		if other, taken := exposedBy[name]; taken {
+			if rt.bindErr == nil {
+				rt.bindErr = fmt.Errorf("methods %s and %s are both exposed as %q", other, methodName, name)
+			}
+			continue
+		}
+		exposedBy[name] = methodName
+		rt.methods[name] = method
+	}
+	rt.appMethods = exposedBy
+
+	for goName, alias := range rt.aliases {
+		if _, exists := typ.MethodByName(goName); !exists && rt.bindErr == nil {
+			rt.bindErr = fmt.Errorf("alias %q refers to unknown method %s", alias, goName)
+		}
+		if (alias == "" || strings.Contains(alias, ".") || strings.HasPrefix(alias, "__")) && rt.bindErr == nil {
+			rt.bindErr = fmt.Errorf("invalid alias %q for method %s", alias, goName)
+		}
+	}
+}
+
+// Bind registers fn as a callable binding named name, alongside the app's
+// methods. fn may be any function, including a closure or a method value from
+// another object; its parameters and results follow the same rules as app
+// methods, and GenerateTypeScript includes it.
+func (rt *Runtime) Bind(name string, fn interface{}) error {
+	method := reflect.ValueOf(fn)
+	if method.Kind() != reflect.Func || method.IsNil() {
+		return fmt.Errorf("binding %q must be a function, got %T", name, fn)
+	}
+	if name == "" || strings.Contains(name, ".") || strings.HasPrefix(name, "__") {
+		return fmt.Errorf("invalid binding name %q", name)
+	}
+
+	rt.mu.Lock()
+	defer rt.mu.Unlock()
+
+	if _, exists := rt.methods[name]; exists || rt.objects[name] {
+		return fmt.Errorf("binding %q is already registered", name)
+	}
+	rt.methods[name] = method
+	return nil
+}
+
+// BindObject registers the exported methods of obj under the namespace name,
+// for apps split across several service objects. The frontend calls them as
+// "name.Method", and GenerateTypeScript declares them as a nested object of
+// StruxBindings. obj may implement MethodAliaser; two methods exposed under
+// the same name are an error, as is a name already taken by a binding, an
+// object, the app struct or an extension namespace.
+func (rt *Runtime) BindObject(name string, obj interface{}) error {
+	if name == "" || strings.Contains(name, ".") || strings.HasPrefix(name, "__") {
+		return fmt.Errorf("invalid object name %q", name)
+	}
+	if obj == nil {
+		return fmt.Errorf("object %q is nil", name)
+	}
+	if name == rt.structName {
+		return fmt.Errorf("object %q collides with the app struct", name)
+	}
+	if _, exists := rt.extensions.GetAllBindings()[name]; exists {
+		return fmt.Errorf("object %q collides with the extension namespace of that name", name)
 	}
+
+	rt.mu.RLock()
+	camel := rt.camelCase
+	rt.mu.RUnlock()
+
+	methods, err := objectMethods(obj, camel)
+	if err != nil {
+		return fmt.Errorf("object %q: %w", name, err)
+	}
+	if len(methods) == 0 {
+		return fmt.Errorf("object %q (%T) has no exported methods", name, obj)
+	}
+
+	rt.mu.Lock()
+	defer rt.mu.Unlock()
+
+	if _, exists := rt.methods[name]; exists || rt.objects[name] {
+		return fmt.Errorf("binding %q is already registered", name)
+	}
+	rt.objects[name] = true
+	for methodName, method := range methods {
+		rt.methods[name+"."+methodName] = method
+	}
+	return nil
+}
+
+// objectMethods returns the exported methods of obj keyed by exposed name,
+// applying its aliases (and camelCase, if set) the way discoverMethods does
+// for the app
+func objectMethods(obj interface{}, camel bool) (map[string]reflect.Value, error) {
+	val := reflect.ValueOf(obj)
+	typ := val.Type()
+
+	var aliases map[string]string
+	aliaser, hasAliases := obj.(MethodAliaser)
+	if hasAliases {
+		aliases = aliaser.MethodAliases()
+	}
+	for goName, alias := range aliases {
+		if _, exists := typ.MethodByName(goName); !exists {
+			return nil, fmt.Errorf("alias %q refers to unknown method %s", alias, goName)
+		}
+		if alias == "" || strings.Contains(alias, ".") || strings.HasPrefix(alias, "__") {
+			return nil, fmt.Errorf("invalid alias %q for method %s", alias, goName)
+		}
+	}
+
+	methods := make(map[string]reflect.Value)
+	exposedBy := make(map[string]string) // exposed name -> Go method name
+	for i := 0; i < val.NumMethod(); i++ {
+		methodName := typ.Method(i).Name
+		if hasAliases && methodName == "MethodAliases" {
+			continue
+		}
+
+		name := methodName
+		if alias, ok := aliases[methodName]; ok {
+			name = alias
+		} else if camel {
+			name = camelCase(methodName)
+		}
+		if other, taken := exposedBy[name]; taken {
+			return nil, fmt.Errorf("methods %s and %s are both exposed as %q", other, methodName, name)
+		}
+		exposedBy[name] = methodName
+		methods[name] = val.Method(i)
+	}
+	return methods, nil
+}
+
+// ExposeOnly restricts the app's exposed methods to names, leaving functions
+// added with Bind untouched. Names may be Go method names or aliases; names that
+// match no app method are reported as a warning.
+func (rt *Runtime) ExposeOnly(names ...string) {
+	keep := rt.appMethodSet(names)
+	rt.filterAppMethods(func(name string) bool { return keep[name] })
+}
+
+// ExposeExcept hides the named app methods from the frontend. Names follow the
+// same rules as ExposeOnly.
+func (rt *Runtime) ExposeExcept(names ...string) {
+	drop := rt.appMethodSet(names)
+	rt.filterAppMethods(func(name string) bool { return !drop[name] })
+}
+
+// appMethodSet resolves method names or aliases to exposed names, warning
+// about names that aren't app methods
+func (rt *Runtime) appMethodSet(names []string) map[string]bool {
+	set := make(map[string]bool, len(names))
+	for _, name := range names {
+		if _, ok := rt.appMethods[name]; ok {
+			set[name] = true
+			continue
+		}
+		if exposed := rt.exposedName(name); exposed != name {
+			if _, ok := rt.appMethods[exposed]; ok {
+				set[exposed] = true
+				continue
+			}
+		}
+		fmt.Printf("Strux Runtime: warning: %q is not a method of %T\n", name, rt.app)
+	}
+	return set
+}
+
+// filterAppMethods unbinds app methods for which keep returns false. The
+// dispatcher and GenerateTypeScript both read rt.methods, so they stay in step.
+func (rt *Runtime) filterAppMethods(keep func(name string) bool) {
+	rt.mu.Lock()
+	defer rt.mu.Unlock()
+
+	for name := range rt.appMethods {
+		if !keep(name) {
+			delete(rt.methods, name)
+		}
+	}
+}
+
+// exposedName returns the name a Go method is called by from the frontend
+func (rt *Runtime) exposedName(methodName string) string {
+	if alias, ok := rt.aliases[methodName]; ok {
+		return alias
+	}
+	if rt.camelCase {
+		return camelCase(methodName)
+	}
+	return methodName
+}
+
+// UseCamelCase exposes the app's methods, and those of objects bound after it
+// with BindObject, in camelCase: GetItems is called and typed as getItems,
+// GetURL as getURL. Aliases are kept as written, and functions added with
+// Bind keep their given names. Two methods ending up with the same name are
+// reported by Start.
+func (rt *Runtime) UseCamelCase() {
+	rt.mu.Lock()
+	defer rt.mu.Unlock()
+
+	if rt.camelCase {
+		return
+	}
+	rt.camelCase = true
+
+	// Unbind every app method before rebinding, so renames can't collide
+	// with names that are about to be freed. Methods hidden by ExposeOnly or
+	// ExposeExcept stay hidden.
+	bound := make(map[string]reflect.Value, len(rt.appMethods))
+	for name, goName := range rt.appMethods {
+		if method, ok := rt.methods[name]; ok {
+			bound[goName] = method
+			delete(rt.methods, name)
+		}
+	}
+
+	exposedBy := make(map[string]string, len(rt.appMethods))
+	for _, oldName := range sortedKeys(rt.appMethods) {
+		goName := rt.appMethods[oldName]
+		name := rt.exposedName(goName)
+		if other, taken := exposedBy[name]; taken {
+			if rt.bindErr == nil {
+				rt.bindErr = fmt.Errorf("methods %s and %s are both exposed as %q", other, goName, name)
+			}
+			continue
+		}
+		if _, taken := rt.methods[name]; taken {
+			if rt.bindErr == nil {
+				rt.bindErr = fmt.Errorf("method %s is exposed as %q, which is already a binding", goName, name)
+			}
+			continue
+		}
+		exposedBy[name] = goName
+		if method, ok := bound[goName]; ok {
+			rt.methods[name] = method
+		}
+	}
+	rt.appMethods = exposedBy
+}
+
+// camelCase lowercases the first word of a Go identifier, treating a leading
+// run of capitals as an acronym: GetURL becomes getURL, URLPath urlPath and
+// ID id
+func camelCase(name string) string {
+	runes := []rune(name)
+	upper := 0
+	for upper < len(runes) && unicode.IsUpper(runes[upper]) {
+		upper++
+	}
+	// In URLPath the P starts the next word, so it keeps its capital
+	if upper > 1 && upper < len(runes) && unicode.IsLower(runes[upper]) {
+		upper--
+	}
+	for i := 0; i < upper; i++ {
+		runes[i] = unicode.ToLower(runes[i])
+	}
+	return string(runes)
 }
 
 // discoverFields uses reflection to find all exported fields
@@ -155,37 +522,71 @@ func (rt *Runtime) GetMethodInfo() []MethodInfo {
 
 	info := make([]MethodInfo, 0, len(rt.methods))
 	for name, method := range rt.methods {
-		typ := method.Type()
-		paramTypes := make([]string, typ.NumIn())
-		for i := 0; i < typ.NumIn(); i++ {
-			paramTypes[i] = typ.In(i).Kind().String()
+		// Object methods are listed under their object, see GetObjectInfo
+		if strings.Contains(name, ".") {
+			continue
 		}
-
-		info = append(info, MethodInfo{
-			Name:       name,
-			ParamCount: typ.NumIn(),
-			ParamTypes: paramTypes,
-		})
+		info = append(info, methodInfo(name, method.Type()))
 	}
 	return info
 }
 
+// GetObjectInfo returns metadata about the methods of each object added with
+// BindObject, keyed by object name
+func (rt *Runtime) GetObjectInfo() map[string][]MethodInfo {
+	rt.mu.RLock()
+	defer rt.mu.RUnlock()
+
+	objects := make(map[string][]MethodInfo, len(rt.objects))
+	for name, method := range rt.methods {
+		object, methodName, ok := strings.Cut(name, ".")
+		if !ok {
+			continue
+		}
+		info := methodInfo(methodName, method.Type())
+		info.Call = name
+		objects[object] = append(objects[object], info)
+	}
+	return objects
+}
+
+// methodInfo describes a method of type typ exposed as name
+func methodInfo(name string, typ reflect.Type) MethodInfo {
+	paramTypes := make([]string, typ.NumIn())
+	for i := 0; i < typ.NumIn(); i++ {
+		paramTypes[i] = typ.In(i).Kind().String()
+	}
+	return MethodInfo{
+		Name:       name,
+		ParamCount: typ.NumIn(),
+		ParamTypes: paramTypes,
+		Required:   extension.RequiredParams(typ),
+		Variadic:   typ.IsVariadic(),
+	}
+}
+
 // GetFieldInfo returns metadata about all bound fields
 func (rt *Runtime) GetFieldInfo() []FieldInfo {
 	rt.mu.RLock()
 	defer rt.mu.RUnlock()
 
+	info := make([]FieldInfo, 0, len(rt.fields))
+	if len(rt.fields) == 0 {
+		// Also covers an invalid app, which New leaves without fields
+		return info
+	}
+
 	typ := reflect.TypeOf(rt.app)
 	if typ.Kind() == reflect.Ptr {
 		typ = typ.Elem()
 	}
 
-	info := make([]FieldInfo, 0, len(rt.fields))
 	for name, idx := range rt.fields {
 		field := typ.Field(idx)
 		info = append(info, FieldInfo{
-			Name: name,
-			Type: field.Type.Kind().String(),
+			Name:     name,
+			Type:     field.Type.Kind().String(),
+			Readonly: isReadonlyField(field),
 		})
 	}
 	return info
@@ -193,6 +594,10 @@ func (rt *Runtime) GetFieldInfo() []FieldInfo {
 
 // Start begins listening for IPC connections
 func (rt *Runtime) Start() error {
+	if rt.bindErr != nil {
+		return rt.bindErr
+	}
+
 	// Remove existing socket if present
 	os.Remove(socketPath)
 
@@ -224,133 +629,370 @@ func (rt *Runtime) acceptConnections() {
 	}
 }
 
+// frameDecoder reads one JSON frame per call; *json.Decoder is one
+type frameDecoder interface {
+	Decode(v interface{}) error
+}
+
+// frameEncoder writes one JSON frame per call; *json.Encoder is one
+type frameEncoder interface {
+	Encode(v interface{}) error
+}
+
 // handleConnection processes messages from a single connection
 func (rt *Runtime) handleConnection(conn net.Conn) {
 	defer conn.Close()
-	decoder := json.NewDecoder(conn)
-	encoder := json.NewEncoder(conn)
+	decoder := newLimitedDecoder(conn, rt.messageLimit)
+	encoder := json.NewEncoder(countingWriter{w: conn, count: &rt.metrics.bytesSent})
+	rt.serveFrames(decoder, encoder)
+}
 
-	for {
-		var msg Message
-		if err := decoder.Decode(&msg); err != nil {
-			return
+// serveFrames answers frames from decoder until it fails, writing responses
+// and subscribed events to encoder
+func (rt *Runtime) serveFrames(decoder frameDecoder, encoder frameEncoder) {
+	client := &ipcClient{encoder: encoder, codec: struxCodec{}}
+	defer rt.unsubscribe(client)
+
+	rt.metrics.connections.Add(1)
+	defer rt.metrics.connections.Add(-1)
+
+	// Answer every queued call before the connection is closed. The
+	// connection's frames run one at a time, in the order they arrived; once
+	// connectionBacklog are outstanding, reading waits for one to finish.
+	var pending sync.WaitGroup
+	defer pending.Wait()
+	queue := &serialQueue{}
+	backlog := make(chan struct{}, connectionBacklog)
+
+	// With JSON-RPC enabled, the first frame picks the connection's protocol
+	first := true
+	pickCodec := func(jsonRPC bool) {
+		if first {
+			first = false
+			if rt.jsonRPCEnabled() && jsonRPC {
+				client.codec = jsonRPCCodec{}
+			}
 		}
+	}
 
-		// Special case: request for method and field metadata
-		if msg.Method == "__getBindings" {
-			methods := rt.GetMethodInfo()
-			fields := rt.GetFieldInfo()
+	for {
+		var frame json.RawMessage
+		if err := decoder.Decode(&frame); err != nil {
+			var malformed *errMalformedFrame
+			if errors.As(err, &malformed) {
+				fmt.Printf("Strux Runtime: rejected inbound message: %v\n", err)
+				id, jsonRPC := frameHead(malformed.frame)
+				pickCodec(jsonRPC)
+				rt.send(client, client.codec.parseError(id))
+				continue
+			}
 
-			// Structure bindings: user app + all registered extensions
-			bindings := map[string]interface{}{
-				rt.pkgName: map[string]interface{}{
-					rt.structName: map[string]interface{}{
-						"methods": methods,
-						"fields":  fields,
-					},
-				},
+			var tooLarge *errMessageTooLarge
+			if !errors.As(err, &tooLarge) {
+				return
+			}
+			fmt.Printf("Strux Runtime: rejected inbound message: %v\n", err)
+			id, jsonRPC := frameHead(tooLarge.head)
+			pickCodec(jsonRPC)
+			if out := client.codec.rejected(id, messageTooLargeError); out != nil {
+				rt.send(client, out)
 			}
+			if !tooLarge.skipped {
+				return
+			}
+			continue
+		}
+		pickCodec(isJSONRPCFrame(frame))
+
+		// The frame was valid JSON, so the stream is intact; a frame that
+		// isn't a message is answered and the connection carries on
+		msgs, batch, err := client.codec.decode(frame)
+		if err != nil {
+			fmt.Printf("Strux Runtime: rejected inbound message: %v\n", err)
+			id, _ := frameHead(frame)
+			rt.send(client, client.codec.parseError(id))
+			continue
+		}
 
-			// Add all extension bindings
-			extensionBindings := rt.extensions.GetAllBindings()
-			for namespace, subNamespaces := range extensionBindings {
-				bindings[namespace] = subNamespaces
+		calls := make([]Message, 0, len(msgs))
+		for _, msg := range msgs {
+			// Answers to Invoke are not calls and get no response. They are
+			// resolved here, so a call awaiting one never waits behind it.
+			if msg.Method == replyMethod {
+				rt.resolveInvoke(msg)
+				continue
 			}
+			calls = append(calls, msg)
+		}
+		if len(calls) == 0 {
+			continue
+		}
 
-			encoder.Encode(Response{
-				ID:     msg.ID,
-				Result: bindings,
-			})
+		// Once Stop has run there is no pool; the calls are refused inline
+		pool := rt.workerPool()
+		if pool == nil {
+			rt.answer(client, calls, batch)
 			continue
 		}
 
-		// Special case: get field value
-		if msg.Method == "__getField" {
-			var params []interface{}
-			if len(msg.Params) > 0 {
-				json.Unmarshal(msg.Params, &params)
+		// A frame's calls run as one job, so a batch is answered as a batch
+		backlog <- struct{}{}
+		pending.Add(1)
+		queued := pool.submit(queue, func() {
+			defer pending.Done()
+			defer func() { <-backlog }()
+			rt.answer(client, calls, batch)
+		})
+		if !queued {
+			<-backlog
+			pending.Done()
+			resps := overloadedResponses(calls)
+			for _, resp := range resps {
+				rt.metrics.recordCall(resp)
 			}
+			rt.reply(client, calls, resps, batch)
+		}
+	}
+}
 
-			if len(params) < 1 {
-				encoder.Encode(Response{
-					ID:    msg.ID,
-					Error: "field name required",
-				})
-				continue
+// answer dispatches the calls of one frame in order and replies to them
+func (rt *Runtime) answer(client *ipcClient, calls []Message, batch bool) {
+	resps := make([]Response, 0, len(calls))
+	for _, msg := range calls {
+		resp := rt.dispatch(client, msg)
+		rt.metrics.recordCall(resp)
+		resps = append(resps, resp)
+	}
+	rt.reply(client, calls, resps, batch)
+}
+
+// reply sends the responses to a frame's calls, replacing them with
+// message_too_large errors if together they exceed the frame limit
+func (rt *Runtime) reply(client *ipcClient, calls []Message, resps []Response, batch bool) {
+	out := client.codec.response(resps, batch)
+	if out == nil {
+		return
+	}
+	var tooLarge *errMessageTooLarge
+	if err := rt.send(client, out); errors.As(err, &tooLarge) {
+		fmt.Printf("Strux Runtime: response to %s not sent: %v\n", messageMethods(calls), err)
+		rt.send(client, client.codec.response(tooLargeResponses(resps), batch))
+	}
+}
+
+// messageMethods lists the methods of a decoded frame for log messages
+func messageMethods(msgs []Message) string {
+	names := make([]string, len(msgs))
+	for i, msg := range msgs {
+		names[i] = msg.Method
+	}
+	return strings.Join(names, ", ")
+}
+
+// dispatch handles one message as an in-flight call
+func (rt *Runtime) dispatch(client *ipcClient, msg Message) Response {
+	// Once Stop has begun, refuse new calls rather than racing the shutdown
+	if !rt.beginCall() {
+		return Response{
+			ID:    msg.ID,
+			Error: "runtime is stopping",
+		}
+	}
+	defer rt.endCall()
+
+	if msg.Method == "" {
+		return Response{ID: msg.ID, Error: invalidRequestError}
+	}
+	return rt.handleMessage(client, msg)
+}
+
+// EnableJSONRPC lets IPC connections speak JSON-RPC 2.0 instead of the strux
+// framing. Each connection is detected from its first message, so the
+// frontend bridge keeps working. Call before Start.
+func (rt *Runtime) EnableJSONRPC() {
+	rt.mu.Lock()
+	defer rt.mu.Unlock()
+	rt.jsonRPC = true
+}
+
+// jsonRPCEnabled reports whether EnableJSONRPC was called
+func (rt *Runtime) jsonRPCEnabled() bool {
+	rt.mu.RLock()
+	defer rt.mu.RUnlock()
+	return rt.jsonRPC
+}
+
+// handleMessage dispatches a single message and returns its response
+func (rt *Runtime) handleMessage(client *ipcClient, msg Message) Response {
+	// Special case: request for method and field metadata
+	if msg.Method == "__getBindings" {
+		methods := rt.GetMethodInfo()
+		fields := rt.GetFieldInfo()
+
+		// Structure bindings: user app + all registered extensions
+		app := map[string]interface{}{
+			rt.structName: map[string]interface{}{
+				"methods": methods,
+				"fields":  fields,
+			},
+		}
+		for object, objectMethods := range rt.GetObjectInfo() {
+			app[object] = map[string]interface{}{
+				"methods": objectMethods,
+				"fields":  []FieldInfo{},
 			}
+		}
+		bindings := map[string]interface{}{
+			rt.pkgName: app,
+		}
 
-			fieldName, ok := params[0].(string)
-			if !ok {
-				encoder.Encode(Response{
-					ID:    msg.ID,
-					Error: "field name must be a string",
-				})
-				continue
+		// Add all extension bindings
+		extensionBindings := rt.extensions.GetAllBindings()
+		for namespace, subNamespaces := range extensionBindings {
+			bindings[namespace] = subNamespaces
+		}
+
+		return Response{
+			ID:     msg.ID,
+			Result: bindings,
+		}
+	}
+
+	// Special case: receive emitted events on this connection
+	if msg.Method == "__subscribe" {
+		rt.subscribe(client)
+		return Response{
+			ID:     msg.ID,
+			Result: rt.eventNames(),
+		}
+	}
+
+	// Special case: describe the API surface for dev tools
+	if msg.Method == "__describe" {
+		return Response{
+			ID:     msg.ID,
+			Result: rt.Describe(),
+		}
+	}
+
+	// Special case: one method's signature for editor tooling
+	if msg.Method == "__signature" {
+		var params []interface{}
+		if len(msg.Params) > 0 {
+			json.Unmarshal(msg.Params, &params)
+		}
+
+		if len(params) < 1 {
+			return Response{
+				ID:    msg.ID,
+				Error: "method name required",
 			}
+		}
 
-			value, err := rt.getField(fieldName)
-			encoder.Encode(Response{
-				ID:     msg.ID,
-				Result: value,
-				Error: func() string {
-					if err != nil {
-						return err.Error()
-					}
-					return ""
-				}(),
-			})
-			continue
+		name, ok := params[0].(string)
+		if !ok {
+			return Response{
+				ID:    msg.ID,
+				Error: "method name must be a string",
+			}
 		}
 
-		// Special case: set field value
-		if msg.Method == "__setField" {
-			var params []interface{}
-			if len(msg.Params) > 0 {
-				json.Unmarshal(msg.Params, &params)
+		sig, found := rt.MethodSignature(name)
+		if !found {
+			return Response{
+				ID:    msg.ID,
+				Error: fmt.Sprintf("method not found: %s", name),
 			}
+		}
+		return Response{
+			ID:     msg.ID,
+			Result: sig,
+		}
+	}
 
-			if len(params) < 2 {
-				encoder.Encode(Response{
-					ID:    msg.ID,
-					Error: "field name and value required",
-				})
-				continue
+	// Special case: get field value
+	if msg.Method == "__getField" {
+		var params []interface{}
+		if len(msg.Params) > 0 {
+			json.Unmarshal(msg.Params, &params)
+		}
+
+		if len(params) < 1 {
+			return Response{
+				ID:    msg.ID,
+				Error: "field name required",
 			}
+		}
 
-			fieldName, ok := params[0].(string)
-			if !ok {
-				encoder.Encode(Response{
-					ID:    msg.ID,
-					Error: "field name must be a string",
-				})
-				continue
+		fieldName, ok := params[0].(string)
+		if !ok {
+			return Response{
+				ID:    msg.ID,
+				Error: "field name must be a string",
 			}
+		}
 
-			err := rt.setField(fieldName, params[1])
-			encoder.Encode(Response{
-				ID: msg.ID,
-				Error: func() string {
-					if err != nil {
-						return err.Error()
-					}
-					return ""
-				}(),
-			})
-			continue
+		value, err := rt.getField(fieldName)
+		return Response{
+			ID:     msg.ID,
+			Result: value,
+			Error: func() string {
+				if err != nil {
+					return err.Error()
+				}
+				return ""
+			}(),
 		}
+	}
 
-		// Execute the method
-		result, err := rt.executeMethod(msg.Method, msg.Params)
+	// Special case: set field value
+	if msg.Method == "__setField" {
+		var params []interface{}
+		if len(msg.Params) > 0 {
+			json.Unmarshal(msg.Params, &params)
+		}
 
-		resp := Response{ID: msg.ID}
-		if err != nil {
-			resp.Error = err.Error()
-		} else {
-			resp.Result = result
+		if len(params) < 2 {
+			return Response{
+				ID:    msg.ID,
+				Error: "field name and value required",
+			}
 		}
 
-		encoder.Encode(resp)
+		fieldName, ok := params[0].(string)
+		if !ok {
+			return Response{
+				ID:    msg.ID,
+				Error: "field name must be a string",
+			}
+		}
+
+		err := rt.setField(fieldName, params[1])
+		return Response{
+			ID: msg.ID,
+			Error: func() string {
+				if err != nil {
+					return err.Error()
+				}
+				return ""
+			}(),
+		}
 	}
+
+	// Execute the method
+	result, err := rt.executeMethod(msg.Method, msg.Params)
+
+	resp := Response{ID: msg.ID}
+	if err == nil {
+		result, resp.Encoding, err = encodeBinaryResult(result)
+	}
+	if err != nil {
+		resp.Error = err.Error()
+	} else {
+		resp.Result = result
+	}
+
+	return resp
 }
 
 // executeMethod calls a bound method with the provided parameters
@@ -382,7 +1024,6 @@ func (rt *Runtime) executeMethod(methodName string, paramsRaw json.RawMessage) (
 	}
 
 	methodType := method.Type()
-	numParams := methodType.NumIn()
 
 	// Parse parameters
 	var params []interface{}
@@ -392,25 +1033,33 @@ func (rt *Runtime) executeMethod(methodName string, paramsRaw json.RawMessage) (
 		}
 	}
 
-	if len(params) != numParams {
-		return nil, fmt.Errorf("expected %d parameters, got %d", numParams, len(params))
+	if err := extension.CheckParamCount(methodType, len(params)); err != nil {
+		return nil, err
 	}
 
 	// Convert parameters to the correct types
-	args := make([]reflect.Value, numParams)
-	for i := 0; i < numParams; i++ {
-		expectedType := methodType.In(i)
+	args := make([]reflect.Value, len(params))
+	for i := range params {
+		expectedType := extension.ParamType(methodType, i)
 
-		// Re-marshal and unmarshal to convert to the correct type
+		// Re-marshal and unmarshal to convert to the correct type, over the
+		// defaults of any fields the caller leaves out
 		paramJSON, _ := json.Marshal(params[i])
-		paramValue := reflect.New(expectedType)
+		paramValue, err := extension.NewDefault(expectedType)
+		if err != nil {
+			return nil, fmt.Errorf("parameter %d: %w", i, err)
+		}
 		if err := json.Unmarshal(paramJSON, paramValue.Interface()); err != nil {
 			return nil, fmt.Errorf("parameter %d type mismatch: %w", i, err)
 		}
 		args[i] = paramValue.Elem()
 	}
 
-	// Call the method
+	// Call the method, filling in omitted optional parameters
+	args, err := extension.FillOptional(methodType, args)
+	if err != nil {
+		return nil, err
+	}
 	results := method.Call(args)
 
 	// Handle return values
@@ -446,6 +1095,26 @@ func (rt *Runtime) executeMethod(methodName string, paramsRaw json.RawMessage) (
 	return resultArray, nil
 }
 
+// encodeBinaryResult routes []byte and io.Reader results through the binary path.
+// They are sent base64-encoded with Encoding set so the frontend can decode them
+// to a Uint8Array instead of receiving a JSON array of numbers.
+func encodeBinaryResult(result interface{}) (interface{}, string, error) {
+	switch v := result.(type) {
+	case []byte:
+		return base64.StdEncoding.EncodeToString(v), "base64", nil
+	case io.Reader:
+		if closer, ok := v.(io.Closer); ok {
+			defer closer.Close()
+		}
+		data, err := io.ReadAll(v)
+		if err != nil {
+			return nil, "", fmt.Errorf("failed to read result: %w", err)
+		}
+		return base64.StdEncoding.EncodeToString(data), "base64", nil
+	}
+	return result, "", nil
+}
+
 // getField retrieves the value of a field
 func (rt *Runtime) getField(fieldName string) (interface{}, error) {
 	rt.mu.RLock()
@@ -480,6 +1149,10 @@ func (rt *Runtime) setField(fieldName string, value interface{}) error {
 		val = val.Elem()
 	}
 
+	if isReadonlyField(val.Type().Field(fieldIdx)) {
+		return fmt.Errorf("field %s is readonly", fieldName)
+	}
+
 	fieldValue := val.Field(fieldIdx)
 
 	if !fieldValue.CanSet() {
@@ -509,13 +1182,60 @@ func (rt *Runtime) setField(fieldName string, value interface{}) error {
 	return nil
 }
 
-// Stop shuts down the IPC server
-func (rt *Runtime) Stop() {
+// Stop shuts down the IPC server. It refuses new calls, waits up to
+// drainTimeout for in-flight calls, then closes extensions in reverse
+// registration order and returns the first Close error.
+func (rt *Runtime) Stop() error {
+	// 1. Stop accepting new connections and calls
+	rt.stopMu.Lock()
+	if rt.stopping {
+		rt.stopMu.Unlock()
+		return nil
+	}
+	rt.stopping = true
+	rt.stopMu.Unlock()
+
 	close(rt.stopChan)
 	if rt.listener != nil {
 		rt.listener.Close()
+		os.Remove(socketPath)
 	}
-	os.Remove(socketPath)
+
+	// 2. Let in-flight calls finish, but don't hang shutdown on a stuck one
+	drained := make(chan struct{})
+	go func() {
+		rt.calls.Wait()
+		close(drained)
+	}()
+	select {
+	case <-drained:
+	case <-time.After(drainTimeout):
+		fmt.Printf("Strux Runtime: in-flight calls still running after %s, closing anyway\n", drainTimeout)
+	}
+
+	// 3. Release the call workers and extension resources, last registered first
+	rt.stopWorkerPool()
+	return rt.extensions.Close()
+}
+
+// beginCall registers an in-flight call, or reports false once Stop has begun
+func (rt *Runtime) beginCall() bool {
+	rt.stopMu.Lock()
+	defer rt.stopMu.Unlock()
+	if rt.stopping {
+		return false
+	}
+	rt.calls.Add(1)
+	rt.inFlight++
+	return true
+}
+
+// endCall marks a call registered with beginCall as finished
+func (rt *Runtime) endCall() {
+	rt.stopMu.Lock()
+	rt.inFlight--
+	rt.stopMu.Unlock()
+	rt.calls.Done()
 }
 
 // registerExtension is an internal method for registering framework extensions
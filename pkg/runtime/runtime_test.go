@@ -0,0 +1,59 @@
+package runtime
+
+import (
+	"io"
+	"strings"
+	"testing"
+)
+
+type validApp struct{}
+
+func (validApp) Hello() string { return "hi" }
+
+func TestValidateApp(t *testing.T) {
+	n := 3
+	tests := []struct {
+		name string
+		app  interface{}
+		want string // substring of the error, or "" for none
+	}{
+		{"nil", nil, "app is nil"},
+		{"typed nil pointer", (*validApp)(nil), "app is a nil *runtime.validApp"},
+		{"int", 42, "got int"},
+		{"string", "app", "got string"},
+		{"map", map[string]int{}, "got map[string]int"},
+		{"func", func() {}, "got func()"},
+		{"pointer to int", &n, "got *int"},
+		{"struct", validApp{}, ""},
+		{"pointer to struct", &validApp{}, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateApp(tt.app)
+			if tt.want == "" {
+				if err != nil {
+					t.Fatalf("got %v, want no error", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Fatalf("got %v, want an error containing %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestInvalidAppIsReportedNotPanicked(t *testing.T) {
+	for _, app := range []interface{}{nil, (*validApp)(nil), 42} {
+		rt := New(app)
+		if err := rt.GenerateTypeScriptTo(io.Discard); err == nil {
+			t.Errorf("GenerateTypeScript with %#v: no error", app)
+		}
+		if err := rt.Start(); err == nil {
+			t.Errorf("Start with %#v: no error", app)
+		}
+		if err := StartStdio(app); err == nil {
+			t.Errorf("StartStdio with %#v: no error", app)
+		}
+	}
+}
@@ -1,27 +1,274 @@
 package runtime
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"log"
+	"net"
 	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
 )
 
+// ServerOptions configures the HTTP server started by StartWithOptions
+type ServerOptions struct {
+	// Addr is the TCP address to listen on (default ":8080")
+	Addr string
+
+	// FrontendDir is the directory static files are served from (default "./frontend")
+	FrontendDir string
+
+	// FrontendDirs overlays several directories, checked in order; the first one
+	// containing a requested path serves it. When set, FrontendDir is ignored.
+	FrontendDirs []string
+
+	// DirectoryListing lets directories without an index.html be listed.
+	// By default they answer 404, so the frontend's file tree isn't exposed.
+	DirectoryListing bool
+
+	// UnixSocket serves over a unix domain socket at this path instead of TCP,
+	// for setups where a local reverse proxy fronts the UI
+	UnixSocket string
+
+	// UnixSocketMode is the permission mode of the socket file (default 0660)
+	UnixSocketMode os.FileMode
+
+	// Metrics serves runtime metrics in the Prometheus text format at /metrics.
+	// Off by default so production builds don't expose internals.
+	Metrics bool
+
+	// APIExplorer serves the app's methods, fields, extensions and events as
+	// JSON at GET /__strux/api (see Runtime.Describe). Meant for development;
+	// leave it off in production builds so the API surface isn't published.
+	APIExplorer bool
+
+	// FilesDir, when set, serves the files below it as downloads at
+	// GET /files/<path>, with range support. Paths resolving outside FilesDir
+	// (through ".." or symlinks) are rejected.
+	FilesDir string
+
+	// FilesAuthorize approves requests to /files/. It is required: without
+	// it every request to /files/ is answered 403 Forbidden.
+	FilesAuthorize func(r *http.Request) bool
+
+	// MaxMessageBytes limits each IPC frame in either direction (default
+	// DefaultMaxMessageBytes). See Runtime.SetMaxMessageBytes.
+	MaxMessageBytes int64
+
+	// Workers and QueueDepth size the pool that runs IPC calls (defaults
+	// DefaultWorkers and DefaultQueueDepth); calls beyond both are answered
+	// with an "overloaded" error. See Runtime.SetWorkerPool.
+	Workers    int
+	QueueDepth int
+
+	// JSONRPC lets IPC clients speak JSON-RPC 2.0 (including batches) instead
+	// of the strux framing. The protocol is detected per connection, so the
+	// frontend bridge is unaffected.
+	JSONRPC bool
+
+	// ExposeOnly limits the app methods callable from the frontend (and present
+	// in generated types) to these names. Go method names or aliases both work.
+	ExposeOnly []string
+
+	// ExposeExcept hides these app methods from the frontend. It cannot be
+	// combined with ExposeOnly.
+	ExposeExcept []string
+
+	// CamelCase exposes app methods in camelCase (getItems rather than
+	// GetItems). See Runtime.UseCamelCase.
+	CamelCase bool
+
+	// OnListen, if set, is called with the bound address before serving begins.
+	// With Addr ":0" this is the only way to learn which port was picked.
+	OnListen func(addr net.Addr)
+}
+
+// shutdownTimeout bounds how long in-flight HTTP requests may take to finish
+const shutdownTimeout = 5 * time.Second
+
+// withDefaults fills in unset options
+func (o ServerOptions) withDefaults() ServerOptions {
+	if o.Addr == "" {
+		o.Addr = ":8080"
+	}
+	if o.FrontendDir == "" {
+		o.FrontendDir = "./frontend"
+	}
+	if len(o.FrontendDirs) == 0 {
+		o.FrontendDirs = []string{o.FrontendDir}
+	}
+	if o.UnixSocketMode == 0 {
+		o.UnixSocketMode = 0660
+	}
+	return o
+}
+
 // Start begins the IPC bridge and HTTP server
 // It serves static files from ./frontend on port 8080
 func Start(app interface{}) error {
+	return StartWithOptions(app, ServerOptions{})
+}
+
+// StartWithOptions begins the IPC bridge and HTTP server using the given options
+// It blocks until the server fails or SIGINT/SIGTERM triggers a graceful shutdown
+func StartWithOptions(app interface{}, opts ServerOptions) error {
+	opts = opts.withDefaults()
+	if len(opts.ExposeOnly) > 0 && len(opts.ExposeExcept) > 0 {
+		return fmt.Errorf("ExposeOnly and ExposeExcept cannot both be set")
+	}
+
 	// Create and start IPC runtime (includes all built-in extensions)
 	rt := New(app)
+	if opts.CamelCase {
+		rt.UseCamelCase()
+	}
+	if len(opts.ExposeOnly) > 0 {
+		rt.ExposeOnly(opts.ExposeOnly...)
+	}
+	if len(opts.ExposeExcept) > 0 {
+		rt.ExposeExcept(opts.ExposeExcept...)
+	}
+	if opts.JSONRPC {
+		rt.EnableJSONRPC()
+	}
+	if opts.MaxMessageBytes > 0 {
+		rt.SetMaxMessageBytes(opts.MaxMessageBytes)
+	}
+	if opts.Workers != 0 || opts.QueueDepth != 0 {
+		rt.SetWorkerPool(opts.Workers, opts.QueueDepth)
+	}
 	if err := rt.Start(); err != nil {
 		return fmt.Errorf("failed to start IPC server: %w", err)
 	}
-	defer rt.Stop()
+	// Deferred first so it runs last, after the HTTP server has stopped
+	defer func() {
+		if err := rt.Stop(); err != nil {
+			log.Printf("Strux: %v\n", err)
+		}
+	}()
 
 	// Setup HTTP handler for static files
-	handler := http.FileServer(http.Dir("./frontend"))
+	handler := frontendHandler(opts)
+	if opts.Metrics || opts.APIExplorer || opts.FilesDir != "" {
+		mux := http.NewServeMux()
+		if opts.Metrics {
+			mux.Handle("/metrics", rt.MetricsHandler())
+		}
+		if opts.APIExplorer {
+			mux.Handle(apiPath, rt.APIHandler())
+		}
+		if opts.FilesDir != "" {
+			if opts.FilesAuthorize == nil {
+				log.Printf("Strux: Warning: FilesDir is set without FilesAuthorize; /files/ refuses every request\n")
+			}
+			mux.Handle(filesPrefix, filesHandler(opts.FilesDir, opts.FilesAuthorize))
+		}
+		mux.Handle("/", handler)
+		handler = mux
+	}
+
+	listener, err := listen(opts)
+	if err != nil {
+		return err
+	}
+	if opts.UnixSocket != "" {
+		defer os.Remove(opts.UnixSocket)
+	}
 
 	// Start HTTP server
-	log.Println("Strux: Starting HTTP server on :8080")
-	log.Println("Strux: Serving static files from ./frontend")
+	log.Printf("Strux: Starting HTTP server on %s\n", listener.Addr())
+	log.Printf("Strux: Serving static files from %s\n", strings.Join(opts.FrontendDirs, ", "))
+	for _, problem := range checkFrontendDirs(opts.FrontendDirs) {
+		log.Printf("Strux: Warning: %s\n", problem)
+	}
+
+	if opts.OnListen != nil {
+		opts.OnListen(listener.Addr())
+	}
+
+	server := &http.Server{Handler: handler}
+
+	// Shut down gracefully on SIGINT/SIGTERM so deferred cleanup runs
+	sigChan := make(chan os.Signal, 1)
+	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
+	defer signal.Stop(sigChan)
+
+	done := make(chan struct{})
+	defer close(done)
+
+	go func() {
+		select {
+		case <-sigChan:
+		case <-done:
+			return
+		}
+		log.Println("Strux: Shutting down HTTP server")
+		ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+		defer cancel()
+		server.Shutdown(ctx)
+	}()
+
+	if err := server.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
+
+// frontendHandler builds the static file handler for the frontend.
+// http.FileServer answers Range requests with 206 Partial Content and sets
+// Accept-Ranges/Content-Range itself, so any wrapper added here must pass
+// ranged requests through unmodified (in particular, never compress them).
+// Unknown extensionless paths fall back to index.html from the highest-priority
+// directory that has one. Until some directory has an index.html, pages get a
+// placeholder explaining that the frontend is missing. Directories without an
+// index.html are not listed unless opts.DirectoryListing is set. Files with a
+// .br or .gz sibling are sent pre-compressed to clients accepting it.
+func frontendHandler(opts ServerOptions) http.Handler {
+	root := make(overlayFS, len(opts.FrontendDirs))
+	for i, dir := range opts.FrontendDirs {
+		root[i] = http.Dir(dir)
+	}
+	files := servePrecompressed(root, http.FileServer(root))
+	if !opts.DirectoryListing {
+		files = hideDirListings(root, files)
+	}
+	return frontendPlaceholder(root, opts.FrontendDirs, spaFallback(root, files))
+}
+
+// listen creates the TCP or unix socket listener described by opts
+func listen(opts ServerOptions) (net.Listener, error) {
+	if opts.UnixSocket == "" {
+		listener, err := net.Listen("tcp", opts.Addr)
+		if err != nil {
+			return nil, fmt.Errorf("failed to listen on %s: %w", opts.Addr, err)
+		}
+		return listener, nil
+	}
+
+	// Remove a stale socket left behind by a previous run, but never a file
+	// that isn't a socket: the path may be mistyped
+	if info, err := os.Lstat(opts.UnixSocket); err == nil {
+		if info.Mode()&os.ModeSocket == 0 {
+			return nil, fmt.Errorf("failed to listen on %s: file exists and is not a socket", opts.UnixSocket)
+		}
+		if err := os.Remove(opts.UnixSocket); err != nil {
+			return nil, fmt.Errorf("failed to remove stale socket %s: %w", opts.UnixSocket, err)
+		}
+	}
+
+	listener, err := net.Listen("unix", opts.UnixSocket)
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen on %s: %w", opts.UnixSocket, err)
+	}
+
+	if err := os.Chmod(opts.UnixSocket, opts.UnixSocketMode); err != nil {
+		listener.Close()
+		return nil, fmt.Errorf("failed to set permissions on %s: %w", opts.UnixSocket, err)
+	}
 
-	return http.ListenAndServe(":8080", handler)
+	return listener, nil
 }
@@ -0,0 +1,41 @@
+package runtime
+
+import (
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestListenReplacesStaleSocket(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.sock")
+	stale, err := net.Listen("unix", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Leave the socket file behind, as a crashed run would
+	stale.(*net.UnixListener).SetUnlinkOnClose(false)
+	stale.Close()
+
+	listener, err := listen(ServerOptions{UnixSocket: path, UnixSocketMode: 0660})
+	if err != nil {
+		t.Fatalf("listen over a stale socket: %v", err)
+	}
+	listener.Close()
+}
+
+func TestListenKeepsRegularFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.json")
+	if err := os.WriteFile(path, []byte("{}"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err := listen(ServerOptions{UnixSocket: path, UnixSocketMode: 0660})
+	if err == nil || !strings.Contains(err.Error(), "not a socket") {
+		t.Fatalf("got %v, want a not-a-socket error", err)
+	}
+	if data, err := os.ReadFile(path); err != nil || string(data) != "{}" {
+		t.Fatalf("file was changed: %q, %v", data, err)
+	}
+}
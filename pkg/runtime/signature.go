@@ -0,0 +1,92 @@
+package runtime
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/strux-dev/strux/pkg/runtime/extension"
+)
+
+// MethodSignature is the TypeScript signature of one bound method or
+// extension method, as GenerateTypeScript declares it
+type MethodSignature struct {
+	Name    string           `json:"name"`
+	Params  []ParamSignature `json:"params"`
+	Returns string           `json:"returns"` // the type the call's promise resolves to
+
+	// Declarations holds the interfaces the signature refers to, so tooling
+	// can show them without the generated file
+	Declarations []string `json:"declarations"`
+}
+
+// ParamSignature is one parameter of a MethodSignature. Go keeps no parameter
+// names at runtime, so names are positional (arg0, arg1, ...), matching the
+// generated definitions. Optional marks a trailing pointer the caller may
+// leave off and Variadic a rest parameter.
+type ParamSignature struct {
+	Name     string `json:"name"`
+	Type     string `json:"type"`
+	Optional bool   `json:"optional,omitempty"`
+	Variadic bool   `json:"variadic,omitempty"`
+}
+
+// MethodSignature returns the signature of the method the frontend calls as
+// name: an app method or Bind function, or an extension method named
+// "namespace.subNamespace.Method". It is also served over the bridge as
+// __signature, for editor hover and completion against a running app.
+func (rt *Runtime) MethodSignature(name string) (MethodSignature, bool) {
+	types := newTSTypeRegistry()
+	rt.mu.RLock()
+	types.mappers = append([]TypeMapper(nil), rt.typeMappers...)
+	method, ok := rt.methods[name]
+	rt.mu.RUnlock()
+
+	sig := MethodSignature{Name: name, Params: []ParamSignature{}}
+	if ok {
+		methodType := method.Type()
+		sig.Params = paramSignatures(types.methodParamsToTS(methodType))
+		sig.Returns = types.methodReturnToTS(methodType)
+	} else if !rt.extensionSignature(name, types, &sig) {
+		return MethodSignature{}, false
+	}
+
+	sig.Declarations = append([]string{}, types.decls...)
+	return sig, true
+}
+
+// extensionSignature fills sig for an extension method named
+// "namespace.subNamespace.Method", reporting whether it exists
+func (rt *Runtime) extensionSignature(name string, types *tsTypeRegistry, sig *MethodSignature) bool {
+	parts := strings.Split(name, ".")
+	if len(parts) != 3 {
+		return false
+	}
+
+	// Read the bindings the generator reads, so the types agree
+	namespaces, _ := rt.extensions.GetAllBindings()[parts[0]].(map[string]interface{})
+	binding, _ := namespaces[parts[1]].(map[string]interface{})
+	methods, _ := binding["methods"].([]extension.MethodInfo)
+	for _, method := range methods {
+		if method.Name != parts[2] {
+			continue
+		}
+		sig.Params = paramSignatures(types.extensionParamsToTS(method))
+		sig.Returns = types.extensionReturnToTS(method)
+		return true
+	}
+	return false
+}
+
+// paramSignatures names params positionally, as formatTSParams does
+func paramSignatures(params []tsParam) []ParamSignature {
+	sigs := make([]ParamSignature, len(params))
+	for i, p := range params {
+		sigs[i] = ParamSignature{
+			Name:     fmt.Sprintf("arg%d", i),
+			Type:     p.Type,
+			Optional: p.Optional,
+			Variadic: p.Variadic,
+		}
+	}
+	return sigs
+}
@@ -0,0 +1,113 @@
+package runtime
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"sync"
+)
+
+// StartStdio runs the IPC bridge over stdin and stdout instead of the unix
+// socket and HTTP server, for hosts that run the app as a subprocess. Each
+// frame is a 4-byte big-endian length followed by that many bytes of JSON,
+// carrying the same messages, responses and events as the socket.
+//
+// Stdout belongs to the framing, so os.Stdout is pointed at stderr for the
+// rest of the process and anything printed with fmt.Print lands there.
+// StartStdio returns when stdin is closed.
+func StartStdio(app interface{}) error {
+	rt := New(app)
+	if rt.bindErr != nil {
+		return rt.bindErr
+	}
+
+	out := os.Stdout
+	os.Stdout = os.Stderr
+
+	rt.serveFrames(&lengthPrefixedDecoder{r: os.Stdin, limit: rt.messageLimit}, &lengthPrefixedEncoder{w: countingWriter{w: out, count: &rt.metrics.bytesSent}})
+	return rt.Stop()
+}
+
+// lengthPrefixedDecoder reads frames written by a lengthPrefixedEncoder.
+// io.ReadFull makes it indifferent to how the pipe splits the bytes. Frames
+// over the limit are skipped without being buffered, and frames that are
+// not JSON are reported with errMalformedFrame; only a cut-off frame ends
+// the stream.
+type lengthPrefixedDecoder struct {
+	r     io.Reader
+	limit func() int64
+}
+
+func (d *lengthPrefixedDecoder) Decode(v interface{}) error {
+	var header [4]byte
+	if _, err := io.ReadFull(d.r, header[:]); err != nil {
+		return err
+	}
+
+	size := binary.BigEndian.Uint32(header[:])
+	if int64(size) > d.limit() {
+		// Keep the start, where the id usually is, so the caller can be answered
+		head := make([]byte, min(int64(size), frameHeadBytes))
+		if _, err := io.ReadFull(d.r, head); err != nil {
+			return err
+		}
+		if _, err := io.CopyN(io.Discard, d.r, int64(size)-int64(len(head))); err != nil {
+			return err
+		}
+		return &errMessageTooLarge{size: int64(size), skipped: true, head: head}
+	}
+
+	frame := make([]byte, size)
+	if _, err := io.ReadFull(d.r, frame); err != nil {
+		if errors.Is(err, io.EOF) {
+			err = io.ErrUnexpectedEOF
+		}
+		return err
+	}
+	if err := json.Unmarshal(frame, v); err != nil {
+		// The whole frame was read, so the next one can still be decoded
+		return &errMalformedFrame{err: err, frame: frame}
+	}
+	return nil
+}
+
+// errMalformedFrame is returned by lengthPrefixedDecoder for a frame that
+// is not valid JSON. Its length prefix was intact, so the stream is not.
+type errMalformedFrame struct {
+	err   error
+	frame []byte
+}
+
+func (e *errMalformedFrame) Error() string {
+	return "malformed frame: " + e.err.Error()
+}
+
+func (e *errMalformedFrame) Unwrap() error {
+	return e.err
+}
+
+// lengthPrefixedEncoder writes each value as one length-prefixed frame.
+// Header and body go out in a single Write so concurrent event and response
+// frames never interleave.
+type lengthPrefixedEncoder struct {
+	w  io.Writer
+	mu sync.Mutex
+}
+
+func (e *lengthPrefixedEncoder) Encode(v interface{}) error {
+	body, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+
+	frame := make([]byte, 4+len(body))
+	binary.BigEndian.PutUint32(frame, uint32(len(body)))
+	copy(frame[4:], body)
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	_, err = e.w.Write(frame)
+	return err
+}
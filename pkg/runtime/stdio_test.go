@@ -0,0 +1,77 @@
+package runtime
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"io"
+	"testing"
+	"time"
+)
+
+func TestStdioAnswersMalformedFrameAndCarriesOn(t *testing.T) {
+	rt := New(limitApp{})
+	c := serveStdioTest(t, rt)
+
+	c.sendRaw(`{"id":"bad","method":"Echo","params":[`)
+	var resp Response
+	c.read(&resp)
+	if resp.ID != "bad" || resp.Error != parseError {
+		t.Fatalf("got %+v, want a parse error for bad", resp)
+	}
+
+	// A frame that is JSON but not a message is answered the same way
+	c.sendRaw(`[1,2]`)
+	resp = Response{}
+	c.read(&resp)
+	if resp.ID != "" || resp.Error != parseError {
+		t.Fatalf("got %+v, want a parse error without an id", resp)
+	}
+
+	c.call("good", "Echo", "hi")
+	resp = Response{}
+	c.read(&resp)
+	if resp.ID != "good" || resp.Result != "hi" {
+		t.Fatalf("got %+v after malformed frames", resp)
+	}
+}
+
+func TestStdioJSONRPCParseError(t *testing.T) {
+	rt := New(limitApp{})
+	rt.EnableJSONRPC()
+	c := serveStdioTest(t, rt)
+
+	c.sendRaw(`{"jsonrpc":"2.0","id":1,"method":"Echo","params":[`)
+	var resp struct {
+		ID    json.RawMessage `json:"id"`
+		Error *jsonRPCError   `json:"error"`
+	}
+	c.read(&resp)
+	if string(resp.ID) != "null" || resp.Error == nil || resp.Error.Code != jsonRPCParseError {
+		t.Fatalf("got id %s error %+v, want -32700 with a null id", resp.ID, resp.Error)
+	}
+
+	c.sendRaw(`{"jsonrpc":"2.0","id":2,"method":"Echo","params":["hi"]}`)
+	var ok map[string]interface{}
+	c.read(&ok)
+	if ok["result"] != "hi" {
+		t.Fatalf("got %v after a parse error", ok)
+	}
+}
+
+func TestStdioCutOffFrameEndsStream(t *testing.T) {
+	rt := New(limitApp{})
+	c := serveStdioTest(t, rt)
+
+	// A length prefix promising more than arrives leaves no frame boundary
+	var header [4]byte
+	binary.BigEndian.PutUint32(header[:], 100)
+	w := c.enc.w.(io.WriteCloser)
+	w.Write(append(header[:], `{"id":"1"`...))
+	w.Close()
+
+	select {
+	case <-c.ended:
+	case <-time.After(5 * time.Second):
+		t.Fatal("stream still served after a cut-off frame")
+	}
+}
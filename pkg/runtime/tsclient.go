@@ -0,0 +1,140 @@
+package runtime
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/strux-dev/strux/pkg/runtime/extension"
+)
+
+// tsClientRuntime is the fixed part of the generated client: the message
+// envelope, the call helper and the transport for the WPE bridge
+const tsClientRuntime = `// Runtime client generated alongside the types above
+export interface StruxMessage {
+  id: string;
+  method: string;
+  params: unknown[];
+}
+
+export interface StruxResponse {
+  id: string;
+  result?: unknown;
+  error?: string;
+  encoding?: "base64";
+}
+
+// A transport delivers one message to the Go runtime and resolves with its response
+export type StruxTransport = (message: StruxMessage) => Promise<StruxResponse>;
+
+let nextCallID = 0;
+
+async function call<T>(transport: StruxTransport, method: string, params: unknown[]): Promise<T> {
+  const response = await transport({ id: String(++nextCallID), method, params });
+  if (response.error) {
+    throw new Error(response.error);
+  }
+  if (response.encoding === "base64" && typeof response.result === "string") {
+    return Uint8Array.from(atob(response.result), (c) => c.charCodeAt(0)) as T;
+  }
+  return response.result as T;
+}
+
+// bridgeTransport routes messages through the functions the WPE extension
+// injects (window.go.<package>.<Struct> and window.strux), which already
+// handle the socket framing
+export const bridgeTransport: StruxTransport = async (message) => {
+  const parts = message.method.split(".");
+  // App methods live on the app struct, objects beside it, extensions at the root
+  const path = parts.length === 1 ? [%[1]s, %[2]s, ...parts] : parts.length === 2 ? [%[1]s, ...parts] : parts;
+  let target: any = path[0] === "strux" ? window : (window as any).go;
+  for (const part of path) {
+    target = target?.[part];
+  }
+  if (typeof target !== "function") {
+    return { id: message.id, error: "method " + message.method + " not found" };
+  }
+  try {
+    return { id: message.id, result: await target(...message.params) };
+  } catch (err) {
+    return { id: message.id, error: String(err) };
+  }
+};
+
+`
+
+// writeTSClient appends the runtime client to sb. Every binding gets a
+// function with the parameters of its Go signature that sends the method name
+// the dispatcher routes by; the result is typed by the declarations generated
+// from the same metadata.
+func (rt *Runtime) writeTSClient(sb *strings.Builder, bound []string, shapes map[string][]tsParam, extensionBindings map[string]interface{}) {
+	sb.WriteString(fmt.Sprintf(tsClientRuntime, jsString(rt.pkgName), jsString(rt.structName)))
+
+	clientType := "StruxBindings"
+	for _, namespace := range sortedKeys(extensionBindings) {
+		clientType += fmt.Sprintf(" & { %s: typeof %s }", tsPropertyName(namespace), namespace)
+	}
+	sb.WriteString(fmt.Sprintf("export type StruxClient = %s;\n\n", clientType))
+
+	sb.WriteString("// createStruxClient implements every binding on top of transport\n")
+	sb.WriteString("export function createStruxClient(transport: StruxTransport = bridgeTransport): StruxClient {\n")
+	sb.WriteString("  return {\n")
+	top, objects := groupObjectMethods(bound)
+	for _, name := range top {
+		sb.WriteString(fmt.Sprintf("    %s: %s,\n", tsPropertyName(name), tsClientFunc(name, shapes[name])))
+	}
+	for _, object := range sortedKeys(objects) {
+		sb.WriteString(fmt.Sprintf("    %s: {\n", tsPropertyName(object)))
+		for _, name := range objects[object] {
+			sb.WriteString(fmt.Sprintf("      %s: %s,\n", tsPropertyName(strings.TrimPrefix(name, object+".")), tsClientFunc(name, shapes[name])))
+		}
+		sb.WriteString("    },\n")
+	}
+
+	for _, namespace := range sortedKeys(extensionBindings) {
+		subNamespaces, ok := extensionBindings[namespace].(map[string]interface{})
+		if !ok {
+			continue
+		}
+		sb.WriteString(fmt.Sprintf("    %s: {\n", tsPropertyName(namespace)))
+		for _, subNamespace := range sortedKeys(subNamespaces) {
+			subData, ok := subNamespaces[subNamespace].(map[string]interface{})
+			if !ok {
+				continue
+			}
+			methods, ok := subData["methods"].([]extension.MethodInfo)
+			if !ok {
+				continue
+			}
+			sb.WriteString(fmt.Sprintf("      %s: {\n", tsPropertyName(subNamespace)))
+			for _, method := range methods {
+				qualified := namespace + "." + subNamespace + "." + method.Name
+				sb.WriteString(fmt.Sprintf("        %s: %s,\n", tsPropertyName(method.Name), tsClientFunc(qualified, newTSTypeRegistry().extensionParamsToTS(method))))
+			}
+			sb.WriteString("      },\n")
+		}
+		sb.WriteString("    },\n")
+	}
+	sb.WriteString("  } as StruxClient;\n")
+	sb.WriteString("}\n")
+}
+
+// tsClientFunc returns an arrow function forwarding params to method. Only
+// their shape matters here; the declarations type the arguments.
+func tsClientFunc(method string, params []tsParam) string {
+	untyped := make([]tsParam, len(params))
+	names := make([]string, len(params))
+	for i, p := range params {
+		untyped[i] = tsParam{Type: "unknown", Optional: p.Optional, Variadic: p.Variadic}
+		names[i] = fmt.Sprintf("arg%d", i)
+		if p.Variadic {
+			untyped[i].Type = "unknown[]"
+			names[i] = "..." + names[i]
+		}
+	}
+	return fmt.Sprintf("(%s) => call(transport, %s, [%s])", formatTSParams(untyped), jsString(method), strings.Join(names, ", "))
+}
+
+// jsString quotes s as a JavaScript string literal
+func jsString(s string) string {
+	return fmt.Sprintf("%q", s)
+}
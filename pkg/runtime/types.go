@@ -1,31 +1,120 @@
 package runtime
 
 import (
+	"bytes"
+	"encoding/json"
 	"fmt"
+	"io"
 	"os"
 	"reflect"
+	"sort"
 	"strings"
+	"time"
+	"unicode"
 
 	"github.com/strux-dev/strux/pkg/runtime/extension"
 )
 
+// TypeScriptOptions configures GenerateTypeScriptWithOptions
+type TypeScriptOptions struct {
+	// BrandedNumbers generates integer Go types as the branded type
+	// Int = number & { __int: void }, so passing a plain (possibly fractional)
+	// number where an integer is expected is a compile error. Floats stay number.
+	BrandedNumbers bool
+
+	// Client also emits a small runtime client: createStruxClient returns an
+	// object implementing every binding by sending its call envelope over a
+	// transport, so frontend code needs no hand-written marshalling. The
+	// output is then an ES module rather than a declarations-only file.
+	Client bool
+
+	// Namespaces restricts the extension namespaces emitted, for frontends
+	// that use only some of them. Each entry names a namespace ("strux"), a
+	// sub-namespace ("boot") or both ("strux.boot"); entries matching
+	// nothing are warned about. Empty emits every namespace.
+	Namespaces []string
+
+	// OmitAppBindings leaves the app's methods and Bind functions out of
+	// StruxBindings, independently of Namespaces
+	OmitAppBindings bool
+}
+
+// TypeMapper maps a Go type to a TypeScript type for generated definitions.
+// It returns ok false to leave the type to the next mapper or the defaults.
+type TypeMapper func(t reflect.Type) (tsType string, ok bool)
+
+// AddTypeMapper appends a mapper to the chain consulted before the built-in
+// mapping in GenerateTypeScript; the first mapper returning ok wins. It
+// applies wherever a type appears: parameters, results, fields and event
+// payloads. For example, to map sql.NullString to a nullable string:
+//
+//	rt.AddTypeMapper(func(t reflect.Type) (string, bool) {
+//		if t == reflect.TypeOf(sql.NullString{}) {
+//			return "string | null", true
+//		}
+//		return "", false
+//	})
+//
+// The type still needs a matching JSON encoding (MarshalJSON) at runtime.
+func (rt *Runtime) AddTypeMapper(mapper TypeMapper) {
+	rt.mu.Lock()
+	defer rt.mu.Unlock()
+	rt.typeMappers = append(rt.typeMappers, mapper)
+}
+
+// intBrandDecl declares the branded integer type used with BrandedNumbers
+const intBrandDecl = "type Int = number & { __int: void };\n"
+
 // GenerateTypeScript creates TypeScript type definitions for the bound methods and extensions
 func (rt *Runtime) GenerateTypeScript(outputPath string) error {
-	var sb strings.Builder
+	return rt.GenerateTypeScriptWithOptions(outputPath, TypeScriptOptions{})
+}
 
-	sb.WriteString("// Auto-generated TypeScript definitions for Strux bindings\n")
-	sb.WriteString("// Generated from Go struct methods and extensions\n\n")
+// GenerateTypeScriptWithOptions creates TypeScript type definitions using opts
+func (rt *Runtime) GenerateTypeScriptWithOptions(outputPath string, opts TypeScriptOptions) error {
+	var buf bytes.Buffer
+	if err := rt.GenerateTypeScriptToWithOptions(&buf, opts); err != nil {
+		return err
+	}
+	return os.WriteFile(outputPath, buf.Bytes(), 0644)
+}
+
+// GenerateTypeScriptTo writes the TypeScript type definitions to w
+func (rt *Runtime) GenerateTypeScriptTo(w io.Writer) error {
+	return rt.GenerateTypeScriptToWithOptions(w, TypeScriptOptions{})
+}
+
+// GenerateTypeScriptToWithOptions writes the TypeScript type definitions to w using opts
+func (rt *Runtime) GenerateTypeScriptToWithOptions(w io.Writer, opts TypeScriptOptions) error {
+	if err := validateApp(rt.app); err != nil {
+		return err
+	}
+
+	var sb strings.Builder
+	types := newTSTypeRegistry()
+	types.brandedNumbers = opts.BrandedNumbers
+	rt.mu.RLock()
+	types.mappers = append([]TypeMapper(nil), rt.typeMappers...)
+	rt.mu.RUnlock()
 
 	// Generate extension namespaces first
 	extensionBindings := rt.extensions.GetAllBindings()
-	for namespace, subNamespaces := range extensionBindings {
+	if len(opts.Namespaces) > 0 {
+		var unknown []string
+		extensionBindings, unknown = filterNamespaces(extensionBindings, opts.Namespaces)
+		for _, name := range unknown {
+			fmt.Printf("Strux Runtime: warning: namespace filter %q matches no extension\n", name)
+		}
+	}
+	for _, namespace := range sortedKeys(extensionBindings) {
+		subNamespaces := extensionBindings[namespace]
 		sb.WriteString(fmt.Sprintf("// %s namespace\n", namespace))
 		sb.WriteString(fmt.Sprintf("declare namespace %s {\n", namespace))
 
 		subNamespacesMap, ok := subNamespaces.(map[string]interface{})
 		if ok {
-			for subNamespace, subData := range subNamespacesMap {
-				subDataMap, ok := subData.(map[string]interface{})
+			for _, subNamespace := range sortedKeys(subNamespacesMap) {
+				subDataMap, ok := subNamespacesMap[subNamespace].(map[string]interface{})
 				if !ok {
 					continue
 				}
@@ -38,18 +127,10 @@ func (rt *Runtime) GenerateTypeScript(outputPath string) error {
 				sb.WriteString(fmt.Sprintf("  export namespace %s {\n", subNamespace))
 
 				for _, method := range methods {
-					// Build parameter list
-					params := []string{}
-					for i, paramType := range method.ParamTypes {
-						tsType := kindStringToTS(paramType)
-						params = append(params, fmt.Sprintf("arg%d: %s", i, tsType))
-					}
-
-					// All extension methods return Promise<void> for now
-					// (we could enhance this with return type metadata)
-					returnType := "Promise<void>"
+					params := formatTSParams(types.extensionParamsToTS(method))
+					returnType := fmt.Sprintf("Promise<%s>", types.extensionReturnToTS(method))
 					sb.WriteString(fmt.Sprintf("    export function %s(%s): %s;\n",
-						method.Name, strings.Join(params, ", "), returnType))
+						method.Name, params, returnType))
 				}
 
 				sb.WriteString("  }\n")
@@ -59,97 +140,214 @@ func (rt *Runtime) GenerateTypeScript(outputPath string) error {
 		sb.WriteString("}\n\n")
 	}
 
-	// Generate interface for user app methods
-	val := reflect.ValueOf(rt.app)
-	typ := val.Type()
+	// Generate interface for user app methods and functions added with Bind,
+	// under the names the dispatcher routes them by
+	bound := make(map[string]reflect.Type)
+	if !opts.OmitAppBindings {
+		rt.mu.RLock()
+		for name, method := range rt.methods {
+			bound[name] = method.Type()
+		}
+		rt.mu.RUnlock()
+	}
 
 	sb.WriteString("// User application bindings\n")
+	if opts.Client {
+		sb.WriteString("export ")
+	}
 	sb.WriteString("interface StruxBindings {\n")
 
-	for i := 0; i < val.NumMethod(); i++ {
-		method := val.Method(i)
-		methodType := method.Type()
-		methodName := typ.Method(i).Name
+	boundNames := make([]string, 0, len(bound))
+	for name := range bound {
+		boundNames = append(boundNames, name)
+	}
+	sort.Strings(boundNames)
 
-		// Only process exported methods
-		if methodName[0] < 'A' || methodName[0] > 'Z' {
-			continue
-		}
+	topNames, objects := groupObjectMethods(boundNames)
+	for _, methodName := range topNames {
+		methodType := bound[methodName]
 
-		// Build parameter list
-		params := []string{}
-		for j := 0; j < methodType.NumIn(); j++ {
-			paramType := methodType.In(j)
-			tsType := goTypeToTS(paramType)
-			params = append(params, fmt.Sprintf("arg%d: %s", j, tsType))
-		}
+		params := formatTSParams(types.methodParamsToTS(methodType))
+		returnType := fmt.Sprintf("Promise<%s>", types.methodReturnToTS(methodType))
+		sb.WriteString(fmt.Sprintf("  %s(%s): %s;\n", tsPropertyName(methodName), params, returnType))
+	}
 
-		// Determine return type
-		returnType := "void"
-		if methodType.NumOut() > 0 {
-			// Get first return value (ignore error if it's the last one)
-			firstReturn := methodType.Out(0)
+	// Objects added with BindObject nest their methods under the object name
+	for _, object := range sortedKeys(objects) {
+		sb.WriteString(fmt.Sprintf("  %s: {\n", tsPropertyName(object)))
+		for _, name := range objects[object] {
+			methodType := bound[name]
 
-			// Check if last return is error
-			hasError := false
-			if methodType.NumOut() > 1 {
-				lastReturn := methodType.Out(methodType.NumOut() - 1)
-				if lastReturn.Implements(reflect.TypeOf((*error)(nil)).Elem()) {
-					hasError = true
-				}
-			}
-
-			if methodType.NumOut() == 1 && firstReturn.Implements(reflect.TypeOf((*error)(nil)).Elem()) {
-				// Only returns error
-				returnType = "void"
-			} else {
-				returnType = goTypeToTS(firstReturn)
-				if hasError {
-					returnType += " | null" // Can be null if error occurs
-				}
-			}
+			params := formatTSParams(types.methodParamsToTS(methodType))
+			returnType := fmt.Sprintf("Promise<%s>", types.methodReturnToTS(methodType))
+			sb.WriteString(fmt.Sprintf("    %s(%s): %s;\n", tsPropertyName(strings.TrimPrefix(name, object+".")), params, returnType))
 		}
-
-		returnType = fmt.Sprintf("Promise<%s>", returnType)
-		sb.WriteString(fmt.Sprintf("  %s(%s): %s;\n", methodName, strings.Join(params, ", "), returnType))
+		sb.WriteString("  };\n")
 	}
 
 	sb.WriteString("}\n\n")
 
-	// Extend Window interface
+	// Map each event declared with RegisterEvent to its payload type
+	sb.WriteString("// Events emitted from Go with rt.Emit\n")
+	sb.WriteString("interface StruxEvents {\n")
+	rt.mu.RLock()
+	for _, name := range rt.eventNamesLocked() {
+		payloadType := "void"
+		if t := rt.events[name]; t != nil {
+			payloadType = types.goTypeToTS(t)
+		}
+		sb.WriteString(fmt.Sprintf("  %s: %s;\n", tsPropertyName(name), payloadType))
+	}
+	rt.mu.RUnlock()
+	sb.WriteString("}\n\n")
+
+	// Module-scope aliases for the namespaces, which the global declarations
+	// below would otherwise see shadowed by the global strux
+	for _, namespace := range sortedKeys(extensionBindings) {
+		sb.WriteString(fmt.Sprintf("type %sNamespace = typeof %s;\n", tsIdentifier(namespace), namespace))
+	}
+	if len(extensionBindings) > 0 {
+		sb.WriteString("\n")
+	}
+
+	// Extend Window interface. Strux is the single root: window.strux (or the
+	// global strux) reaches every strux.* namespace with full typing, and
+	// other extension namespaces hang off window the same way.
 	sb.WriteString("// Extend Window interface with Strux bindings\n")
 	sb.WriteString("declare global {\n")
-	sb.WriteString("  interface Window extends StruxBindings {}\n")
+	sb.WriteString("  interface Strux {\n")
+	sb.WriteString("    on<K extends keyof StruxEvents>(event: K, callback: (payload: StruxEvents[K]) => void): () => void;\n")
+	if subNamespaces, ok := extensionBindings["strux"].(map[string]interface{}); ok {
+		for _, subNamespace := range sortedKeys(subNamespaces) {
+			sb.WriteString(fmt.Sprintf("    %s: struxNamespace[%q];\n", tsPropertyName(subNamespace), subNamespace))
+		}
+	}
+	sb.WriteString("  }\n")
+	sb.WriteString("  interface Window extends StruxBindings {\n")
+	sb.WriteString("    strux: Strux;\n")
+	for _, namespace := range sortedKeys(extensionBindings) {
+		if namespace != "strux" {
+			sb.WriteString(fmt.Sprintf("    %s: %sNamespace;\n", tsPropertyName(namespace), tsIdentifier(namespace)))
+		}
+	}
+	sb.WriteString("  }\n")
+	sb.WriteString("  var strux: Strux;\n")
 	sb.WriteString("}\n\n")
 
-	sb.WriteString("export {};\n")
+	if opts.Client {
+		shapes := make(map[string][]tsParam, len(bound))
+		for name, methodType := range bound {
+			shapes[name] = types.methodParamsToTS(methodType)
+		}
+		rt.writeTSClient(&sb, boundNames, shapes, extensionBindings)
+	} else {
+		sb.WriteString("export {};\n")
+	}
+
+	// Struct interfaces go first, each emitted once no matter how many methods use it
+	var out strings.Builder
+	out.WriteString("// Auto-generated TypeScript definitions for Strux bindings\n")
+	out.WriteString("// Generated from Go struct methods and extensions\n\n")
+	if opts.BrandedNumbers {
+		out.WriteString(intBrandDecl + "\n")
+	}
+	for _, decl := range types.decls {
+		out.WriteString(decl)
+		out.WriteString("\n")
+	}
+	out.WriteString(sb.String())
+
+	_, err := io.WriteString(w, out.String())
+	return err
+}
+
+var (
+	timeType     = reflect.TypeOf(time.Time{})
+	durationType = reflect.TypeOf(time.Duration(0))
+	bytesType    = reflect.TypeOf([]byte(nil))
+	readerType   = reflect.TypeOf((*io.Reader)(nil)).Elem()
+)
+
+// isBinaryResult reports whether a return type is sent through the binary path
+func isBinaryResult(t reflect.Type) bool {
+	return t == bytesType || (t.Kind() == reflect.Interface && t.Implements(readerType))
+}
+
+// tsTypeRegistry assigns every Go struct type exactly one TypeScript interface
+// name and collects the interface declarations in first-use order
+type tsTypeRegistry struct {
+	names map[reflect.Type]string // struct type -> interface name
+	taken map[string]bool         // interface names already assigned
+	decls []string
 
-	// Write to file
-	return os.WriteFile(outputPath, []byte(sb.String()), 0644)
+	brandedNumbers bool         // integers map to Int rather than number
+	mappers        []TypeMapper // custom mappings tried before the built-in ones
+}
+
+func newTSTypeRegistry() *tsTypeRegistry {
+	return &tsTypeRegistry{
+		names: make(map[reflect.Type]string),
+		// Names the generated file declares itself
+		taken: map[string]bool{"StruxBindings": true, "StruxEvents": true, "Strux": true, "Window": true, "Int": true},
+	}
 }
 
 // goTypeToTS maps Go types to TypeScript types
-func goTypeToTS(t reflect.Type) string {
+func (r *tsTypeRegistry) goTypeToTS(t reflect.Type) string {
+	for _, mapper := range r.mappers {
+		if tsType, ok := mapper(t); ok {
+			return tsType
+		}
+	}
+
+	// Types with custom JSON encodings are matched before their kind
+	switch t {
+	case timeType:
+		return "string" // RFC 3339 / ISO 8601
+	case durationType:
+		if r.brandedNumbers {
+			return "Int"
+		}
+		return "number" // nanoseconds
+	case bytesType:
+		return "string" // base64
+	}
+
 	switch t.Kind() {
 	case reflect.String:
 		return "string"
 	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
-		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
-		reflect.Float32, reflect.Float64:
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		if r.brandedNumbers {
+			return "Int"
+		}
+		return "number"
+	case reflect.Float32, reflect.Float64:
 		return "number"
 	case reflect.Bool:
 		return "boolean"
 	case reflect.Slice, reflect.Array:
-		elemType := goTypeToTS(t.Elem())
+		elemType := r.goTypeToTS(t.Elem())
+		if strings.Contains(elemType, " | ") {
+			elemType = "(" + elemType + ")"
+		}
 		return elemType + "[]"
 	case reflect.Map:
-		keyType := goTypeToTS(t.Key())
-		valueType := goTypeToTS(t.Elem())
+		keyType := r.goTypeToTS(t.Key())
+		valueType := r.goTypeToTS(t.Elem())
 		return fmt.Sprintf("Record<%s, %s>", keyType, valueType)
 	case reflect.Struct:
-		return "object"
+		if t.Name() == "" {
+			return "{ " + strings.Join(r.structFields(t), " ") + " }"
+		}
+		return r.interfaceFor(t)
 	case reflect.Ptr:
-		return goTypeToTS(t.Elem())
+		// A nil pointer marshals to null, so single pointers are nullable.
+		// Double pointers are rare enough that they stay untyped.
+		if t.Elem().Kind() == reflect.Ptr {
+			return "any"
+		}
+		return r.goTypeToTS(t.Elem()) + " | null"
 	case reflect.Interface:
 		return "any"
 	default:
@@ -157,14 +355,324 @@ func goTypeToTS(t reflect.Type) string {
 	}
 }
 
+// interfaceFor returns the interface name for a named struct type,
+// declaring the interface the first time the type is seen
+func (r *tsTypeRegistry) interfaceFor(t reflect.Type) string {
+	if name, ok := r.names[t]; ok {
+		return name
+	}
+
+	name := r.uniqueName(t)
+	// Register before walking fields so self-referencing structs terminate
+	r.names[t] = name
+	r.taken[name] = true
+
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("interface %s {\n", name))
+	for _, field := range r.structFields(t) {
+		sb.WriteString("  " + field + "\n")
+	}
+	sb.WriteString("}\n")
+	r.decls = append(r.decls, sb.String())
+
+	return name
+}
+
+// uniqueName picks an interface name for t. Types from different packages that
+// share a simple name are disambiguated with their package name as a prefix.
+func (r *tsTypeRegistry) uniqueName(t reflect.Type) string {
+	base := tsIdentifier(t.Name())
+	if !r.taken[base] {
+		return base
+	}
+
+	pkg := t.PkgPath()
+	pkg = pkg[strings.LastIndex(pkg, "/")+1:]
+	prefix := ""
+	if pkg != "" {
+		prefix = strings.ToUpper(pkg[:1]) + pkg[1:]
+	}
+
+	name := tsIdentifier(prefix + base)
+	for i := 2; r.taken[name]; i++ {
+		name = tsIdentifier(fmt.Sprintf("%s%s%d", prefix, base, i))
+	}
+	return name
+}
+
+// structFields renders the fields of a struct the way encoding/json encodes them.
+// Pointer, omitempty and defaulted fields are optional, the default documented
+// in JSDoc; embedded structs are flattened.
+func (r *tsTypeRegistry) structFields(t reflect.Type) []string {
+	var fields []string
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name, opts, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "-" && opts == "" {
+			continue
+		}
+
+		if field.Anonymous && name == "" {
+			embedded := field.Type
+			if embedded.Kind() == reflect.Ptr {
+				embedded = embedded.Elem()
+			}
+			if embedded.Kind() == reflect.Struct {
+				fields = append(fields, r.structFields(embedded)...)
+				continue
+			}
+		}
+
+		if !field.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+
+		optional := ""
+		if field.Type.Kind() == reflect.Ptr || strings.Contains(","+opts+",", ",omitempty,") {
+			optional = "?"
+		}
+		if value, ok := extension.DefaultTag(field); ok {
+			optional = "?"
+			fields = append(fields, fmt.Sprintf("/** @default %s */", tsDefault(field.Type, value)))
+		}
+
+		modifier := ""
+		if isReadonlyField(field) {
+			modifier = "readonly "
+		}
+
+		fields = append(fields, fmt.Sprintf("%s%s%s: %s;", modifier, tsPropertyName(name), optional, r.goTypeToTS(field.Type)))
+	}
+	return fields
+}
+
+// tsDefault renders the text of a default tag for JSDoc: strings quoted,
+// everything else as written, and never closing the comment early
+func tsDefault(t reflect.Type, value string) string {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t.Kind() == reflect.String {
+		quoted, _ := json.Marshal(value)
+		value = string(quoted)
+	}
+	return strings.ReplaceAll(value, "*/", "*\\/")
+}
+
+// isReadonlyField reports whether a field is tagged strux:"readonly", meaning
+// the frontend may read it but writes are rejected
+func isReadonlyField(field reflect.StructField) bool {
+	for _, opt := range strings.Split(field.Tag.Get("strux"), ",") {
+		if opt == "readonly" {
+			return true
+		}
+	}
+	return false
+}
+
+// tsIdentifier replaces characters that are not valid in a TypeScript identifier,
+// such as the brackets in generic type names
+func tsIdentifier(name string) string {
+	return strings.Map(func(r rune) rune {
+		if r == '_' || r == '$' || unicode.IsLetter(r) || unicode.IsDigit(r) {
+			return r
+		}
+		return '_'
+	}, name)
+}
+
+// tsPropertyName quotes property names that are not valid identifiers
+func tsPropertyName(name string) string {
+	if name != "" && tsIdentifier(name) == name && !unicode.IsDigit(rune(name[0])) {
+		return name
+	}
+	return fmt.Sprintf("%q", name)
+}
+
+// sortedKeys returns the keys of m in sorted order so output is deterministic
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// filterNamespaces returns the namespaces and sub-namespaces of bindings
+// that filter names (see TypeScriptOptions.Namespaces), and the filter
+// entries that matched nothing
+func filterNamespaces(bindings map[string]interface{}, filter []string) (map[string]interface{}, []string) {
+	matched := make(map[string]bool, len(filter))
+	kept := make(map[string]interface{})
+	for _, namespace := range sortedKeys(bindings) {
+		subNamespaces, ok := bindings[namespace].(map[string]interface{})
+		if !ok {
+			continue
+		}
+		keptSubs := make(map[string]interface{})
+		for _, subNamespace := range sortedKeys(subNamespaces) {
+			for _, name := range filter {
+				if name == namespace || name == subNamespace || name == namespace+"."+subNamespace {
+					matched[name] = true
+					keptSubs[subNamespace] = subNamespaces[subNamespace]
+				}
+			}
+		}
+		if len(keptSubs) > 0 {
+			kept[namespace] = keptSubs
+		}
+	}
+
+	var unknown []string
+	for _, name := range filter {
+		if !matched[name] {
+			unknown = append(unknown, name)
+		}
+	}
+	return kept, unknown
+}
+
+// tsParam is one positional parameter of a generated signature
+type tsParam struct {
+	Type     string
+	Optional bool // a trailing pointer the caller may leave off
+	Variadic bool // a rest parameter gathering a Go variadic
+}
+
+// groupObjectMethods splits sorted binding names into top-level names and the
+// "object.Method" names of each object added with BindObject, keyed by object
+func groupObjectMethods(names []string) (top []string, objects map[string][]string) {
+	objects = make(map[string][]string)
+	for _, name := range names {
+		if object, _, ok := strings.Cut(name, "."); ok {
+			objects[object] = append(objects[object], name)
+		} else {
+			top = append(top, name)
+		}
+	}
+	return top, objects
+}
+
+// methodParamsToTS maps a bound method's parameters to TypeScript. Trailing
+// pointers become optional and a variadic becomes a rest parameter, since the
+// dispatcher fills in whatever the caller leaves off.
+func (r *tsTypeRegistry) methodParamsToTS(methodType reflect.Type) []tsParam {
+	required := extension.RequiredParams(methodType)
+	params := make([]tsParam, methodType.NumIn())
+	for i := range params {
+		params[i] = tsParam{Type: r.goTypeToTS(methodType.In(i)), Optional: i >= required}
+	}
+	if methodType.IsVariadic() {
+		params[len(params)-1].Variadic = true
+	}
+	return params
+}
+
+// extensionParamsToTS is methodParamsToTS for an extension method's metadata
+func (r *tsTypeRegistry) extensionParamsToTS(method extension.MethodInfo) []tsParam {
+	params := make([]tsParam, len(method.ParamTypes))
+	for i, paramType := range method.ParamTypes {
+		params[i] = tsParam{Type: r.kindStringToTS(paramType), Optional: i >= method.Required}
+	}
+	if method.Variadic && len(params) > 0 {
+		params[len(params)-1].Variadic = true
+	}
+	return params
+}
+
+// formatTSParams renders params as a parameter list with positional names
+// (arg0, arg1, ...)
+func formatTSParams(params []tsParam) string {
+	parts := make([]string, len(params))
+	for i, p := range params {
+		switch {
+		case p.Variadic:
+			parts[i] = fmt.Sprintf("...arg%d: %s", i, p.Type)
+		case p.Optional:
+			parts[i] = fmt.Sprintf("arg%d?: %s", i, p.Type)
+		default:
+			parts[i] = fmt.Sprintf("arg%d: %s", i, p.Type)
+		}
+	}
+	return strings.Join(parts, ", ")
+}
+
+// methodReturnToTS maps a bound method's results to the type its promise
+// resolves to: the first result, or void when it only returns an error
+func (r *tsTypeRegistry) methodReturnToTS(methodType reflect.Type) string {
+	if methodType.NumOut() == 0 {
+		return "void"
+	}
+
+	// Get first return value (ignore error if it's the last one)
+	firstReturn := methodType.Out(0)
+
+	// Check if last return is error
+	hasError := false
+	if methodType.NumOut() > 1 {
+		lastReturn := methodType.Out(methodType.NumOut() - 1)
+		if lastReturn.Implements(errorType) {
+			hasError = true
+		}
+	}
+
+	if methodType.NumOut() == 1 && firstReturn.Implements(errorType) {
+		// Only returns error
+		return "void"
+	}
+	if isBinaryResult(firstReturn) && (methodType.NumOut() == 1 || hasError && methodType.NumOut() == 2) {
+		// Decoded by the bridge from base64 (see encodeBinaryResult)
+		if hasError {
+			return "Uint8Array | null"
+		}
+		return "Uint8Array"
+	}
+
+	returnType := r.goTypeToTS(firstReturn)
+	if hasError && !strings.HasSuffix(returnType, " | null") {
+		returnType += " | null" // Can be null if error occurs
+	}
+	return returnType
+}
+
+// extensionReturnToTS maps an extension method's results to the type its
+// promise resolves to. Several results arrive as an array (see ExecuteMethod).
+func (r *tsTypeRegistry) extensionReturnToTS(method extension.MethodInfo) string {
+	var returnType string
+	switch len(method.ReturnTypes) {
+	case 0:
+		return "void"
+	case 1:
+		returnType = r.kindStringToTS(method.ReturnTypes[0])
+	default:
+		elems := make([]string, len(method.ReturnTypes))
+		for i, kind := range method.ReturnTypes {
+			elems[i] = r.kindStringToTS(kind)
+		}
+		returnType = "[" + strings.Join(elems, ", ") + "]"
+	}
+	if method.HasError {
+		returnType += " | null" // Can be null if error occurs
+	}
+	return returnType
+}
+
 // kindStringToTS converts a string representation of a Go kind to TypeScript
-func kindStringToTS(kindStr string) string {
+func (r *tsTypeRegistry) kindStringToTS(kindStr string) string {
 	switch kindStr {
 	case "string":
 		return "string"
 	case "int", "int8", "int16", "int32", "int64",
-		"uint", "uint8", "uint16", "uint32", "uint64",
-		"float32", "float64":
+		"uint", "uint8", "uint16", "uint32", "uint64":
+		if r.brandedNumbers {
+			return "Int"
+		}
+		return "number"
+	case "float32", "float64":
 		return "number"
 	case "bool":
 		return "boolean"
@@ -0,0 +1,119 @@
+package runtime
+
+import (
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+type timedEvent struct {
+	At    time.Time
+	Every time.Duration
+	Last  *time.Time
+}
+
+type timeApp struct{}
+
+func (timeApp) Now() time.Time { return time.Time{} }
+
+func (timeApp) Wait(d time.Duration, until time.Time) timedEvent { return timedEvent{} }
+
+func TestTimeTypesToTS(t *testing.T) {
+	tests := []struct {
+		value   interface{}
+		branded bool
+		want    string
+	}{
+		{time.Time{}, false, "string"},
+		{(*time.Time)(nil), false, "string | null"},
+		{[]time.Time(nil), false, "string[]"},
+		{map[string]time.Time(nil), false, "Record<string, string>"},
+		{time.Duration(0), false, "number"},
+		{time.Duration(0), true, "Int"},
+		{[]time.Duration(nil), false, "number[]"},
+		{struct{ At time.Time }{}, false, "{ At: string; }"},
+	}
+	for _, tt := range tests {
+		r := newTSTypeRegistry()
+		r.brandedNumbers = tt.branded
+		typ := reflect.TypeOf(tt.value)
+		if got := r.goTypeToTS(typ); got != tt.want {
+			t.Errorf("goTypeToTS(%v, branded=%v) = %q, want %q", typ, tt.branded, got, tt.want)
+		}
+	}
+}
+
+func TestGenerateTypeScriptTimeTypes(t *testing.T) {
+	var out strings.Builder
+	if err := New(timeApp{}).GenerateTypeScriptTo(&out); err != nil {
+		t.Fatal(err)
+	}
+	ts := out.String()
+	for _, want := range []string{
+		"Now(): Promise<string>;",
+		"Wait(arg0: number, arg1: string): Promise<timedEvent>;",
+		"  At: string;\n  Every: number;\n  Last?: string | null;\n",
+	} {
+		if !strings.Contains(ts, want) {
+			t.Errorf("generated TypeScript lacks %q:\n%s", want, ts)
+		}
+	}
+}
+
+type User struct {
+	Name string
+}
+
+// URL shares its simple name with net/url.URL
+type URL struct {
+	Href string
+}
+
+type userApp struct{}
+
+func (userApp) GetUser(id int) User        { return User{} }
+func (userApp) FindUser(name string) *User { return nil }
+func (userApp) ListUsers() []User          { return nil }
+func (userApp) Link() URL                  { return URL{} }
+func (userApp) Parse(raw string) url.URL   { return url.URL{} }
+
+func TestGenerateTypeScriptEmitsEachStructOnce(t *testing.T) {
+	var out strings.Builder
+	if err := New(userApp{}).GenerateTypeScriptTo(&out); err != nil {
+		t.Fatal(err)
+	}
+	ts := out.String()
+
+	if n := strings.Count(ts, "interface User {"); n != 1 {
+		t.Errorf("interface User declared %d times, want once:\n%s", n, ts)
+	}
+	for _, want := range []string{
+		"GetUser(arg0: number): Promise<User>;",
+		"FindUser(arg0: string): Promise<User | null>;",
+		"ListUsers(): Promise<User[]>;",
+		"interface URL {",
+		"interface UrlURL {",
+	} {
+		if !strings.Contains(ts, want) {
+			t.Errorf("generated TypeScript lacks %q:\n%s", want, ts)
+		}
+	}
+	// Whichever URL was named first, the methods must use different names
+	link := between(ts, "Link(): Promise<", ">;")
+	parse := between(ts, "Parse(arg0: string): Promise<", ">;")
+	if link == parse || link == "" || parse == "" {
+		t.Errorf("Link returns %q and Parse returns %q", link, parse)
+	}
+}
+
+// between returns the text of s after the first start and before the next end
+func between(s, start, end string) string {
+	_, rest, ok := strings.Cut(s, start)
+	if !ok {
+		return ""
+	}
+	inner, _, _ := strings.Cut(rest, end)
+	return inner
+}
@@ -57,6 +57,8 @@
 #include "seat.h"
 #include "server.h"
 #include "splash.h"
+#include "control.h"
+#include "dpms.h"
 #include "view.h"
 #include "xdg_shell.h"
 #if CAGE_HAS_XWAYLAND
@@ -236,6 +238,8 @@ usage(FILE *file, const char *cage)
 		" -s\t Allow VT switching\n"
 		" -v\t Show the version number and exit\n"
 		" --splash-image=PATH\t Show splash screen from PNG image\n"
+		" --hide-cursor\t Never show the cursor, even with a pointer device attached\n"
+		" --blank-timeout=SECONDS\t Turn the display off after SECONDS without input (0 never blanks)\n"
 		"\n"
 		" Use -- when you want to pass arguments to APPLICATION\n",
 		cage);
@@ -246,6 +250,8 @@ parse_args(struct cg_server *server, int argc, char *argv[])
 {
 	static struct option long_options[] = {
 		{"splash-image", required_argument, NULL, 'S'},
+		{"hide-cursor", no_argument, NULL, 'C'},
+		{"blank-timeout", required_argument, NULL, 'B'},
 		{NULL, 0, NULL, 0}
 	};
 
@@ -277,6 +283,12 @@ parse_args(struct cg_server *server, int argc, char *argv[])
 		case 'S':
 			server->splash_image_path = strdup(optarg);
 			break;
+		case 'C':
+			server->hide_cursor = true;
+			break;
+		case 'B':
+			server->blank_timeout = atoi(optarg);
+			break;
 		default:
 			usage(stderr, argv[0]);
 			return false;
@@ -432,6 +444,18 @@ main(int argc, char *argv[])
 	wl_signal_add(&server.idle_inhibit_v1->events.new_inhibitor, &server.new_idle_inhibitor_v1);
 	wl_list_init(&server.inhibitors);
 
+	// Strux: blank the display after blank_timeout seconds without input
+	server.dpms = dpms_create(&server, server.blank_timeout);
+	if (!server.dpms) {
+		wlr_log(WLR_ERROR, "Unable to create the display power manager");
+	}
+
+	// Strux: serve the control socket, with or without a splash image
+	server.control = control_create(&server);
+	if (!server.control) {
+		wlr_log(WLR_ERROR, "Unable to create the Strux control socket");
+	}
+
 	struct wlr_xdg_shell *xdg_shell = wlr_xdg_shell_create(server.wl_display, 5);
 	if (!xdg_shell) {
 		wlr_log(WLR_ERROR, "Unable to create the XDG shell interface");
@@ -647,7 +671,9 @@ end:
 	if (sigchld_source) {
 		wl_event_source_remove(sigchld_source);
 	}
+	control_destroy(server.control);
 	splash_destroy(server.splash);
+	dpms_destroy(server.dpms);
 	free(server.splash_image_path);
 	seat_destroy(server.seat);
 	/* This function is not null-safe, but we only ever get here
@@ -0,0 +1,165 @@
+/*
+ * Strux OS control socket for Cage
+ *
+ * Lets the runtime hide the splash screen, show or hide the cursor and
+ * control display blanking. Commands for a feature that isn't set up, such
+ * as HIDE_SPLASH without a splash image, are accepted and do nothing.
+ */
+
+#define _POSIX_C_SOURCE 200809L
+
+#include "control.h"
+#include "dpms.h"
+#include "seat.h"
+#include "server.h"
+#include "splash.h"
+
+#include <errno.h>
+#include <stdlib.h>
+#include <string.h>
+#include <sys/socket.h>
+#include <sys/stat.h>
+#include <sys/un.h>
+#include <unistd.h>
+#include <wlr/util/log.h>
+
+#define STRUX_CONTROL_SOCKET "/tmp/strux-cage-control.sock"
+
+// Context for client connection event source
+struct client_context {
+	struct cg_control *control;
+	struct wl_event_source *source;
+	int fd;
+};
+
+static void
+handle_command(struct cg_server *server, const char *command)
+{
+	if (strcmp(command, "HIDE_SPLASH") == 0) {
+		wlr_log(WLR_INFO, "Received HIDE_SPLASH command");
+		splash_hide(server->splash);
+	} else if (strcmp(command, "HIDE_CURSOR") == 0) {
+		wlr_log(WLR_INFO, "Received HIDE_CURSOR command");
+		seat_set_cursor_hidden(server->seat, true);
+	} else if (strcmp(command, "SHOW_CURSOR") == 0) {
+		wlr_log(WLR_INFO, "Received SHOW_CURSOR command");
+		seat_set_cursor_hidden(server->seat, false);
+	} else if (strcmp(command, "WAKE") == 0) {
+		wlr_log(WLR_INFO, "Received WAKE command");
+		dpms_set_blanked(server->dpms, false);
+	} else if (strcmp(command, "BLANK") == 0) {
+		wlr_log(WLR_INFO, "Received BLANK command");
+		dpms_set_blanked(server->dpms, true);
+	} else if (strncmp(command, "BLANK_TIMEOUT ", 14) == 0) {
+		wlr_log(WLR_INFO, "Received %s command", command);
+		dpms_set_timeout(server->dpms, atoi(command + 14));
+	} else {
+		wlr_log(WLR_ERROR, "Unknown control command: %s", command);
+	}
+}
+
+static int
+handle_control_message(int fd, uint32_t mask, void *data)
+{
+	struct client_context *ctx = data;
+	char buffer[256];
+
+	ssize_t n = recv(fd, buffer, sizeof(buffer) - 1, 0);
+	if (n > 0) {
+		buffer[n] = '\0';
+		handle_command(ctx->control->server, buffer);
+	}
+
+	// One command per connection: remove the event source and cleanup
+	wl_event_source_remove(ctx->source);
+	close(fd);
+	free(ctx);
+	return 0;
+}
+
+static int
+handle_control_connection(int fd, uint32_t mask, void *data)
+{
+	struct cg_control *control = data;
+
+	int client_fd = accept(control->fd, NULL, NULL);
+	if (client_fd < 0) {
+		wlr_log(WLR_ERROR, "Failed to accept control connection: %s", strerror(errno));
+		return 0;
+	}
+
+	// Create context for this client connection
+	struct client_context *ctx = calloc(1, sizeof(struct client_context));
+	if (!ctx) {
+		close(client_fd);
+		return 0;
+	}
+	ctx->control = control;
+	ctx->fd = client_fd;
+
+	struct wl_event_loop *loop = wl_display_get_event_loop(control->server->wl_display);
+	ctx->source = wl_event_loop_add_fd(loop, client_fd, WL_EVENT_READABLE, handle_control_message, ctx);
+
+	return 0;
+}
+
+struct cg_control *
+control_create(struct cg_server *server)
+{
+	unlink(STRUX_CONTROL_SOCKET);
+
+	int fd = socket(AF_UNIX, SOCK_STREAM, 0);
+	if (fd < 0) {
+		wlr_log(WLR_ERROR, "Failed to create control socket: %s", strerror(errno));
+		return NULL;
+	}
+
+	struct sockaddr_un addr = {0};
+	addr.sun_family = AF_UNIX;
+	strncpy(addr.sun_path, STRUX_CONTROL_SOCKET, sizeof(addr.sun_path) - 1);
+
+	if (bind(fd, (struct sockaddr *)&addr, sizeof(addr)) < 0) {
+		wlr_log(WLR_ERROR, "Failed to bind control socket: %s", strerror(errno));
+		close(fd);
+		return NULL;
+	}
+
+	if (listen(fd, 5) < 0) {
+		wlr_log(WLR_ERROR, "Failed to listen on control socket: %s", strerror(errno));
+		close(fd);
+		unlink(STRUX_CONTROL_SOCKET);
+		return NULL;
+	}
+
+	chmod(STRUX_CONTROL_SOCKET, 0666);
+
+	struct cg_control *control = calloc(1, sizeof(struct cg_control));
+	if (!control) {
+		close(fd);
+		unlink(STRUX_CONTROL_SOCKET);
+		return NULL;
+	}
+	control->server = server;
+	control->fd = fd;
+
+	struct wl_event_loop *loop = wl_display_get_event_loop(server->wl_display);
+	control->source = wl_event_loop_add_fd(loop, fd, WL_EVENT_READABLE, handle_control_connection, control);
+
+	wlr_log(WLR_INFO, "Control socket listening: %s", STRUX_CONTROL_SOCKET);
+	return control;
+}
+
+void
+control_destroy(struct cg_control *control)
+{
+	if (!control) {
+		return;
+	}
+
+	if (control->source) {
+		wl_event_source_remove(control->source);
+	}
+	close(control->fd);
+	unlink(STRUX_CONTROL_SOCKET);
+	free(control);
+}
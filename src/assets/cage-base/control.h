@@ -0,0 +1,24 @@
+#ifndef CG_CONTROL_H
+#define CG_CONTROL_H
+
+#include <wayland-server-core.h>
+
+struct cg_server;
+
+/**
+ * Control socket for the Strux runtime (strux.boot and strux.display).
+ * Each connection carries one command: HIDE_SPLASH, HIDE_CURSOR,
+ * SHOW_CURSOR, BLANK, WAKE or BLANK_TIMEOUT <seconds>. It is served whether
+ * or not a splash image was configured.
+ */
+struct cg_control {
+	struct cg_server *server;
+	int fd;
+	struct wl_event_source *source;
+};
+
+struct cg_control *control_create(struct cg_server *server);
+
+void control_destroy(struct cg_control *control);
+
+#endif
@@ -0,0 +1,151 @@
+/*
+ * Strux OS display power management for Cage
+ *
+ * Outputs are powered down (not removed from the layout) when the idle timer
+ * fires, so the application keeps its geometry and the first input event
+ * brings the picture straight back. Idle inhibitors, such as a playing video,
+ * keep the display on.
+ */
+
+#define _POSIX_C_SOURCE 200809L
+
+#include "dpms.h"
+#include "output.h"
+#include "server.h"
+
+#include <stdlib.h>
+#include <wlr/types/wlr_output.h>
+#include <wlr/util/log.h>
+
+static void
+set_outputs_enabled(struct cg_server *server, bool enabled)
+{
+	struct cg_output *output;
+	wl_list_for_each (output, &server->outputs, link) {
+		// Only touch outputs DPMS itself turned off, so outputs disabled by
+		// the multi-output mode stay disabled on wake
+		if (enabled ? !output->dpms_off : !output->wlr_output->enabled) {
+			continue;
+		}
+
+		struct wlr_output_state state = {0};
+		wlr_output_state_set_enabled(&state, enabled);
+		if (!wlr_output_commit_state(output->wlr_output, &state)) {
+			wlr_log(WLR_ERROR, "DPMS: failed to turn output %s %s", output->wlr_output->name,
+				enabled ? "on" : "off");
+		} else {
+			output->dpms_off = !enabled;
+		}
+		wlr_output_state_finish(&state);
+	}
+}
+
+static void
+schedule(struct cg_dpms *dpms)
+{
+	if (dpms->timer) {
+		wl_event_source_timer_update(dpms->timer, dpms->timeout_ms);
+	}
+}
+
+static int
+handle_timeout(void *data)
+{
+	struct cg_dpms *dpms = data;
+
+	if (dpms->timeout_ms == 0) {
+		return 0;
+	}
+	if (!wl_list_empty(&dpms->server->inhibitors)) {
+		// Check again later rather than blanking over an inhibitor
+		schedule(dpms);
+		return 0;
+	}
+
+	wlr_log(WLR_INFO, "DPMS: idle for %d ms, blanking display", dpms->timeout_ms);
+	dpms_set_blanked(dpms, true);
+	return 0;
+}
+
+struct cg_dpms *
+dpms_create(struct cg_server *server, int timeout_seconds)
+{
+	struct cg_dpms *dpms = calloc(1, sizeof(struct cg_dpms));
+	if (!dpms) {
+		return NULL;
+	}
+
+	dpms->server = server;
+	struct wl_event_loop *loop = wl_display_get_event_loop(server->wl_display);
+	dpms->timer = wl_event_loop_add_timer(loop, handle_timeout, dpms);
+	if (!dpms->timer) {
+		wlr_log(WLR_ERROR, "DPMS: unable to create idle timer, blanking disabled");
+	}
+
+	dpms_set_timeout(dpms, timeout_seconds);
+	return dpms;
+}
+
+void
+dpms_set_timeout(struct cg_dpms *dpms, int timeout_seconds)
+{
+	if (!dpms) {
+		return;
+	}
+
+	dpms->timeout_ms = timeout_seconds > 0 ? timeout_seconds * 1000 : 0;
+	wlr_log(WLR_INFO, "DPMS: blank timeout %s", dpms->timeout_ms ? "enabled" : "disabled");
+
+	// Wake first: a shorter timeout must not leave the display dark, and a
+	// disabled timeout must not leave it dark forever
+	dpms_set_blanked(dpms, false);
+}
+
+void
+dpms_set_blanked(struct cg_dpms *dpms, bool blanked)
+{
+	if (!dpms) {
+		return;
+	}
+
+	if (dpms->blanked != blanked) {
+		dpms->blanked = blanked;
+		set_outputs_enabled(dpms->server, !blanked);
+	}
+
+	if (blanked) {
+		if (dpms->timer) {
+			wl_event_source_timer_update(dpms->timer, 0);
+		}
+	} else {
+		schedule(dpms);
+	}
+}
+
+void
+dpms_notify_activity(struct cg_dpms *dpms)
+{
+	if (!dpms) {
+		return;
+	}
+
+	if (dpms->blanked) {
+		wlr_log(WLR_INFO, "DPMS: input activity, waking display");
+		dpms_set_blanked(dpms, false);
+		return;
+	}
+	schedule(dpms);
+}
+
+void
+dpms_destroy(struct cg_dpms *dpms)
+{
+	if (!dpms) {
+		return;
+	}
+
+	if (dpms->timer) {
+		wl_event_source_remove(dpms->timer);
+	}
+	free(dpms);
+}
@@ -0,0 +1,41 @@
+#ifndef CG_DPMS_H
+#define CG_DPMS_H
+
+#include <stdbool.h>
+#include <wayland-server-core.h>
+
+struct cg_server;
+
+/**
+ * Display power management for Strux.
+ * Blanks every output after a period without input and wakes them again on
+ * the next input event or an explicit WAKE command on the control socket.
+ */
+struct cg_dpms {
+	struct cg_server *server;
+	struct wl_event_source *timer;
+
+	int timeout_ms; // 0 disables blanking
+	bool blanked;
+};
+
+struct cg_dpms *dpms_create(struct cg_server *server, int timeout_seconds);
+
+/**
+ * Change the blanking timeout. 0 disables blanking and wakes the display.
+ */
+void dpms_set_timeout(struct cg_dpms *dpms, int timeout_seconds);
+
+/**
+ * Turn the outputs off or back on. Turning them on restarts the idle timer.
+ */
+void dpms_set_blanked(struct cg_dpms *dpms, bool blanked);
+
+/**
+ * Record user activity: wake the display and restart the idle timer.
+ */
+void dpms_notify_activity(struct cg_dpms *dpms);
+
+void dpms_destroy(struct cg_dpms *dpms);
+
+#endif
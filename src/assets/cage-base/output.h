@@ -18,6 +18,9 @@ struct cg_output {
 	struct wl_listener frame;
 
 	struct wl_list link; // cg_server::outputs
+
+	// Strux: powered down by DPMS blanking
+	bool dpms_off;
 };
 
 void handle_output_manager_apply(struct wl_listener *listener, void *data);
@@ -35,6 +35,7 @@
 #include <wlr/xwayland.h>
 #endif
 
+#include "dpms.h"
 #include "output.h"
 #include "seat.h"
 #include "server.h"
@@ -126,8 +127,8 @@ update_capabilities(struct cg_seat *seat)
 	}
 	wlr_seat_set_capabilities(seat->seat, caps);
 
-	/* Hide cursor if the seat doesn't have pointer capability. */
-	if ((caps & WL_SEAT_CAPABILITY_POINTER) == 0) {
+	/* Hide cursor if the seat doesn't have pointer capability or hiding was requested. */
+	if ((caps & WL_SEAT_CAPABILITY_POINTER) == 0 || seat->server->hide_cursor) {
 		wlr_cursor_unset_image(seat->cursor);
 	} else {
 		wlr_cursor_set_xcursor(seat->cursor, seat->xcursor_manager, DEFAULT_XCURSOR);
@@ -251,6 +252,7 @@ handle_modifier_event(struct wlr_keyboard *keyboard, struct cg_seat *seat)
 	wlr_seat_keyboard_notify_modifiers(seat->seat, &keyboard->modifiers);
 
 	wlr_idle_notifier_v1_notify_activity(seat->server->idle, seat->seat);
+	dpms_notify_activity(seat->server->dpms);
 }
 
 static bool
@@ -273,6 +275,7 @@ handle_keybinding(struct cg_server *server, xkb_keysym_t sym)
 		return false;
 	}
 	wlr_idle_notifier_v1_notify_activity(server->idle, server->seat->seat);
+	dpms_notify_activity(server->dpms);
 	return true;
 }
 
@@ -305,6 +308,7 @@ handle_key_event(struct wlr_keyboard *keyboard, struct cg_seat *seat, void *data
 	}
 
 	wlr_idle_notifier_v1_notify_activity(seat->server->idle, seat->seat);
+	dpms_notify_activity(seat->server->dpms);
 }
 
 static void
@@ -480,6 +484,11 @@ handle_request_set_cursor(struct wl_listener *listener, void *data)
 		focused_client = wl_resource_get_client(focused_surface->resource);
 	}
 
+	/* Clients may not bring back a hidden cursor. */
+	if (seat->server->hide_cursor) {
+		return;
+	}
+
 	/* This can be sent by any client, so we check to make sure
 	 * this one actually has pointer focus first. */
 	if (focused_client == event->seat_client->client) {
@@ -513,6 +522,7 @@ handle_touch_down(struct wl_listener *listener, void *data)
 	}
 
 	wlr_idle_notifier_v1_notify_activity(seat->server->idle, seat->seat);
+	dpms_notify_activity(seat->server->dpms);
 }
 
 static void
@@ -532,6 +542,7 @@ handle_touch_up(struct wl_listener *listener, void *data)
 
 	wlr_seat_touch_notify_up(seat->seat, event->time_msec, event->touch_id);
 	wlr_idle_notifier_v1_notify_activity(seat->server->idle, seat->seat);
+	dpms_notify_activity(seat->server->dpms);
 }
 
 static void
@@ -564,6 +575,7 @@ handle_touch_motion(struct wl_listener *listener, void *data)
 	}
 
 	wlr_idle_notifier_v1_notify_activity(seat->server->idle, seat->seat);
+	dpms_notify_activity(seat->server->dpms);
 }
 
 static void
@@ -573,6 +585,7 @@ handle_touch_frame(struct wl_listener *listener, void *data)
 
 	wlr_seat_touch_notify_frame(seat->seat);
 	wlr_idle_notifier_v1_notify_activity(seat->server->idle, seat->seat);
+	dpms_notify_activity(seat->server->dpms);
 }
 
 static void
@@ -582,6 +595,7 @@ handle_cursor_frame(struct wl_listener *listener, void *data)
 
 	wlr_seat_pointer_notify_frame(seat->seat);
 	wlr_idle_notifier_v1_notify_activity(seat->server->idle, seat->seat);
+	dpms_notify_activity(seat->server->dpms);
 }
 
 static void
@@ -593,6 +607,7 @@ handle_cursor_axis(struct wl_listener *listener, void *data)
 	wlr_seat_pointer_notify_axis(seat->seat, event->time_msec, event->orientation, event->delta,
 				     event->delta_discrete, event->source, event->relative_direction);
 	wlr_idle_notifier_v1_notify_activity(seat->server->idle, seat->seat);
+	dpms_notify_activity(seat->server->dpms);
 }
 
 static void
@@ -605,6 +620,7 @@ handle_cursor_button(struct wl_listener *listener, void *data)
 	press_cursor_button(seat, &event->pointer->base, event->time_msec, event->button, event->state, seat->cursor->x,
 			    seat->cursor->y);
 	wlr_idle_notifier_v1_notify_activity(seat->server->idle, seat->seat);
+	dpms_notify_activity(seat->server->dpms);
 }
 
 static void
@@ -635,6 +651,7 @@ process_cursor_motion(struct cg_seat *seat, uint32_t time_msec, double dx, doubl
 	}
 
 	wlr_idle_notifier_v1_notify_activity(seat->server->idle, seat->seat);
+	dpms_notify_activity(seat->server->dpms);
 }
 
 static void
@@ -652,6 +669,7 @@ handle_cursor_motion_absolute(struct wl_listener *listener, void *data)
 	wlr_cursor_warp_absolute(seat->cursor, &event->pointer->base, event->x, event->y);
 	process_cursor_motion(seat, event->time_msec, dx, dy, dx, dy);
 	wlr_idle_notifier_v1_notify_activity(seat->server->idle, seat->seat);
+	dpms_notify_activity(seat->server->dpms);
 }
 
 static void
@@ -664,6 +682,7 @@ handle_cursor_motion_relative(struct wl_listener *listener, void *data)
 	process_cursor_motion(seat, event->time_msec, event->delta_x, event->delta_y, event->unaccel_dx,
 			      event->unaccel_dy);
 	wlr_idle_notifier_v1_notify_activity(seat->server->idle, seat->seat);
+	dpms_notify_activity(seat->server->dpms);
 }
 
 static void
@@ -966,3 +985,10 @@ seat_center_cursor(struct cg_seat *seat)
 	wlr_output_layout_get_box(seat->server->output_layout, NULL, &layout_box);
 	wlr_cursor_warp(seat->cursor, NULL, layout_box.width / 2, layout_box.height / 2);
 }
+
+void
+seat_set_cursor_hidden(struct cg_seat *seat, bool hidden)
+{
+	seat->server->hide_cursor = hidden;
+	update_capabilities(seat);
+}
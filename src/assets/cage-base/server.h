@@ -6,6 +6,8 @@
 #include <wayland-server-core.h>
 
 struct cg_splash;
+struct cg_dpms;
+struct cg_control;
 #include <wlr/types/wlr_idle_inhibit_v1.h>
 #include <wlr/types/wlr_idle_notify_v1.h>
 #include <wlr/types/wlr_output_layout.h>
@@ -72,6 +74,14 @@ struct cg_server {
 	// Strux splash screen
 	struct cg_splash *splash;
 	char *splash_image_path;
+	bool hide_cursor;
+
+	// Strux display power management
+	struct cg_dpms *dpms;
+	int blank_timeout;
+
+	// Strux control socket (splash, cursor and blanking commands)
+	struct cg_control *control;
 };
 
 void server_terminate(struct cg_server *server);
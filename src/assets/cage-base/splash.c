@@ -4,7 +4,8 @@
  * Provides splash screen with:
  * - Framebuffer rendering during early boot
  * - Wayland scene rendering (black background + centered image)
- * - Control socket for strux.boot.HideSplash()
+ *
+ * The control socket that hides it lives in control.c.
  */
 
 #define _POSIX_C_SOURCE 200809L
@@ -17,16 +18,12 @@
 #include <wlr/types/wlr_cursor.h>
 #include <wlr/types/wlr_xcursor_manager.h>
 
-#include <errno.h>
 #include <fcntl.h>
 #include <png.h>
 #include <stdio.h>
 #include <stdlib.h>
 #include <string.h>
 #include <sys/mman.h>
-#include <sys/socket.h>
-#include <sys/stat.h>
-#include <sys/un.h>
 #include <unistd.h>
 
 #include <drm_fourcc.h>
@@ -35,7 +32,6 @@
 #include <wlr/types/wlr_scene.h>
 #include <wlr/util/log.h>
 
-#define STRUX_CONTROL_SOCKET "/tmp/strux-cage-control.sock"
 #define FB_DEVICE "/dev/fb0"
 #define FB_SYS_PATH "/sys/class/graphics/fb0/virtual_size"
 
@@ -308,106 +304,6 @@ static void clear_framebuffer(void)
 	close(fb_fd);
 }
 
-/* ===== Control Socket ===== */
-
-// Context for client connection event source
-struct client_context {
-	struct cg_splash *splash;
-	struct wl_event_source *source;
-	int fd;
-};
-
-static int handle_control_message(int fd, uint32_t mask, void *data)
-{
-	struct client_context *ctx = data;
-	char buffer[256];
-
-	ssize_t n = recv(fd, buffer, sizeof(buffer) - 1, 0);
-	if (n <= 0) {
-		// Connection closed or error - remove event source and cleanup
-		wl_event_source_remove(ctx->source);
-		close(fd);
-		free(ctx);
-		return 0;
-	}
-
-	buffer[n] = '\0';
-
-	if (strcmp(buffer, "HIDE_SPLASH") == 0) {
-		wlr_log(WLR_INFO, "Received HIDE_SPLASH command");
-		splash_hide(ctx->splash);
-	}
-
-	// Remove event source and cleanup after handling message
-	wl_event_source_remove(ctx->source);
-	close(fd);
-	free(ctx);
-	return 0;
-}
-
-static int handle_control_connection(int fd, uint32_t mask, void *data)
-{
-	struct cg_splash *splash = data;
-
-	int client_fd = accept(splash->control_fd, NULL, NULL);
-	if (client_fd < 0) {
-		wlr_log(WLR_ERROR, "Failed to accept control connection: %s", strerror(errno));
-		return 0;
-	}
-
-	// Create context for this client connection
-	struct client_context *ctx = calloc(1, sizeof(struct client_context));
-	if (!ctx) {
-		close(client_fd);
-		return 0;
-	}
-	ctx->splash = splash;
-	ctx->fd = client_fd;
-
-	struct wl_event_loop *loop = wl_display_get_event_loop(splash->server->wl_display);
-	ctx->source = wl_event_loop_add_fd(loop, client_fd, WL_EVENT_READABLE, handle_control_message, ctx);
-
-	return 0;
-}
-
-static bool setup_control_socket(struct cg_splash *splash)
-{
-	unlink(STRUX_CONTROL_SOCKET);
-
-	splash->control_fd = socket(AF_UNIX, SOCK_STREAM, 0);
-	if (splash->control_fd < 0) {
-		wlr_log(WLR_ERROR, "Failed to create control socket");
-		return false;
-	}
-
-	struct sockaddr_un addr = {0};
-	addr.sun_family = AF_UNIX;
-	strncpy(addr.sun_path, STRUX_CONTROL_SOCKET, sizeof(addr.sun_path) - 1);
-
-	if (bind(splash->control_fd, (struct sockaddr *)&addr, sizeof(addr)) < 0) {
-		wlr_log(WLR_ERROR, "Failed to bind control socket: %s", strerror(errno));
-		close(splash->control_fd);
-		splash->control_fd = -1;
-		return false;
-	}
-
-	if (listen(splash->control_fd, 5) < 0) {
-		wlr_log(WLR_ERROR, "Failed to listen on control socket");
-		close(splash->control_fd);
-		splash->control_fd = -1;
-		return false;
-	}
-
-	chmod(STRUX_CONTROL_SOCKET, 0666);
-
-	struct wl_event_loop *loop = wl_display_get_event_loop(splash->server->wl_display);
-	splash->control_source = wl_event_loop_add_fd(loop, splash->control_fd,
-						       WL_EVENT_READABLE, handle_control_connection, splash);
-
-	wlr_log(WLR_INFO, "Control socket listening: %s", STRUX_CONTROL_SOCKET);
-	return true;
-}
-
 /* ===== Wayland Scene Splash ===== */
 
 struct cg_splash *splash_create(struct cg_server *server, const char *image_path)
@@ -418,7 +314,6 @@ struct cg_splash *splash_create(struct cg_server *server, const char *image_path
 	}
 
 	splash->server = server;
-	splash->control_fd = -1;
 	splash->visible = false;
 
 	if (image_path) {
@@ -436,9 +331,6 @@ struct cg_splash *splash_create(struct cg_server *server, const char *image_path
 		}
 	}
 
-	// Setup control socket
-	setup_control_socket(splash);
-
 	wlr_log(WLR_INFO, "Splash system initialized");
 	return splash;
 }
@@ -570,8 +462,8 @@ void splash_hide(struct cg_splash *splash)
 		wlr_scene_node_set_enabled(&splash->tree->node, false);
 	}
 
-	// Restore cursor
-	if (splash->server->seat && splash->server->seat->cursor &&
+	// Restore cursor, unless it has been hidden for good
+	if (!splash->server->hide_cursor && splash->server->seat && splash->server->seat->cursor &&
 	    splash->server->seat->xcursor_manager) {
 		wlr_cursor_set_xcursor(splash->server->seat->cursor,
 				       splash->server->seat->xcursor_manager, "default");
@@ -589,14 +481,6 @@ void splash_destroy(struct cg_splash *splash)
 
 	splash_hide(splash);
 
-	if (splash->control_source) {
-		wl_event_source_remove(splash->control_source);
-	}
-	if (splash->control_fd >= 0) {
-		close(splash->control_fd);
-		unlink(STRUX_CONTROL_SOCKET);
-	}
-
 	if (splash->tree) {
 		wlr_scene_node_destroy(&splash->tree->node);
 	}
@@ -20,10 +20,6 @@ struct cg_splash {
 	// Image dimensions (loaded from PNG)
 	int image_width;
 	int image_height;
-
-	// Control socket for strux.boot.HideSplash()
-	int control_fd;
-	struct wl_event_source *control_source;
 };
 
 /**
@@ -39,7 +35,7 @@ struct cg_splash *splash_create(struct cg_server *server, const char *image_path
 void splash_show_wayland(struct cg_splash *splash);
 
 /**
- * Hide the splash screen (called via control socket).
+ * Hide the splash screen (called via control socket). Safe with NULL.
  */
 void splash_hide(struct cg_splash *splash);
 
@@ -0,0 +1,34 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// New projects get only the client files that artifacts.ts copies, so a
+// source file missing there builds here but not in a generated project
+func TestArtifactsCopiesEverySourceFile(t *testing.T) {
+	artifacts, err := os.ReadFile(filepath.Join("..", "..", "commands", "build", "artifacts.ts"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	script := string(artifacts)
+
+	sources, err := filepath.Glob("*.go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range sources {
+		if strings.HasSuffix(name, "_test.go") {
+			continue
+		}
+		if !strings.Contains(script, `"../../assets/client-base/`+name+`"`) {
+			t.Errorf("artifacts.ts does not import %s", name)
+		}
+		if !strings.Contains(script, `join(clientSrcPath, "`+name+`")`) {
+			t.Errorf("artifacts.ts does not write %s", name)
+		}
+	}
+}
@@ -0,0 +1,99 @@
+//
+// Strux Client - Exec Audit
+//
+// Records which shells were started on the device, for whom and how they
+// ended, without any of their output. The log is kept in memory and can also
+// be forwarded entry by entry with an audit callback.
+//
+
+package main
+
+import (
+	"sync"
+	"time"
+)
+
+// DefaultAuditLogSize is how many audit entries an ExecManager retains
+const DefaultAuditLogSize = 256
+
+// Audit events
+const (
+	AuditStart   = "start"
+	AuditRespawn = "respawn"
+	AuditExit    = "exit"
+)
+
+// AuditEntry records one event in the life of an exec session
+type AuditEntry struct {
+	Time      time.Time `json:"time"`
+	Event     string    `json:"event"` // AuditStart, AuditRespawn or AuditExit
+	SessionID string    `json:"sessionId"`
+	Client    string    `json:"client,omitempty"` // who asked for the session (see ExecOptions.Client)
+	Shell     string    `json:"shell"`
+	PID       int       `json:"pid"`
+
+	// InitCommand is the session's init command, on start entries
+	InitCommand string `json:"initCommand,omitempty"`
+
+	// ExitCode, Stopped and Duration are set on exit entries. Stopped means
+	// the session was ended with Stop rather than by its shell exiting.
+	ExitCode int           `json:"exitCode"`
+	Stopped  bool          `json:"stopped,omitempty"`
+	Duration time.Duration `json:"duration,omitempty"`
+}
+
+// auditLog is a bounded, in-memory record of audit entries
+type auditLog struct {
+	entries  []AuditEntry
+	next     int
+	full     bool
+	callback func(AuditEntry)
+	mu       sync.Mutex
+}
+
+func newAuditLog(size int) *auditLog {
+	return &auditLog{entries: make([]AuditEntry, size)}
+}
+
+// record stamps entry, stores it and passes it to the callback
+func (a *auditLog) record(entry AuditEntry) {
+	entry.Time = time.Now()
+
+	a.mu.Lock()
+	a.entries[a.next] = entry
+	a.next = (a.next + 1) % len(a.entries)
+	if a.next == 0 {
+		a.full = true
+	}
+	callback := a.callback
+	a.mu.Unlock()
+
+	if callback != nil {
+		callback(entry)
+	}
+}
+
+// list returns the retained entries, oldest first
+func (a *auditLog) list() []AuditEntry {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if !a.full {
+		return append([]AuditEntry(nil), a.entries[:a.next]...)
+	}
+	return append(append([]AuditEntry(nil), a.entries[a.next:]...), a.entries[:a.next]...)
+}
+
+// SetAuditCallback registers a function called with each audit entry as it
+// is recorded, e.g. to ship the trail off the device. It runs on the
+// session's goroutines, so it should not block.
+func (m *ExecManager) SetAuditCallback(callback func(AuditEntry)) {
+	m.audit.mu.Lock()
+	defer m.audit.mu.Unlock()
+	m.audit.callback = callback
+}
+
+// AuditLog returns the most recent DefaultAuditLogSize audit entries, oldest first
+func (m *ExecManager) AuditLog() []AuditEntry {
+	return m.audit.list()
+}
@@ -0,0 +1,60 @@
+//
+// Strux Client - Backoff
+//
+// Shared retry timing for loops that wait on something outside the client
+// (a log file appearing, the dev server coming back). Delays grow
+// exponentially up to a cap and are jittered so devices restarted together
+// don't retry in lockstep.
+//
+
+package main
+
+import (
+	"math"
+	"math/rand"
+	"time"
+)
+
+// maxBackoffDelay stops doubling before an uncapped delay overflows
+const maxBackoffDelay = time.Duration(math.MaxInt64 / 2)
+
+// Backoff describes exponentially growing retry delays
+type Backoff struct {
+	// Base is the delay before the first retry
+	Base time.Duration
+
+	// Max caps the delay; zero means uncapped
+	Max time.Duration
+
+	// Jitter randomly shortens each delay by up to this fraction (0 to 1).
+	// Zero gives exact, deterministic delays.
+	Jitter float64
+
+	// MaxAttempts bounds the number of retries; zero means unlimited
+	MaxAttempts int
+}
+
+// Delay returns how long to wait before retry number attempt (counting from 0)
+func (b Backoff) Delay(attempt int) time.Duration {
+	delay := b.Base
+	for i := 0; i < attempt && delay < maxBackoffDelay && (b.Max == 0 || delay < b.Max); i++ {
+		delay *= 2
+	}
+	if b.Max > 0 && delay > b.Max {
+		delay = b.Max
+	}
+
+	if b.Jitter > 0 {
+		jitter := b.Jitter
+		if jitter > 1 {
+			jitter = 1
+		}
+		delay -= time.Duration(rand.Float64() * jitter * float64(delay))
+	}
+	return delay
+}
+
+// Exhausted reports whether attempt is past MaxAttempts
+func (b Backoff) Exhausted(attempt int) bool {
+	return b.MaxAttempts > 0 && attempt >= b.MaxAttempts
+}
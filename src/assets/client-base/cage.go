@@ -28,10 +28,62 @@ type LaunchOptions struct {
 	CogURL string
 	// Resolution is the display resolution (e.g., "1920x1080")
 	Resolution string
+	// HideCursor starts Cage with --hide-cursor so no cursor is drawn even with a
+	// pointer attached. It is independent of Resolution: the cursor stays hidden
+	// across mode changes, and strux.display.SetCursorVisible can toggle it at runtime.
+	HideCursor bool
+	// BlankTimeout turns the display off after this many seconds without
+	// input (0 never blanks). strux.display.SetBlankTimeout changes it at runtime.
+	BlankTimeout int
 	// SplashImage is the path to the splash image (optional)
 	SplashImage string
 	// Inspector holds the WebKit Inspector configuration (optional, for dev mode)
 	Inspector *InspectorConfig
+	// EnvAllowlist names compositor/browser tuning variables (e.g.
+	// WEBKIT_DISABLE_COMPOSITING_MODE) forwarded from the parent environment
+	EnvAllowlist []string
+	// LogPath is where Cage/Cog output is written (default DefaultCageLogPath)
+	LogPath string
+}
+
+// compositorEnvPrefixes are the variable families that tune Cage, wlroots,
+// WPE and Cog. Inherited variables with these prefixes are dropped unless
+// allowlisted, so the compositor environment stays reproducible.
+var compositorEnvPrefixes = []string{"WEBKIT_", "WPE_", "WLR_", "COG_"}
+
+// compositorEnv returns the parent environment with unlisted tuning variables removed,
+// followed by the fixed Strux settings and finally the allowlisted variables,
+// so an allowlisted variable can override a fixed setting
+func compositorEnv(fixed []string, allowlist []string) []string {
+	allowed := make(map[string]bool, len(allowlist))
+	for _, name := range allowlist {
+		allowed[name] = true
+	}
+
+	env := []string{}
+	passthrough := []string{}
+	for _, kv := range os.Environ() {
+		name, _, _ := strings.Cut(kv, "=")
+		switch {
+		case allowed[name]:
+			passthrough = append(passthrough, kv)
+		case !hasAnyPrefix(name, compositorEnvPrefixes):
+			env = append(env, kv)
+		}
+	}
+
+	env = append(env, fixed...)
+	return append(env, passthrough...)
+}
+
+// hasAnyPrefix reports whether s starts with any of the prefixes
+func hasAnyPrefix(s string, prefixes []string) bool {
+	for _, prefix := range prefixes {
+		if strings.HasPrefix(s, prefix) {
+			return true
+		}
+	}
+	return false
 }
 
 // CageLauncher manages the Cage compositor process
@@ -210,6 +262,14 @@ func (c *CageLauncher) Launch(opts LaunchOptions) error {
 		args = append(args, fmt.Sprintf("--splash-image=%s", opts.SplashImage))
 	}
 
+	if opts.HideCursor {
+		args = append(args, "--hide-cursor")
+	}
+
+	if opts.BlankTimeout > 0 {
+		args = append(args, fmt.Sprintf("--blank-timeout=%d", opts.BlankTimeout))
+	}
+
 	// Build the shell command to run inside Cage
 	// 1. Set display resolution using wlr-randr
 	// 2. Launch Cog browser with the specified URL
@@ -231,7 +291,7 @@ func (c *CageLauncher) Launch(opts LaunchOptions) error {
 	c.process = exec.Command("cage", args...)
 
 	// Set environment variables required for Cage and WebKit
-	c.process.Env = append(os.Environ(),
+	c.process.Env = compositorEnv([]string{
 		"WPE_WEB_EXTENSION_PATH=/usr/lib/wpe-web-extensions",
 		"SEATD_SOCK=/run/seatd.sock",
 		"WEBKIT_DISABLE_SANDBOX_THIS_IS_DANGEROUS=1",
@@ -243,7 +303,7 @@ func (c *CageLauncher) Launch(opts LaunchOptions) error {
 		"LIBPROXY_IGNORE_SETTINGS=1",
 		"GIO_USE_PROXY_RESOLVER=direct",
 		"GSETTINGS_BACKEND=memory",
-	)
+	}, opts.EnvAllowlist)
 
 	// Add WebKit Inspector HTTP server if enabled (dev mode)
 	// Must bind to 0.0.0.0 so it's accessible via QEMU port forwarding
@@ -257,8 +317,12 @@ func (c *CageLauncher) Launch(opts LaunchOptions) error {
 	}
 
 	// Open log file
+	logPath := opts.LogPath
+	if logPath == "" {
+		logPath = DefaultCageLogPath
+	}
 	var err error
-	c.logFile, err = os.Create("/tmp/strux-cage.log")
+	c.logFile, err = os.Create(logPath)
 	if err != nil {
 		c.logger.Warn("Could not create log file: %v", err)
 	}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCoalescerJoinsContinuations(t *testing.T) {
+	var got lineLog
+	c := NewLogCoalescer(got.add, nil, time.Hour)
+	add := c.Callback()
+
+	add("panic: boom")
+	add("")
+	add("goroutine 1 [running]:")
+	add("\tmain.main()")
+	add("Exception in thread \"main\" java.lang.IllegalStateException")
+	add("\tat Main.run(Main.java:3)")
+	add("Caused by: java.io.IOException")
+	c.Flush()
+
+	want := []string{
+		"panic: boom",
+		"",
+		"goroutine 1 [running]:\n\tmain.main()",
+		"Exception in thread \"main\" java.lang.IllegalStateException\n\tat Main.run(Main.java:3)\nCaused by: java.io.IOException",
+	}
+	if lines := got.get(); !equalLines(lines, want) {
+		t.Errorf("got %q, want %q", lines, want)
+	}
+}
+
+func TestCoalescerFlushesWhenIdle(t *testing.T) {
+	var got lineLog
+	c := NewLogCoalescer(got.add, nil, 10*time.Millisecond)
+	c.Callback()("last entry")
+	c.Callback()("  and its continuation")
+
+	waitFor(t, "the idle flush", func() bool { return len(got.get()) == 1 })
+	if lines := got.get(); lines[0] != "last entry\n  and its continuation" {
+		t.Errorf("got %q", lines)
+	}
+}
+
+func TestCoalescerDeliversNothingAfterStop(t *testing.T) {
+	var got lineLog
+	c := NewLogCoalescer(got.add, nil, 5*time.Millisecond)
+	add := c.Callback()
+
+	add("pending")
+	c.Stop()
+	if lines := got.get(); !equalLines(lines, []string{"pending"}) {
+		t.Fatalf("Stop delivered %q, want the pending entry", lines)
+	}
+
+	add("after stop")
+	time.Sleep(50 * time.Millisecond) // well past the idle flush
+	c.Flush()
+	if lines := got.get(); len(lines) != 1 {
+		t.Errorf("delivered %q after Stop", lines[1:])
+	}
+}
+
+func TestStoppedStreamFlushesCoalescerOnce(t *testing.T) {
+	var got lineLog
+	c := NewLogCoalescer(got.add, nil, time.Hour)
+	l, release := startGated(t, "error: failed\n\tat step one\n\tat step two\n", c.Callback())
+	if err := l.SetFlush("journal", c.Stop); err != nil {
+		t.Fatal(err)
+	}
+	release()
+	waitFor(t, "three lines", func() bool {
+		stats, err := l.Stats("journal")
+		return err == nil && stats.Lines == 3
+	})
+
+	l.StopAndWait("journal")
+	want := []string{"error: failed\n\tat step one\n\tat step two"}
+	if lines := got.get(); !equalLines(lines, want) {
+		t.Errorf("got %q, want %q", lines, want)
+	}
+}
+
+func TestExitedStreamStopsCoalescer(t *testing.T) {
+	// Wired the way the socket client does it
+	source := &fakeSource{script: func(name string, args []string) fakeRun {
+		return fakeRun{stdout: "error: failed\n\tat step one\n"}
+	}}
+	l := newTestStreamer(source)
+	l.SetAllowedCommands([]string{"app-status"})
+	t.Cleanup(l.StopAll)
+
+	var got lineLog
+	c := NewLogCoalescer(got.add, nil, 5*time.Millisecond)
+	if err := l.StartCommandStream("cmd", "app-status", nil, c.Callback(), StreamSetup{Flush: c.Stop}); err != nil {
+		t.Fatal(err)
+	}
+	waitFor(t, "the stream to end", func() bool { return len(l.GetActiveStreams()) == 0 })
+
+	want := []string{"error: failed\n\tat step one"}
+	if lines := got.get(); !equalLines(lines, want) {
+		t.Fatalf("got %q, want %q", lines, want)
+	}
+	c.Callback()("late line")
+	time.Sleep(50 * time.Millisecond) // well past the idle flush
+	if lines := got.get(); len(lines) != 1 {
+		t.Errorf("delivered %q after the stream ended", lines[1:])
+	}
+}
@@ -27,6 +27,28 @@ type InspectorConfig struct {
 	Port int `json:"port"`
 }
 
+// KeepaliveConfig holds the dev transport ping/pong settings
+type KeepaliveConfig struct {
+	// IntervalSeconds is how often to ping the dev server (0 disables keepalive)
+	IntervalSeconds int `json:"intervalSeconds"`
+	// TimeoutSeconds is how long to wait for a pong beyond the interval
+	TimeoutSeconds int `json:"timeoutSeconds"`
+}
+
+// HeartbeatConfig holds the dev transport heartbeat settings
+type HeartbeatConfig struct {
+	// IntervalSeconds is how often to send vitals to the dev server (0 disables heartbeats)
+	IntervalSeconds int `json:"intervalSeconds"`
+}
+
+// LogPathsConfig overrides where the app and Cage log files are read from
+type LogPathsConfig struct {
+	// AppLog is the file the user's app output is written to
+	AppLog string `json:"appLog,omitempty"`
+	// CageLog is the file Cage/Cog output is written to
+	CageLog string `json:"cageLog,omitempty"`
+}
+
 // Config holds the dev client configuration
 type Config struct {
 	// ClientKey is the authentication key for the dev server
@@ -40,6 +62,19 @@ type Config struct {
 
 	// Inspector holds the WebKit Inspector configuration
 	Inspector InspectorConfig `json:"inspector"`
+
+	// Keepalive overrides the WebSocket keepalive defaults when set
+	Keepalive *KeepaliveConfig `json:"keepalive,omitempty"`
+
+	// Heartbeat overrides the heartbeat interval when set
+	Heartbeat *HeartbeatConfig `json:"heartbeat,omitempty"`
+
+	// LogPaths overrides the default app and Cage log file paths when set
+	LogPaths *LogPathsConfig `json:"logPaths,omitempty"`
+
+	// AllowedCommands lists the commands the dev server may follow with
+	// "command" log streams; none are allowed when empty
+	AllowedCommands []string `json:"allowedCommands,omitempty"`
 }
 
 // LoadConfig loads the configuration from the specified path
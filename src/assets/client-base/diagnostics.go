@@ -0,0 +1,255 @@
+//
+// Strux Client - Diagnostics
+//
+// Collects recent journal output and the app and Cage log files into a
+// tar.gz for bug reports. Every source is capped so the archive stays small
+// however noisy the device has been; sources that can't be read are listed
+// in the archive instead of failing it.
+//
+
+package main
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Defaults for DiagnosticsOptions. With three sources capped at
+// DefaultDiagnosticsMaxBytes, even incompressible logs keep the base64
+// archive well under the dev server's 16 MB WebSocket message limit.
+const (
+	DefaultDiagnosticsJournalLines = 5000
+	DefaultDiagnosticsMaxBytes     = 2 << 20
+	DefaultDiagnosticsTimeout      = 10 * time.Second
+)
+
+// DiagnosticsOptions bounds what CollectDiagnostics gathers
+type DiagnosticsOptions struct {
+	// JournalLines is how many of the most recent journal lines to include
+	// (default DefaultDiagnosticsJournalLines)
+	JournalLines int
+
+	// MaxBytes caps each archive entry (default DefaultDiagnosticsMaxBytes).
+	// Every source keeps its last MaxBytes, where the newest lines are.
+	MaxBytes int64
+
+	// Timeout bounds the journalctl dump (default DefaultDiagnosticsTimeout)
+	Timeout time.Duration
+}
+
+func (o DiagnosticsOptions) withDefaults() DiagnosticsOptions {
+	if o.JournalLines <= 0 {
+		o.JournalLines = DefaultDiagnosticsJournalLines
+	}
+	if o.MaxBytes <= 0 {
+		o.MaxBytes = DefaultDiagnosticsMaxBytes
+	}
+	if o.Timeout <= 0 {
+		o.Timeout = DefaultDiagnosticsTimeout
+	}
+	return o
+}
+
+// CollectDiagnostics writes a tar.gz to w holding journal.log (recent
+// journalctl output), app.log and cage.log. Sources that are missing or
+// fail are described in errors.txt; the returned error is only for a
+// failure to write the archive itself.
+func (l *LogStreamer) CollectDiagnostics(w io.Writer, opts DiagnosticsOptions) error {
+	opts = opts.withDefaults()
+
+	l.mu.Lock()
+	source := l.source
+	appLog := l.appLogPath
+	cageLog := l.cageLogPath
+	l.mu.Unlock()
+
+	gz := gzip.NewWriter(w)
+	archive := tar.NewWriter(gz)
+	now := time.Now()
+
+	var problems []string
+	add := func(name string, data []byte, err error) error {
+		if err != nil {
+			problems = append(problems, fmt.Sprintf("%s: %v", name, err))
+			if data == nil {
+				return nil
+			}
+		}
+		return writeTarFile(archive, name, data, now)
+	}
+
+	data, err := dumpJournal(source, opts)
+	if err := add("journal.log", data, err); err != nil {
+		return err
+	}
+	data, err = readFileTail(appLog, opts.MaxBytes)
+	if err := add("app.log", data, err); err != nil {
+		return err
+	}
+	data, err = readFileTail(cageLog, opts.MaxBytes)
+	if err := add("cage.log", data, err); err != nil {
+		return err
+	}
+	if len(problems) > 0 {
+		report := []byte(strings.Join(problems, "\n") + "\n")
+		if err := writeTarFile(archive, "errors.txt", report, now); err != nil {
+			return err
+		}
+	}
+
+	if err := archive.Close(); err != nil {
+		return fmt.Errorf("failed to finish diagnostics archive: %w", err)
+	}
+	if err := gz.Close(); err != nil {
+		return fmt.Errorf("failed to finish diagnostics archive: %w", err)
+	}
+	return nil
+}
+
+// writeTarFile adds data to archive as a regular file called name
+func writeTarFile(archive *tar.Writer, name string, data []byte, modTime time.Time) error {
+	header := &tar.Header{
+		Name:    name,
+		Mode:    0644,
+		Size:    int64(len(data)),
+		ModTime: modTime,
+	}
+	if err := archive.WriteHeader(header); err != nil {
+		return fmt.Errorf("failed to write %s to diagnostics archive: %w", name, err)
+	}
+	if _, err := archive.Write(data); err != nil {
+		return fmt.Errorf("failed to write %s to diagnostics archive: %w", name, err)
+	}
+	return nil
+}
+
+// dumpJournal returns the last opts.JournalLines journal lines, cut to their
+// last opts.MaxBytes since journalctl prints the newest lines last. It stops
+// journalctl once opts.Timeout has passed. Output read before a failure is
+// returned along with the error.
+func dumpJournal(source LogSource, opts DiagnosticsOptions) ([]byte, error) {
+	if !source.Available("journalctl") {
+		return nil, ErrNoLogBackend
+	}
+	args, err := JournalOptions{}.args()
+	if err != nil {
+		return nil, err
+	}
+	args = append(args, "-n", strconv.Itoa(opts.JournalLines))
+
+	proc, err := source.Start("journalctl", args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to start journalctl: %w", err)
+	}
+	defer proc.Close()
+
+	// Closing the streams after Kill unblocks the reads below even if a
+	// child of journalctl (such as its pager) still holds them open
+	stop := func() {
+		proc.Kill()
+		proc.Close()
+	}
+	var timedOut atomic.Bool
+	timer := time.AfterFunc(opts.Timeout, func() {
+		timedOut.Store(true)
+		stop()
+	})
+	defer timer.Stop()
+
+	var errOut bytes.Buffer
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		io.Copy(&errOut, io.LimitReader(proc.Stderr(), 4096))
+		io.Copy(io.Discard, proc.Stderr())
+	}()
+
+	out := &tailBuffer{max: opts.MaxBytes}
+	io.Copy(out, proc.Stdout())
+	truncated := out.written > opts.MaxBytes
+	wg.Wait()
+	waitErr := proc.Wait()
+
+	// Checked as in checkJournalAccess: without access journalctl may still
+	// succeed, showing only the caller's own entries
+	message := strings.TrimSpace(errOut.String())
+	if strings.Contains(message, "insufficient permissions") || strings.Contains(strings.ToLower(message), "permission denied") {
+		return out.Bytes(), fmt.Errorf("%w: %s", ErrJournalPermission, firstLine(message))
+	}
+	if truncated {
+		return out.Bytes(), fmt.Errorf("truncated to its last %d bytes", opts.MaxBytes)
+	}
+	if timedOut.Load() {
+		return out.Bytes(), fmt.Errorf("journalctl stopped after %s", opts.Timeout)
+	}
+	if waitErr != nil {
+		if message == "" {
+			return out.Bytes(), fmt.Errorf("journalctl failed: %w", waitErr)
+		}
+		return out.Bytes(), fmt.Errorf("journalctl failed: %s", firstLine(message))
+	}
+	return out.Bytes(), nil
+}
+
+// tailBuffer is an io.Writer that keeps the last max bytes written to it
+type tailBuffer struct {
+	max     int64
+	data    []byte
+	written int64
+}
+
+func (b *tailBuffer) Write(p []byte) (int, error) {
+	b.written += int64(len(p))
+	b.data = append(b.data, p...)
+	// Trim in batches so a write doesn't copy the whole tail every time
+	if int64(len(b.data)) > 2*b.max {
+		b.data = append([]byte(nil), b.data[int64(len(b.data))-b.max:]...)
+	}
+	return len(p), nil
+}
+
+// Bytes returns the last max bytes written
+func (b *tailBuffer) Bytes() []byte {
+	if int64(len(b.data)) > b.max {
+		return b.data[int64(len(b.data))-b.max:]
+	}
+	return b.data
+}
+
+// readFileTail returns the last max bytes of the file at path. A file that
+// was cut is returned along with an error saying so.
+func readFileTail(path string, max int64) ([]byte, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return nil, err
+	}
+	if info.Size() > max {
+		if _, err := file.Seek(-max, io.SeekEnd); err != nil {
+			return nil, err
+		}
+	}
+	data, err := io.ReadAll(io.LimitReader(file, max))
+	if err != nil {
+		return nil, err
+	}
+	if info.Size() > max {
+		return data, fmt.Errorf("%s truncated to its last %d bytes", path, max)
+	}
+	return data, nil
+}
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDumpJournalKeepsNewestLines(t *testing.T) {
+	source := &fakeSource{script: func(name string, args []string) fakeRun {
+		return fakeRun{stdout: "oldest\nolder\nnewer\nnewest\n"}
+	}}
+
+	data, err := dumpJournal(source, DiagnosticsOptions{MaxBytes: 13}.withDefaults())
+	if got, want := string(data), "newer\nnewest\n"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+	if err == nil || !strings.Contains(err.Error(), "truncated") {
+		t.Errorf("got error %v, want one saying the output was truncated", err)
+	}
+
+	data, err = dumpJournal(source, DiagnosticsOptions{MaxBytes: 100}.withDefaults())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := string(data), "oldest\nolder\nnewer\nnewest\n"; got != want {
+		t.Errorf("under the limit: got %q, want %q", got, want)
+	}
+}
+
+func TestTailBuffer(t *testing.T) {
+	b := &tailBuffer{max: 4}
+	for _, chunk := range []string{"ab", "cdefghij", "k", "lm"} {
+		b.Write([]byte(chunk))
+	}
+	if got := string(b.Bytes()); got != "jklm" {
+		t.Errorf("got %q, want %q", got, "jklm")
+	}
+	if b.written != 13 {
+		t.Errorf("written = %d, want 13", b.written)
+	}
+}
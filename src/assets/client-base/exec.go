@@ -7,94 +7,388 @@
 package main
 
 import (
+	"bytes"
+	"errors"
 	"fmt"
+	"io"
 	"os"
-	"os/exec"
+	"strings"
 	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	// DefaultExecReadBufferSize is the PTY read size, and the most output held before a flush
+	DefaultExecReadBufferSize = 32 * 1024
+	// DefaultExecCoalesceDelay is how long output is held to batch bursts into fewer messages
+	DefaultExecCoalesceDelay = 5 * time.Millisecond
+)
 
-	"github.com/creack/pty"
+// Respawning sessions relaunch their shell at most maxRespawns times within
+// respawnWindow; a shell that keeps exiting faster than that ends the session
+const (
+	maxRespawns   = 5
+	respawnWindow = time.Minute
 )
 
+// outputDrainTimeout bounds how long waitLoop waits for the last output of an
+// exited shell; a background job still holding the terminal keeps it open
+const outputDrainTimeout = 100 * time.Millisecond
+
+// RespawnMarker is sent as output when a respawning session relaunches its shell
+const RespawnMarker = "\r\n--- shell restarted ---\r\n"
+
+// Errors returned by ExecManager, wrapped with the session ID; compare with errors.Is
+var (
+	// ErrSessionExists is returned when starting a session whose ID is in use
+	ErrSessionExists = errors.New("session already exists")
+
+	// ErrSessionNotFound is returned for an ID with no running session
+	ErrSessionNotFound = errors.New("session not found")
+
+	// ErrNoShell is returned by Probe when none of DefaultShells exists
+	ErrNoShell = errors.New("no shell available")
+)
+
+// DefaultShells are the shells a session falls back to, in order of
+// preference, when ExecOptions.Shell is unset or missing
+var DefaultShells = []string{"/bin/bash", "/bin/sh"}
+
 type ExecSession struct {
 	id   string
-	cmd  *exec.Cmd
-	pty  *os.File
 	done chan struct{}
+
+	// pty is replaced when the shell respawns, so it is guarded by ptyMu,
+	// as is size, the last size set so a respawned shell starts at it
+	pty   ptyProcess
+	size  ptySize
+	ptyMu sync.Mutex
+
+	// How to relaunch the shell, and recent relaunch times, for Respawn
+	shellPath string
+	args      []string
+	env       []string
+	respawn   bool
+	respawns  []time.Time
+
+	// For audit entries
+	client    string
+	startedAt time.Time
+
+	// inputMu serializes writes to the PTY (see writeInput)
+	inputMu sync.Mutex
+
+	// Output coalescing state
+	pending    []byte
+	flushTimer *time.Timer
+	outMu      sync.Mutex
+
+	// Flow control (see Pause); resumed is closed by Resume. Guarded by outMu.
+	paused  bool
+	resumed chan struct{}
 }
 
 type ExecManager struct {
-	sessions map[string]*ExecSession
-	mu       sync.Mutex
-	logger   *Logger
-	onOutput func(sessionID, stream, data string)
-	onExit   func(sessionID string, code int)
-	onError  func(sessionID string, err error)
+	sessions       map[string]*ExecSession
+	mu             sync.Mutex
+	logger         *Logger
+	onStart        func(sessionID string, pid int)
+	onOutput       func(sessionID, stream, data string)
+	onExit         func(sessionID string, code int)
+	onError        func(sessionID string, err error)
+	ptys           ptyFactory
+	readBufferSize int
+	coalesceDelay  time.Duration
+	audit          *auditLog
 }
 
-func NewExecManager(onOutput func(string, string, string), onExit func(string, int), onError func(string, error)) *ExecManager {
+func NewExecManager(onStart func(string, int), onOutput func(string, string, string), onExit func(string, int), onError func(string, error)) *ExecManager {
 	return &ExecManager{
-		sessions: make(map[string]*ExecSession),
-		logger:   NewLogger("ExecManager"),
-		onOutput: onOutput,
-		onExit:   onExit,
-		onError:  onError,
+		sessions:       make(map[string]*ExecSession),
+		logger:         NewLogger("ExecManager"),
+		onStart:        onStart,
+		onOutput:       onOutput,
+		onExit:         onExit,
+		onError:        onError,
+		ptys:           creackPTYFactory{},
+		readBufferSize: DefaultExecReadBufferSize,
+		coalesceDelay:  DefaultExecCoalesceDelay,
+		audit:          newAuditLog(DefaultAuditLogSize),
+	}
+}
+
+// SetReadBuffer configures the PTY read size and the output coalescing delay
+// for sessions started afterwards. A zero delay disables coalescing.
+func (m *ExecManager) SetReadBuffer(size int, coalesceDelay time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if size > 0 {
+		m.readBufferSize = size
+	}
+	if coalesceDelay >= 0 {
+		m.coalesceDelay = coalesceDelay
+	}
+}
+
+// ExecOptions configures a session started with StartWithOptions
+type ExecOptions struct {
+	// Shell is the shell to run (default /bin/bash, falling back to /bin/sh)
+	Shell string
+
+	// InitCommand runs before the interactive shell takes over, e.g.
+	// "cd /app && set -a && . ./.env && set +a". It is passed to the shell with -c
+	// followed by "exec <shell>" instead of being typed into the PTY, so nothing is
+	// echoed. Because the interactive shell replaces the one that ran InitCommand,
+	// only the working directory and exported variables carry over; use set -a
+	// (or export) when sourcing env files. The shell starts even if InitCommand fails.
+	InitCommand string
+
+	// Respawn relaunches the shell in the same session when it exits cleanly
+	// (e.g. the user typed exit), after sending RespawnMarker. InitCommand runs
+	// again. A shell that exits more than maxRespawns times within
+	// respawnWindow, or with a non-zero code, ends the session as usual.
+	Respawn bool
+
+	// Lang and LCAll set LANG and LC_ALL for the shell. Unset, the client's
+	// own values are inherited, and LANG falls back to defaultLang if the
+	// client has none, so UTF-8 output is not mangled.
+	Lang  string
+	LCAll string
+
+	// TrueColor sets COLORTERM=truecolor for tools that check it before
+	// using 24-bit color
+	TrueColor bool
+
+	// InitialCols and InitialRows size the terminal before the shell starts,
+	// so full-screen programs draw their first frame correctly. If only one
+	// is set the other takes its default (DefaultPTYCols or DefaultPTYRows);
+	// with neither set the PTY keeps the kernel default.
+	InitialCols uint16
+	InitialRows uint16
+
+	// Client identifies who asked for the session in audit entries
+	Client string
+}
+
+// Terminal size used for an unset dimension of ExecOptions
+const (
+	DefaultPTYCols = 80
+	DefaultPTYRows = 24
+)
+
+// initialSize returns the PTY size opts asks for
+func (opts ExecOptions) initialSize() ptySize {
+	if opts.InitialCols == 0 && opts.InitialRows == 0 {
+		return ptySize{}
+	}
+	size := ptySize{Rows: opts.InitialRows, Cols: opts.InitialCols}
+	if size.Rows == 0 {
+		size.Rows = DefaultPTYRows
+	}
+	if size.Cols == 0 {
+		size.Cols = DefaultPTYCols
+	}
+	return size
+}
+
+// terminalEnv returns base with the terminal and locale variables for a
+// session configured by opts
+func terminalEnv(base []string, opts ExecOptions) []string {
+	env := setEnv(append([]string(nil), base...), "TERM", "xterm-256color")
+
+	if opts.Lang != "" {
+		env = setEnv(env, "LANG", opts.Lang)
+	} else if !hasEnv(env, "LANG") {
+		env = setEnv(env, "LANG", defaultLang)
+	}
+	if opts.LCAll != "" {
+		env = setEnv(env, "LC_ALL", opts.LCAll)
+	}
+	if opts.TrueColor {
+		env = setEnv(env, "COLORTERM", "truecolor")
+	}
+	return env
+}
+
+// setEnv sets key to value in env, replacing any existing entry
+func setEnv(env []string, key, value string) []string {
+	prefix := key + "="
+	for i, entry := range env {
+		if strings.HasPrefix(entry, prefix) {
+			env[i] = prefix + value
+			return env
+		}
 	}
+	return append(env, prefix+value)
 }
 
+// hasEnv reports whether env sets key to a non-empty value
+func hasEnv(env []string, key string) bool {
+	prefix := key + "="
+	for _, entry := range env {
+		if strings.HasPrefix(entry, prefix) && len(entry) > len(prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// defaultLang is the LANG given to shells when neither the session nor the
+// client environment sets one
+const defaultLang = "C.UTF-8"
+
 func (m *ExecManager) Start(sessionID string, shell string) error {
+	return m.StartWithOptions(sessionID, ExecOptions{Shell: shell})
+}
+
+// StartWithOptions starts an interactive shell session configured by opts
+func (m *ExecManager) StartWithOptions(sessionID string, opts ExecOptions) error {
+	if err := validateID("session", sessionID); err != nil {
+		return err
+	}
+
 	m.mu.Lock()
 	if _, exists := m.sessions[sessionID]; exists {
 		m.mu.Unlock()
-		return fmt.Errorf("session already exists: %s", sessionID)
+		return fmt.Errorf("%w: %s", ErrSessionExists, sessionID)
 	}
 	m.mu.Unlock()
 
-	shellPath := shell
+	shellPath := opts.Shell
 	if shellPath == "" || !fileExists(shellPath) {
-		if fileExists("/bin/bash") {
-			shellPath = "/bin/bash"
-		} else {
-			shellPath = "/bin/sh"
+		// With none installed, the last fallback's start error says why
+		shellPath = DefaultShells[len(DefaultShells)-1]
+		if shells, err := m.Probe(); err == nil {
+			shellPath = shells[0]
 		}
 	}
 
-	cmd := exec.Command(shellPath)
-	cmd.Env = append(os.Environ(), "TERM=xterm-256color")
+	var args []string
+	if opts.InitCommand != "" {
+		args = []string{"-c", opts.InitCommand + "\nexec " + shellQuote(shellPath)}
+	}
+	env := terminalEnv(os.Environ(), opts)
 
-	ptmx, err := pty.Start(cmd)
+	size := opts.initialSize()
+	proc, err := m.ptys.Start(shellPath, args, env, size)
 	if err != nil {
 		return fmt.Errorf("failed to start pty: %w", err)
 	}
 
 	session := &ExecSession{
-		id:   sessionID,
-		cmd:  cmd,
-		pty:  ptmx,
-		done: make(chan struct{}),
+		id:        sessionID,
+		pty:       proc,
+		size:      size,
+		done:      make(chan struct{}),
+		shellPath: shellPath,
+		args:      args,
+		env:       env,
+		respawn:   opts.Respawn,
+		client:    opts.Client,
+		startedAt: time.Now(),
 	}
 
 	m.mu.Lock()
 	m.sessions[sessionID] = session
 	m.mu.Unlock()
 
-	go m.readLoop(session)
-	go m.waitLoop(session)
+	m.audit.record(AuditEntry{
+		Event:       AuditStart,
+		SessionID:   sessionID,
+		Client:      opts.Client,
+		Shell:       shellPath,
+		PID:         proc.Pid(),
+		InitCommand: opts.InitCommand,
+	})
+
+	// Announce the session before reading so no output can arrive ahead of it
+	if m.onStart != nil {
+		m.onStart(sessionID, proc.Pid())
+	}
+
+	m.watch(session, proc)
 
 	m.logger.Info("Started exec session: %s", sessionID)
 	return nil
 }
 
+// Probe reports which of DefaultShells exist, in order of preference, without
+// starting anything, so a frontend can disable its terminal when the device
+// has no shell. It returns ErrNoShell when none does.
+func (m *ExecManager) Probe() ([]string, error) {
+	var shells []string
+	for _, shell := range DefaultShells {
+		if fileExists(shell) {
+			shells = append(shells, shell)
+		}
+	}
+	if len(shells) == 0 {
+		return nil, ErrNoShell
+	}
+	return shells, nil
+}
+
+// process returns the session's current PTY process
+func (s *ExecSession) process() ptyProcess {
+	s.ptyMu.Lock()
+	defer s.ptyMu.Unlock()
+	return s.pty
+}
+
+// shellQuote quotes s as a single POSIX shell word
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// SendInput writes text input to a session's PTY
 func (m *ExecManager) SendInput(sessionID string, data string) error {
+	return m.SendInputBytes(sessionID, []byte(data))
+}
+
+// SendInputBytes writes raw input, such as control bytes or a binary paste,
+// to a session's PTY unchanged
+func (m *ExecManager) SendInputBytes(sessionID string, data []byte) error {
 	m.mu.Lock()
 	session, exists := m.sessions[sessionID]
 	m.mu.Unlock()
 
 	if !exists {
-		return fmt.Errorf("session not found: %s", sessionID)
+		return fmt.Errorf("%w: %s", ErrSessionNotFound, sessionID)
 	}
 
-	_, err := session.pty.Write([]byte(data))
-	return err
+	return session.writeInput(data)
+}
+
+// writeInput writes all of data to the session's PTY, looping over short
+// writes. Writers are serialized so concurrent inputs don't interleave.
+// Stopping the session mid-write ends it with ErrSessionNotFound.
+func (s *ExecSession) writeInput(data []byte) error {
+	s.inputMu.Lock()
+	defer s.inputMu.Unlock()
+
+	for len(data) > 0 {
+		select {
+		case <-s.done:
+			return fmt.Errorf("%w: %s stopped during input", ErrSessionNotFound, s.id)
+		default:
+		}
+
+		n, err := s.process().Write(data)
+		data = data[n:]
+		if err != nil {
+			select {
+			case <-s.done:
+				return fmt.Errorf("%w: %s stopped during input", ErrSessionNotFound, s.id)
+			default:
+			}
+			return err
+		}
+		if n == 0 {
+			return io.ErrShortWrite // no progress and no error; don't spin
+		}
+	}
+	return nil
 }
 
 func (m *ExecManager) Stop(sessionID string) {
@@ -109,13 +403,104 @@ func (m *ExecManager) Stop(sessionID string) {
 		return
 	}
 
+	// Deliver output still held for coalescing before tearing down
+	m.flushOutput(session, true)
+
 	close(session.done)
-	if session.cmd.Process != nil {
-		_ = session.cmd.Process.Kill()
+	proc := session.process()
+	_ = proc.Kill()
+	_ = proc.Close()
+}
+
+// Pause stops delivering a session's output without stopping its shell.
+// The session stops reading the PTY, so output backs up in the kernel's PTY
+// buffer and, once that fills, the shell's writes block. At most one read's
+// worth of output is held by the client. Pausing a paused session is a no-op.
+func (m *ExecManager) Pause(sessionID string) error {
+	m.mu.Lock()
+	session, exists := m.sessions[sessionID]
+	m.mu.Unlock()
+
+	if !exists {
+		return fmt.Errorf("%w: %s", ErrSessionNotFound, sessionID)
 	}
-	if session.pty != nil {
-		_ = session.pty.Close()
+
+	session.outMu.Lock()
+	defer session.outMu.Unlock()
+	if !session.paused {
+		session.paused = true
+		session.resumed = make(chan struct{})
 	}
+	return nil
+}
+
+// Resume delivers the output held while a session was paused and continues
+// reading its PTY
+func (m *ExecManager) Resume(sessionID string) error {
+	m.mu.Lock()
+	session, exists := m.sessions[sessionID]
+	m.mu.Unlock()
+
+	if !exists {
+		return fmt.Errorf("%w: %s", ErrSessionNotFound, sessionID)
+	}
+
+	session.outMu.Lock()
+	defer session.outMu.Unlock()
+	if session.paused {
+		session.paused = false
+		close(session.resumed)
+		m.flushOutputLocked(session, false)
+	}
+	return nil
+}
+
+// waitWhilePaused blocks while the session is paused. It reports false if
+// the session was stopped meanwhile.
+func (session *ExecSession) waitWhilePaused() bool {
+	session.outMu.Lock()
+	paused, resumed := session.paused, session.resumed
+	session.outMu.Unlock()
+
+	if !paused {
+		return true
+	}
+	select {
+	case <-resumed:
+		return true
+	case <-session.done:
+		return false
+	}
+}
+
+// Resize sets the terminal size of a session
+func (m *ExecManager) Resize(sessionID string, rows, cols uint16) error {
+	m.mu.Lock()
+	session, exists := m.sessions[sessionID]
+	m.mu.Unlock()
+
+	if !exists {
+		return fmt.Errorf("%w: %s", ErrSessionNotFound, sessionID)
+	}
+
+	session.ptyMu.Lock()
+	session.size = ptySize{Rows: rows, Cols: cols}
+	proc := session.pty
+	session.ptyMu.Unlock()
+
+	return proc.Setsize(rows, cols)
+}
+
+// SessionIDs returns the IDs of all running sessions
+func (m *ExecManager) SessionIDs() []string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	ids := make([]string, 0, len(m.sessions))
+	for id := range m.sessions {
+		ids = append(ids, id)
+	}
+	return ids
 }
 
 func (m *ExecManager) StopAll() {
@@ -131,8 +516,24 @@ func (m *ExecManager) StopAll() {
 	}
 }
 
-func (m *ExecManager) readLoop(session *ExecSession) {
-	buf := make([]byte, 4096)
+// watch starts forwarding output from, and waiting on, one PTY process of the session
+func (m *ExecManager) watch(session *ExecSession, proc ptyProcess) {
+	readDone := make(chan struct{})
+	go func() {
+		defer close(readDone)
+		m.readLoop(session, proc)
+	}()
+	go m.waitLoop(session, proc, readDone)
+}
+
+// readLoop forwards output from one PTY process of the session
+func (m *ExecManager) readLoop(session *ExecSession, proc ptyProcess) {
+	m.mu.Lock()
+	bufferSize := m.readBufferSize
+	coalesceDelay := m.coalesceDelay
+	m.mu.Unlock()
+
+	buf := make([]byte, bufferSize)
 
 	for {
 		select {
@@ -141,30 +542,207 @@ func (m *ExecManager) readLoop(session *ExecSession) {
 		default:
 		}
 
-		n, err := session.pty.Read(buf)
+		// Leave output in the PTY while paused so the kernel applies flow control
+		if !session.waitWhilePaused() {
+			return
+		}
+
+		n, err := proc.Read(buf)
+		if n > 0 {
+			m.queueOutput(session, buf[:n], bufferSize, coalesceDelay)
+		}
 		if err != nil {
-			if m.onError != nil {
+			m.flushOutput(session, true)
+			// The PTY closing is how a respawning shell's exit looks from here;
+			// waitLoop decides whether the session goes on
+			if m.onError != nil && !session.respawn {
 				m.onError(session.id, err)
 			}
 			return
 		}
+	}
+}
 
-		if n > 0 && m.onOutput != nil {
-			m.onOutput(session.id, "stdout", string(buf[:n]))
-		}
+// queueOutput appends PTY output to the session's pending buffer and schedules a flush.
+// Chunks are only cut to hold back a trailing partial UTF-8 character or escape
+// sequence, so a message never ends partway through either.
+func (m *ExecManager) queueOutput(session *ExecSession, data []byte, bufferSize int, coalesceDelay time.Duration) {
+	session.outMu.Lock()
+	defer session.outMu.Unlock()
+
+	session.pending = append(session.pending, data...)
+
+	if coalesceDelay == 0 || len(session.pending) >= bufferSize {
+		m.flushOutputLocked(session, false)
+		return
+	}
+
+	if session.flushTimer == nil {
+		session.flushTimer = time.AfterFunc(coalesceDelay, func() {
+			m.flushOutput(session, false)
+		})
 	}
 }
 
-func (m *ExecManager) waitLoop(session *ExecSession) {
-	err := session.cmd.Wait()
-	exitCode := 0
-	if err != nil {
-		if exitErr, ok := err.(*exec.ExitError); ok {
-			exitCode = exitErr.ExitCode()
+// flushOutput delivers pending output for the session. Unless final is set,
+// an incomplete UTF-8 or escape sequence at the end is held back for the next read.
+func (m *ExecManager) flushOutput(session *ExecSession, final bool) {
+	session.outMu.Lock()
+	defer session.outMu.Unlock()
+	m.flushOutputLocked(session, final)
+}
+
+// flushOutputLocked delivers pending output; session.outMu must be held.
+// A paused session keeps its output until Resume, unless the flush is final.
+func (m *ExecManager) flushOutputLocked(session *ExecSession, final bool) {
+	if session.flushTimer != nil {
+		session.flushTimer.Stop()
+		session.flushTimer = nil
+	}
+	if session.paused && !final {
+		return
+	}
+
+	ready := len(session.pending)
+	if !final {
+		if held := incompleteEscapeSuffix(session.pending); held > 0 {
+			ready -= held
 		} else {
-			exitCode = 1
+			ready -= incompleteUTF8Suffix(session.pending)
 		}
 	}
+	if ready == 0 {
+		return
+	}
+
+	data := string(session.pending[:ready])
+	session.pending = append(session.pending[:0], session.pending[ready:]...)
+	if m.onOutput != nil {
+		m.onOutput(session.id, "stdout", data)
+	}
+}
+
+// incompleteUTF8Suffix returns how many trailing bytes of b start a UTF-8
+// sequence that the next read has yet to complete
+func incompleteUTF8Suffix(b []byte) int {
+	// A sequence is at most utf8.UTFMax bytes, so only the tail can be incomplete
+	for i := 1; i < utf8.UTFMax && i <= len(b); i++ {
+		c := b[len(b)-i]
+		if !utf8.RuneStart(c) {
+			continue // continuation byte, keep looking for the lead
+		}
+		need := 0
+		switch {
+		case c&0xE0 == 0xC0:
+			need = 2
+		case c&0xF0 == 0xE0:
+			need = 3
+		case c&0xF8 == 0xF0:
+			need = 4
+		}
+		if need > i {
+			return i
+		}
+		return 0
+	}
+	return 0
+}
+
+// maxEscapeHold bounds how much of an unterminated escape sequence is held
+// back; output that never finishes its sequence is delivered as it is
+const maxEscapeHold = 4096
+
+// incompleteEscapeSuffix returns how many trailing bytes of b belong to an
+// ANSI escape sequence the next read has yet to complete: a CSI
+// (ESC [ params final), an OSC (ESC ] ... BEL or ESC \) or a short
+// ESC sequence such as ESC ( B
+func incompleteEscapeSuffix(b []byte) int {
+	start := bytes.LastIndexByte(b, 0x1b)
+	if start < 0 || len(b)-start > maxEscapeHold {
+		return 0
+	}
+
+	seq := b[start+1:]
+	if len(seq) == 0 {
+		// A lone ESC may be the first half of the ST ending an OSC; hold
+		// the whole OSC so it is not split
+		if osc := bytes.LastIndex(b[:start], []byte{0x1b, ']'}); osc >= 0 &&
+			len(b)-osc <= maxEscapeHold && bytes.IndexByte(b[osc:start], 0x07) < 0 &&
+			bytes.IndexByte(b[osc+2:start], 0x1b) < 0 {
+			return len(b) - osc
+		}
+		return len(b) - start
+	}
+
+	complete := true
+	switch seq[0] {
+	case '[':
+		// Parameter and intermediate bytes until a final byte
+		complete = false
+		for _, c := range seq[1:] {
+			if c >= 0x40 && c <= 0x7e {
+				complete = true
+				break
+			}
+			if c < 0x20 || c > 0x3f {
+				complete = true // malformed; nothing to wait for
+				break
+			}
+		}
+	case ']':
+		// Terminated by BEL, or by ST (ESC \), whose ESC would have been
+		// found as the last one above
+		complete = bytes.IndexByte(seq, 0x07) >= 0
+	default:
+		// Intermediate bytes until a final byte
+		complete = false
+		for _, c := range seq {
+			if c < 0x20 || c > 0x2f {
+				complete = true
+				break
+			}
+		}
+	}
+
+	if complete {
+		return 0
+	}
+	return len(b) - start
+}
+
+// waitLoop waits for one PTY process of the session to exit, then ends the
+// session or, in respawn mode, relaunches the shell
+func (m *ExecManager) waitLoop(session *ExecSession, proc ptyProcess, readDone <-chan struct{}) {
+	exitCode := proc.Wait()
+
+	select {
+	case <-readDone:
+	case <-time.After(outputDrainTimeout):
+	}
+
+	// Output must reach the client before the exit event
+	m.flushOutput(session, true)
+
+	if exitCode == 0 && m.respawnShell(session, proc) {
+		return
+	}
+
+	stopped := false
+	select {
+	case <-session.done:
+		stopped = true
+	default:
+	}
+	m.audit.record(AuditEntry{
+		Event:     AuditExit,
+		SessionID: session.id,
+		Client:    session.client,
+		Shell:     session.shellPath,
+		PID:       proc.Pid(),
+		ExitCode:  exitCode,
+		Stopped:   stopped,
+		Duration:  time.Since(session.startedAt),
+	})
 
 	if m.onExit != nil {
 		m.onExit(session.id, exitCode)
@@ -172,3 +750,71 @@ func (m *ExecManager) waitLoop(session *ExecSession) {
 
 	m.Stop(session.id)
 }
+
+// respawnShell relaunches the shell of a respawning session whose previous
+// process, old, exited. It reports false if the session should end instead:
+// respawn is off, the session was stopped, the limit was hit or the launch failed.
+func (m *ExecManager) respawnShell(session *ExecSession, old ptyProcess) bool {
+	if !session.respawn {
+		return false
+	}
+
+	select {
+	case <-session.done:
+		return false
+	default:
+	}
+
+	now := time.Now()
+	recent := session.respawns[:0]
+	for _, t := range session.respawns {
+		if now.Sub(t) < respawnWindow {
+			recent = append(recent, t)
+		}
+	}
+	session.respawns = recent
+	if len(recent) >= maxRespawns {
+		m.logger.Warn("Session %s: shell exited %d times within %v, not respawning", session.id, len(recent), respawnWindow)
+		return false
+	}
+	session.respawns = append(session.respawns, now)
+
+	session.ptyMu.Lock()
+	size := session.size
+	session.ptyMu.Unlock()
+
+	proc, err := m.ptys.Start(session.shellPath, session.args, session.env, size)
+	if err != nil {
+		m.logger.Error("Session %s: failed to respawn shell: %v", session.id, err)
+		return false
+	}
+	_ = old.Close()
+
+	session.ptyMu.Lock()
+	session.pty = proc
+	session.ptyMu.Unlock()
+
+	// Stop may have run while the shell was starting; it closed the old PTY
+	select {
+	case <-session.done:
+		_ = proc.Kill()
+		_ = proc.Close()
+		return true
+	default:
+	}
+
+	if m.onOutput != nil {
+		m.onOutput(session.id, "stdout", RespawnMarker)
+	}
+	m.logger.Info("Session %s: shell respawned (PID: %d)", session.id, proc.Pid())
+	m.audit.record(AuditEntry{
+		Event:     AuditRespawn,
+		SessionID: session.id,
+		Client:    session.client,
+		Shell:     session.shellPath,
+		PID:       proc.Pid(),
+	})
+
+	m.watch(session, proc)
+	return true
+}
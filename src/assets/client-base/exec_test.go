@@ -0,0 +1,197 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"sync"
+	"testing"
+	"time"
+)
+
+// outputRecorder returns an ExecManager that delivers output without
+// coalescing, and the messages it sent
+func outputRecorder() (*ExecManager, *[]string) {
+	var sent []string
+	m := NewExecManager(nil, func(_, _, data string) { sent = append(sent, data) }, nil, nil)
+	return m, &sent
+}
+
+func TestSplitUTF8IsHeldUntilComplete(t *testing.T) {
+	m, sent := outputRecorder()
+	session := &ExecSession{id: "s"}
+
+	// "é" is 0xC3 0xA9, and "€" is 0xE2 0x82 0xAC; each read ends mid-character
+	reads := [][]byte{
+		{'c', 'a', 'f', 0xC3},
+		{0xA9, ' ', 0xE2, 0x82},
+		{0xAC},
+	}
+	for _, read := range reads {
+		m.queueOutput(session, read, DefaultExecReadBufferSize, 0)
+	}
+
+	want := []string{"caf", "é ", "€"}
+	if len(*sent) != len(want) {
+		t.Fatalf("sent %q, want %q", *sent, want)
+	}
+	for i := range want {
+		if (*sent)[i] != want[i] {
+			t.Fatalf("sent %q, want %q", *sent, want)
+		}
+	}
+}
+
+func TestFinalFlushSendsHeldBytes(t *testing.T) {
+	m, sent := outputRecorder()
+	session := &ExecSession{id: "s"}
+
+	m.queueOutput(session, []byte{'x', 0xE2, 0x82}, DefaultExecReadBufferSize, 0)
+	m.flushOutput(session, true)
+
+	if got := len(*sent); got != 2 || (*sent)[1] != "\xE2\x82" {
+		t.Fatalf("sent %q, want the partial character after x", *sent)
+	}
+}
+
+func TestIncompleteSuffixes(t *testing.T) {
+	tests := []struct {
+		in         string
+		utf8, escs int
+	}{
+		{"plain", 0, 0},
+		{"caf\xC3", 1, 0},
+		{"caf\xC3\xA9", 0, 0},
+		{"\xF0\x9F\x98", 3, 0},
+		{"\xF0\x9F\x98\x80", 0, 0},
+		{"red \x1b[31", 0, 4},
+		{"red \x1b[31m", 0, 0},
+		{"title \x1b]0;shell", 0, 9},
+		{"title \x1b]0;shell\x07", 0, 0},
+		{"end \x1b", 0, 1},
+	}
+	for _, tt := range tests {
+		if got := incompleteUTF8Suffix([]byte(tt.in)); got != tt.utf8 {
+			t.Errorf("incompleteUTF8Suffix(%q) = %d, want %d", tt.in, got, tt.utf8)
+		}
+		if got := incompleteEscapeSuffix([]byte(tt.in)); got != tt.escs {
+			t.Errorf("incompleteEscapeSuffix(%q) = %d, want %d", tt.in, got, tt.escs)
+		}
+	}
+}
+
+func TestColorSequenceSplitAcrossReads(t *testing.T) {
+	m, sent := outputRecorder()
+	session := &ExecSession{id: "s"}
+
+	// "\x1b[1;31m" arrives in three pieces
+	for _, read := range []string{"ok \x1b[", "1;3", "1merror\x1b[0m\r\n"} {
+		m.queueOutput(session, []byte(read), DefaultExecReadBufferSize, 0)
+	}
+
+	want := []string{"ok ", "\x1b[1;31merror\x1b[0m\r\n"}
+	if len(*sent) != len(want) || (*sent)[0] != want[0] || (*sent)[1] != want[1] {
+		t.Fatalf("sent %q, want %q", *sent, want)
+	}
+}
+
+func TestFullBufferDefersPartialSequence(t *testing.T) {
+	m, sent := outputRecorder()
+	session := &ExecSession{id: "s"}
+
+	// A full buffer is flushed at once, even with coalescing, but still
+	// not inside the OSC title sequence at its end
+	const bufferSize = 16
+	m.queueOutput(session, []byte("0123456789\x1b]0;ti"), bufferSize, time.Hour)
+	if len(*sent) != 1 {
+		t.Fatalf("a full buffer sent %q", *sent)
+	}
+	m.queueOutput(session, []byte("tle\x07$ "), bufferSize, time.Hour)
+	m.flushOutput(session, false)
+
+	want := []string{"0123456789", "\x1b]0;title\x07$ "}
+	if len(*sent) != len(want) || (*sent)[0] != want[0] || (*sent)[1] != want[1] {
+		t.Fatalf("sent %q, want %q", *sent, want)
+	}
+}
+
+// shortPTY is a ptyProcess whose writes take at most max bytes each. After
+// limit writes it runs onLimit and fails every later write.
+type shortPTY struct {
+	mu      sync.Mutex
+	max     int
+	written bytes.Buffer
+	writes  int
+	limit   int
+	onLimit func()
+}
+
+func (p *shortPTY) Write(b []byte) (int, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.limit > 0 && p.writes >= p.limit {
+		return 0, errors.New("input/output error")
+	}
+	p.writes++
+	n := min(len(b), p.max)
+	p.written.Write(b[:n])
+	if p.limit > 0 && p.writes == p.limit && p.onLimit != nil {
+		p.onLimit()
+	}
+	return n, nil
+}
+
+func (p *shortPTY) Read([]byte) (int, error)        { return 0, io.EOF }
+func (p *shortPTY) Close() error                    { return nil }
+func (p *shortPTY) Setsize(rows, cols uint16) error { return nil }
+func (p *shortPTY) Pid() int                        { return 1 }
+func (p *shortPTY) Kill() error                     { return nil }
+func (p *shortPTY) Wait() int                       { return 0 }
+
+// inputSession registers a session writing to pty
+func inputSession(m *ExecManager, pty ptyProcess) *ExecSession {
+	session := &ExecSession{id: "s", done: make(chan struct{}), pty: pty}
+	m.sessions[session.id] = session
+	return session
+}
+
+func TestSendInputSurvivesShortWrites(t *testing.T) {
+	m, _ := outputRecorder()
+	pty := &shortPTY{max: 3}
+	inputSession(m, pty)
+
+	input := "echo hello, world\n"
+	if err := m.SendInput("s", input); err != nil {
+		t.Fatal(err)
+	}
+	if got := pty.written.String(); got != input {
+		t.Errorf("PTY got %q, want %q", got, input)
+	}
+	if pty.writes != 6 {
+		t.Errorf("took %d writes, want 6", pty.writes)
+	}
+}
+
+func TestSendInputStoppedMidWrite(t *testing.T) {
+	m, _ := outputRecorder()
+	pty := &shortPTY{max: 4, limit: 2}
+	session := inputSession(m, pty)
+	pty.onLimit = func() { close(session.done) }
+
+	err := m.SendInput("s", "a long line of input\n")
+	if !errors.Is(err, ErrSessionNotFound) {
+		t.Fatalf("got %v, want ErrSessionNotFound", err)
+	}
+	if got := pty.written.String(); got != "a long l" {
+		t.Errorf("PTY got %q before the stop", got)
+	}
+}
+
+func TestSendInputWithoutProgress(t *testing.T) {
+	m, _ := outputRecorder()
+	inputSession(m, &shortPTY{max: 0})
+
+	if err := m.SendInput("s", "x"); !errors.Is(err, io.ErrShortWrite) {
+		t.Fatalf("got %v, want io.ErrShortWrite", err)
+	}
+}
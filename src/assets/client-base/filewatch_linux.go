@@ -0,0 +1,53 @@
+//
+// Strux Client - File Watch (Linux)
+//
+// Wakes tailed log streams with inotify when their file is written, so
+// idle streams don't poll.
+//
+
+package main
+
+import (
+	"errors"
+	"os"
+	"syscall"
+	"time"
+)
+
+// inotifyWatcher waits for writes to one file
+type inotifyWatcher struct {
+	file *os.File // the inotify descriptor, registered with the runtime poller
+}
+
+// newFileWatcher watches path for writes. It fails where inotify is
+// unavailable (e.g. some network and FUSE filesystems), and callers then poll.
+func newFileWatcher(path string) (fileWatcher, error) {
+	fd, err := syscall.InotifyInit1(syscall.IN_CLOEXEC | syscall.IN_NONBLOCK)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := syscall.InotifyAddWatch(fd, path, syscall.IN_MODIFY|syscall.IN_ATTRIB|syscall.IN_CLOSE_WRITE); err != nil {
+		syscall.Close(fd)
+		return nil, err
+	}
+	return &inotifyWatcher{file: os.NewFile(uintptr(fd), "inotify")}, nil
+}
+
+// Wait blocks until the file changes or timeout passes. Queued events are
+// drained in one read, so a burst of writes wakes the reader once.
+func (w *inotifyWatcher) Wait(timeout time.Duration) error {
+	if err := w.file.SetReadDeadline(time.Now().Add(timeout)); err != nil {
+		return err
+	}
+	buf := make([]byte, 4096)
+	_, err := w.file.Read(buf)
+	if errors.Is(err, os.ErrDeadlineExceeded) {
+		return nil
+	}
+	return err
+}
+
+// Close releases the watch and wakes a pending Wait
+func (w *inotifyWatcher) Close() error {
+	return w.file.Close()
+}
@@ -0,0 +1,16 @@
+//go:build !linux
+
+//
+// Strux Client - File Watch (other platforms)
+//
+// inotify is Linux-only; tailed streams poll elsewhere.
+//
+
+package main
+
+import "errors"
+
+// newFileWatcher always fails, so tailed streams fall back to polling
+func newFileWatcher(path string) (fileWatcher, error) {
+	return nil, errors.New("file watching is not supported on this platform")
+}
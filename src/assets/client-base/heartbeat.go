@@ -0,0 +1,118 @@
+//
+// Strux Client - Heartbeat
+//
+// Periodically reports basic device vitals to the dev server. Unlike the
+// WebSocket ping/pong, which only proves the link is up, a heartbeat comes
+// from the client's own goroutines, so the server can tell a hung client
+// from a disconnected one when heartbeats stop.
+//
+
+package main
+
+import (
+	"bufio"
+	"os"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultHeartbeatInterval is how often vitals are sent to the dev server
+const DefaultHeartbeatInterval = 5 * time.Second
+
+// processStart is when the client started, for HeartbeatPayload.ProcessUptime
+var processStart = time.Now()
+
+// HeartbeatPayload carries the vitals sent with each heartbeat. Uptime and
+// memory figures come from /proc and are zero where it can't be read.
+type HeartbeatPayload struct {
+	Interval      float64 `json:"interval"`      // seconds until the next heartbeat
+	Uptime        float64 `json:"uptime"`        // device uptime in seconds
+	ProcessUptime float64 `json:"processUptime"` // client uptime in seconds
+	MemTotal      uint64  `json:"memTotal"`      // bytes
+	MemAvailable  uint64  `json:"memAvailable"`  // bytes
+	HeapAlloc     uint64  `json:"heapAlloc"`     // bytes allocated by the client itself
+	Goroutines    int     `json:"goroutines"`
+	Streams       int     `json:"streams"`  // active log streams
+	Sessions      int     `json:"sessions"` // active exec sessions
+	Channels      int     `json:"channels"` // open IPC channels
+}
+
+// collectVitals builds a heartbeat from the client's current state
+func (s *SocketClient) collectVitals(interval time.Duration) HeartbeatPayload {
+	var mem runtime.MemStats
+	runtime.ReadMemStats(&mem)
+
+	payload := HeartbeatPayload{
+		Interval:      interval.Seconds(),
+		Uptime:        readUptime(),
+		ProcessUptime: time.Since(processStart).Seconds(),
+		HeapAlloc:     mem.HeapAlloc,
+		Goroutines:    runtime.NumGoroutine(),
+		Streams:       len(s.logStreams.GetActiveStreams()),
+		Sessions:      len(s.exec.SessionIDs()),
+		Channels:      len(s.ipc.ChannelIDs()),
+	}
+	payload.MemTotal, payload.MemAvailable = readMemInfo()
+	return payload
+}
+
+// heartbeatLoop sends a heartbeat right away and then every interval until
+// done is closed. Heartbeats due while disconnected are skipped, not queued.
+func (s *SocketClient) heartbeatLoop(done chan struct{}, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		s.SendHeartbeat(interval)
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// readUptime returns the seconds since boot from /proc/uptime
+func readUptime() float64 {
+	data, err := os.ReadFile("/proc/uptime")
+	if err != nil {
+		return 0
+	}
+	fields := strings.Fields(string(data))
+	if len(fields) == 0 {
+		return 0
+	}
+	uptime, _ := strconv.ParseFloat(fields[0], 64)
+	return uptime
+}
+
+// readMemInfo returns total and available memory in bytes from /proc/meminfo
+func readMemInfo() (total, available uint64) {
+	file, err := os.Open("/proc/meminfo")
+	if err != nil {
+		return 0, 0
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		// Lines look like "MemTotal:        2009644 kB"
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 2 {
+			continue
+		}
+		kb, err := strconv.ParseUint(fields[1], 10, 64)
+		if err != nil {
+			continue
+		}
+		switch fields[0] {
+		case "MemTotal:":
+			total = kb * 1024
+		case "MemAvailable:":
+			available = kb * 1024
+		}
+	}
+	return total, available
+}
@@ -7,6 +7,7 @@
 package main
 
 import (
+	"fmt"
 	"os"
 )
 
@@ -24,3 +25,31 @@ func readFileIntoString(path string) (string, error) {
 	}
 	return string(data), nil
 }
+
+// MaxIDLength is the longest stream or session ID accepted from the server
+const MaxIDLength = 128
+
+// validateID checks a server-supplied stream or session ID before it is used
+// as a map key or logged: 1 to MaxIDLength characters from [A-Za-z0-9._:-].
+// The error never echoes more than a short prefix of the ID.
+func validateID(kind, id string) error {
+	if id == "" {
+		return fmt.Errorf("%s ID cannot be empty", kind)
+	}
+	if len(id) > MaxIDLength {
+		return fmt.Errorf("%s ID is too long (%d bytes, max %d)", kind, len(id), MaxIDLength)
+	}
+	for i := 0; i < len(id); i++ {
+		c := id[i]
+		if ('a' <= c && c <= 'z') || ('A' <= c && c <= 'Z') || ('0' <= c && c <= '9') ||
+			c == '.' || c == '_' || c == ':' || c == '-' {
+			continue
+		}
+		prefix := id
+		if len(prefix) > 32 {
+			prefix = prefix[:32]
+		}
+		return fmt.Errorf("%s ID %q contains an invalid character at position %d", kind, prefix, i)
+	}
+	return nil
+}
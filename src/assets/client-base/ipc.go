@@ -0,0 +1,229 @@
+//
+// Strux Client - IPC Channels
+//
+// Carries the app's IPC bridge over the dev server WebSocket, next to log
+// streams and exec sessions, so one connection (and one reconnect) serves
+// all three. Each channel is a connection to the runtime's unix socket,
+// identified by a channel ID chosen by the server; frames are the bridge's
+// JSON messages, passed through unchanged in both directions.
+//
+
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+)
+
+// DefaultIPCSocketPath is where the Strux runtime listens for IPC connections
+const DefaultIPCSocketPath = "/tmp/strux-ipc.sock"
+
+// ErrChannelNotFound is returned, wrapped with the channel ID, for an ID with
+// no open channel; compare with errors.Is
+var ErrChannelNotFound = errors.New("channel not found")
+
+type ipcChannel struct {
+	id   string
+	conn net.Conn
+
+	// writes from the server are serialized so frames don't interleave
+	writeMu sync.Mutex
+}
+
+// IPCManager relays IPC channels between the server and the runtime
+type IPCManager struct {
+	channels   map[string]*ipcChannel
+	mu         sync.Mutex
+	logger     *Logger
+	socketPath string
+	onFrame    func(channelID string, frame json.RawMessage)
+	onClose    func(channelID string, err error)
+}
+
+// NewIPCManager creates an IPC manager. onFrame receives each frame the
+// runtime sends on a channel; onClose is called once when a channel ends,
+// with the error that ended it or nil if it was closed by Close.
+func NewIPCManager(onFrame func(string, json.RawMessage), onClose func(string, error)) *IPCManager {
+	return &IPCManager{
+		channels:   make(map[string]*ipcChannel),
+		logger:     NewLogger("IPCManager"),
+		socketPath: DefaultIPCSocketPath,
+		onFrame:    onFrame,
+		onClose:    onClose,
+	}
+}
+
+// SetSocketPath changes the runtime socket used by channels opened afterwards
+func (m *IPCManager) SetSocketPath(path string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.socketPath = path
+}
+
+// Open connects a new channel to the runtime
+func (m *IPCManager) Open(channelID string) error {
+	if err := validateID("channel", channelID); err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, exists := m.channels[channelID]; exists {
+		return fmt.Errorf("channel already exists: %s", channelID)
+	}
+
+	conn, err := net.Dial("unix", m.socketPath)
+	if err != nil {
+		return fmt.Errorf("failed to connect to runtime: %w", err)
+	}
+
+	channel := &ipcChannel{id: channelID, conn: conn}
+	m.channels[channelID] = channel
+
+	m.logger.Info("Opened IPC channel %s", channelID)
+	go m.readLoop(channel)
+	return nil
+}
+
+// Send writes one frame from the server to the runtime. A frame that can't
+// be delivered ends the channel, and onClose reports the error.
+func (m *IPCManager) Send(channelID string, frame json.RawMessage) error {
+	m.mu.Lock()
+	channel, exists := m.channels[channelID]
+	m.mu.Unlock()
+
+	if !exists {
+		return fmt.Errorf("%w: %s", ErrChannelNotFound, channelID)
+	}
+
+	var err error
+	if !json.Valid(frame) {
+		err = fmt.Errorf("invalid frame on channel %s", channelID)
+	} else {
+		channel.writeMu.Lock()
+		_, err = channel.conn.Write(append(append([]byte(nil), frame...), '\n'))
+		channel.writeMu.Unlock()
+	}
+	if err != nil {
+		m.end(channel, err)
+	}
+	return err
+}
+
+// Close ends a channel; onClose is called with a nil error
+func (m *IPCManager) Close(channelID string) {
+	m.mu.Lock()
+	channel, exists := m.channels[channelID]
+	if exists {
+		delete(m.channels, channelID)
+	}
+	m.mu.Unlock()
+
+	if exists {
+		channel.conn.Close()
+		m.logger.Info("Closed IPC channel %s", channelID)
+		if m.onClose != nil {
+			m.onClose(channelID, nil)
+		}
+	}
+}
+
+// ChannelIDs returns the IDs of the open channels
+func (m *IPCManager) ChannelIDs() []string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	ids := make([]string, 0, len(m.channels))
+	for id := range m.channels {
+		ids = append(ids, id)
+	}
+	return ids
+}
+
+// CloseAll ends every channel
+func (m *IPCManager) CloseAll() {
+	for _, id := range m.ChannelIDs() {
+		m.Close(id)
+	}
+}
+
+// readLoop forwards frames from the runtime until the connection ends
+func (m *IPCManager) readLoop(channel *ipcChannel) {
+	// The runtime bounds the frames it writes (see runtime.SetMaxMessageBytes)
+	decoder := json.NewDecoder(channel.conn)
+
+	var err error
+	for {
+		var frame json.RawMessage
+		if err = decoder.Decode(&frame); err != nil {
+			break
+		}
+		if m.onFrame != nil {
+			m.onFrame(channel.id, frame)
+		}
+	}
+
+	m.end(channel, err)
+}
+
+// end removes a channel that failed and reports err through onClose
+func (m *IPCManager) end(channel *ipcChannel, err error) {
+	// A channel removed by Close has already reported its end
+	m.mu.Lock()
+	current := m.channels[channel.id] == channel
+	if current {
+		delete(m.channels, channel.id)
+	}
+	m.mu.Unlock()
+
+	if current {
+		channel.conn.Close()
+		m.logger.Warn("IPC channel %s ended: %v", channel.id, err)
+		if m.onClose != nil {
+			m.onClose(channel.id, err)
+		}
+	}
+}
+
+// channelQueue runs each channel's events one at a time in the order they
+// were pushed, so a frame never overtakes the open before it, without one
+// slow channel holding up the others
+type channelQueue struct {
+	mu     sync.Mutex
+	queues map[string][]func() // present while the channel's runner is active
+}
+
+func newChannelQueue() *channelQueue {
+	return &channelQueue{queues: make(map[string][]func())}
+}
+
+// push queues fn behind the channel's earlier events
+func (q *channelQueue) push(channelID string, fn func()) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	pending, running := q.queues[channelID]
+	q.queues[channelID] = append(pending, fn)
+	if !running {
+		go q.run(channelID)
+	}
+}
+
+func (q *channelQueue) run(channelID string) {
+	for {
+		q.mu.Lock()
+		pending := q.queues[channelID]
+		if len(pending) == 0 {
+			delete(q.queues, channelID)
+			q.mu.Unlock()
+			return
+		}
+		fn := pending[0]
+		q.queues[channelID] = pending[1:]
+		q.mu.Unlock()
+		fn()
+	}
+}
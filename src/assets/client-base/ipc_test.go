@@ -0,0 +1,99 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net"
+	"path/filepath"
+	"sync"
+	"testing"
+)
+
+func TestChannelQueueKeepsOrderPerChannel(t *testing.T) {
+	q := newChannelQueue()
+	var mu sync.Mutex
+	got := map[string][]int{}
+	var wg sync.WaitGroup
+	for i := 0; i < 200; i++ {
+		id := []string{"a", "b"}[i%2]
+		wg.Add(1)
+		q.push(id, func() {
+			defer wg.Done()
+			mu.Lock()
+			defer mu.Unlock()
+			got[id] = append(got[id], i)
+		})
+	}
+	wg.Wait()
+
+	for id, seen := range got {
+		for j := 1; j < len(seen); j++ {
+			if seen[j] < seen[j-1] {
+				t.Fatalf("channel %s ran %d after %d", id, seen[j], seen[j-1])
+			}
+		}
+	}
+}
+
+// closedChannels records each onClose call
+type closedChannels struct {
+	mu   sync.Mutex
+	errs map[string]error
+}
+
+func (c *closedChannels) add(channelID string, err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.errs[channelID] = err
+}
+
+func (c *closedChannels) get(channelID string) (error, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	err, ok := c.errs[channelID]
+	return err, ok
+}
+
+func TestSendReportsFailedFrames(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "ipc.sock")
+	listener, err := net.Listen("unix", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { listener.Close() })
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go io.Copy(io.Discard, conn)
+		}
+	}()
+
+	closed := &closedChannels{errs: map[string]error{}}
+	m := NewIPCManager(nil, closed.add)
+	m.SetSocketPath(path)
+	t.Cleanup(m.CloseAll)
+
+	if err := m.Send("missing", json.RawMessage(`{}`)); !errors.Is(err, ErrChannelNotFound) {
+		t.Errorf("Send on a missing channel: got %v, want ErrChannelNotFound", err)
+	}
+
+	if err := m.Open("ch1"); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Send("ch1", json.RawMessage(`{"id":1}`)); err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+	if err := m.Send("ch1", json.RawMessage(`{"id":`)); err == nil {
+		t.Fatal("Send accepted an invalid frame")
+	}
+	if err, ok := closed.get("ch1"); !ok || err == nil {
+		t.Errorf("onClose got (%v, %v), want the frame error", err, ok)
+	}
+	if ids := m.ChannelIDs(); len(ids) != 0 {
+		t.Errorf("channels after a failed frame: %q", ids)
+	}
+}
@@ -0,0 +1,81 @@
+//
+// Strux Client - Log Records
+//
+// Parses journalctl short-precise lines ("Mon DD HH:MM:SS.ffffff host
+// unit[pid]: message") into LogRecords for callers that want timestamps
+// and sources without switching the stream to -o json.
+//
+
+package main
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// LogRecord is one parsed log line. Lines that are not in short-precise
+// form only have Message set, to the whole line.
+type LogRecord struct {
+	Time     time.Time
+	Hostname string
+	Unit     string // the syslog identifier, e.g. "systemd" or "kernel"
+	PID      int    // 0 when the line carries no [pid]
+	Message  string
+}
+
+// LogRecordCallback is called for each parsed log line
+type LogRecordCallback func(record LogRecord)
+
+// RecordCallback adapts a LogRecordCallback into a LogCallback by parsing
+// each line with ParseShortPrecise. Use it with streams started with the
+// default short-precise output format.
+func RecordCallback(callback LogRecordCallback) LogCallback {
+	return func(line string) {
+		callback(ParseShortPrecise(line))
+	}
+}
+
+// shortPreciseLine matches "Jan 02 15:04:05.000000 host ident[pid]: message".
+// journalctl zero-pads the day, but a space-padded day is accepted too.
+var shortPreciseLine = regexp.MustCompile(
+	`^([A-Z][a-z]{2}) +(\d{1,2}) (\d{2}:\d{2}:\d{2}\.\d{6}) (\S+) ([^\s\[]+?)(?:\[(\d+)\])?: ?(.*)$`)
+
+// shortPreciseLayout parses the timestamp once the year has been prepended
+const shortPreciseLayout = "2006 Jan 2 15:04:05.000000"
+
+// ParseShortPrecise parses a journalctl short-precise line. The format has
+// no year, so the current one is assumed, or the previous one for a
+// timestamp more than a day in the future (a log read across New Year).
+func ParseShortPrecise(line string) LogRecord {
+	return parseShortPreciseAt(line, time.Now())
+}
+
+// parseShortPreciseAt is ParseShortPrecise with the current time given
+func parseShortPreciseAt(line string, now time.Time) LogRecord {
+	m := shortPreciseLine.FindStringSubmatch(line)
+	if m == nil {
+		return LogRecord{Message: line}
+	}
+
+	stamp := strings.Join([]string{m[1], m[2], m[3]}, " ")
+	t, err := time.ParseInLocation(shortPreciseLayout, strconv.Itoa(now.Year())+" "+stamp, now.Location())
+	if err != nil {
+		return LogRecord{Message: line}
+	}
+	if t.After(now.Add(24 * time.Hour)) {
+		t = t.AddDate(-1, 0, 0)
+	}
+
+	record := LogRecord{
+		Time:     t,
+		Hostname: m[4],
+		Unit:     m[5],
+		Message:  m[7],
+	}
+	if m[6] != "" {
+		record.PID, _ = strconv.Atoi(m[6])
+	}
+	return record
+}
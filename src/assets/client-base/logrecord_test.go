@@ -0,0 +1,70 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseShortPrecise(t *testing.T) {
+	now := time.Date(2026, time.October, 17, 12, 0, 0, 0, time.UTC)
+	at := func(month time.Month, day, hour, min, sec, usec int) time.Time {
+		return time.Date(2026, month, day, hour, min, sec, usec*1000, time.UTC)
+	}
+
+	tests := []struct {
+		line string
+		want LogRecord
+	}{
+		{
+			"Oct 17 07:48:18.123456 strux-device systemd[1]: Started Strux Cage.",
+			LogRecord{Time: at(time.October, 17, 7, 48, 18, 123456), Hostname: "strux-device", Unit: "systemd", PID: 1, Message: "Started Strux Cage."},
+		},
+		{
+			"Oct 17 07:48:18.200001 strux-device kernel: usb 1-1: new high-speed USB device number 2 using xhci_hcd",
+			LogRecord{Time: at(time.October, 17, 7, 48, 18, 200001), Hostname: "strux-device", Unit: "kernel", Message: "usb 1-1: new high-speed USB device number 2 using xhci_hcd"},
+		},
+		{
+			"Oct 17 07:48:19.000002 strux-device systemd-journald[212]: Journal started",
+			LogRecord{Time: at(time.October, 17, 7, 48, 19, 2), Hostname: "strux-device", Unit: "systemd-journald", PID: 212, Message: "Journal started"},
+		},
+		{
+			"Oct  7 09:01:02.345678 strux-device sshd[88]: Accepted publickey for root: ssh-ed25519",
+			LogRecord{Time: at(time.October, 7, 9, 1, 2, 345678), Hostname: "strux-device", Unit: "sshd", PID: 88, Message: "Accepted publickey for root: ssh-ed25519"},
+		},
+		{
+			"Oct 17 07:48:20.000000 strux-device cage[412]:",
+			LogRecord{Time: at(time.October, 17, 7, 48, 20, 0), Hostname: "strux-device", Unit: "cage", PID: 412},
+		},
+		// journalctl's own markers and foreign lines pass through as they are
+		{"-- Boot 3f2a9c1e4b5d4e6f8a7b9c0d1e2f3a4b --", LogRecord{Message: "-- Boot 3f2a9c1e4b5d4e6f8a7b9c0d1e2f3a4b --"}},
+		{"-- No entries --", LogRecord{Message: "-- No entries --"}},
+		{"2026-10-17T07:48:18+0000 strux-device app[9]: short-iso", LogRecord{Message: "2026-10-17T07:48:18+0000 strux-device app[9]: short-iso"}},
+		{"", LogRecord{}},
+	}
+	for _, tt := range tests {
+		got := parseShortPreciseAt(tt.line, now)
+		if !got.Time.Equal(tt.want.Time) || got.Hostname != tt.want.Hostname || got.Unit != tt.want.Unit ||
+			got.PID != tt.want.PID || got.Message != tt.want.Message {
+			t.Errorf("parse %q:\n got %+v\nwant %+v", tt.line, got, tt.want)
+		}
+	}
+}
+
+func TestParseShortPreciseAcrossNewYear(t *testing.T) {
+	now := time.Date(2027, time.January, 1, 0, 30, 0, 0, time.UTC)
+	got := parseShortPreciseAt("Dec 31 23:59:59.999999 strux-device app[7]: last of the year", now)
+	if want := time.Date(2026, time.December, 31, 23, 59, 59, 999999000, time.UTC); !got.Time.Equal(want) {
+		t.Errorf("got %v, want %v", got.Time, want)
+	}
+}
+
+func TestRecordCallback(t *testing.T) {
+	var got []LogRecord
+	callback := RecordCallback(func(record LogRecord) { got = append(got, record) })
+	callback("Oct 17 07:48:18.123456 strux-device app[5]: ready")
+	callback("plain line")
+
+	if len(got) != 2 || got[0].Unit != "app" || got[0].Message != "ready" || got[1].Message != "plain line" || got[1].Unit != "" {
+		t.Errorf("got %+v", got)
+	}
+}
@@ -9,10 +9,17 @@ package main
 
 import (
 	"bufio"
+	"compress/gzip"
+	"context"
+	"errors"
 	"fmt"
 	"io"
 	"os"
 	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
 	"sync"
 	"time"
 )
@@ -20,6 +27,155 @@ import (
 // LogCallback is called for each log line
 type LogCallback func(line string)
 
+// TaggedLogCallback is called for each log line with the output it was read
+// from, OutputStdout or OutputStderr. Lines read from files are tagged
+// OutputStdout.
+type TaggedLogCallback func(stream string, line string)
+
+// Output tags passed to a TaggedLogCallback
+const (
+	OutputStdout = "stdout"
+	OutputStderr = "stderr"
+)
+
+// untagged adapts a LogCallback into a TaggedLogCallback that drops the tag
+func untagged(callback LogCallback) TaggedLogCallback {
+	return func(_ string, line string) {
+		callback(line)
+	}
+}
+
+// SequencedLogCallback is called for each log line with its sequence number.
+// Numbers start at 1 and increase by one per line within a stream, so a gap
+// means lines were lost.
+type SequencedLogCallback func(seq uint64, line string)
+
+// StreamRestartMarker is delivered to a SequencedLogCallback, with its own
+// sequence number, when a stream ID whose previous stream ended by itself is
+// started again; numbering continues from where that stream stopped.
+const StreamRestartMarker = "-- stream restarted --"
+
+// WriterCallback adapts an io.Writer into a LogCallback.
+// Each line is written with a trailing newline; writes are serialized
+// because stdout and stderr readers deliver lines concurrently.
+func WriterCallback(w io.Writer) LogCallback {
+	var mu sync.Mutex
+	return func(line string) {
+		mu.Lock()
+		defer mu.Unlock()
+		io.WriteString(w, line+"\n")
+	}
+}
+
+// ChannelCallback adapts a channel into a LogCallback.
+// Sends block, so the receiver must keep draining the channel until the stream is stopped.
+func ChannelCallback(ch chan<- string) LogCallback {
+	return func(line string) {
+		ch <- line
+	}
+}
+
+// DefaultContinuation matches indented lines and "Caused by:" chains,
+// which covers Go panics and Java/Kotlin stack traces
+var DefaultContinuation = regexp.MustCompile(`^(\s|Caused by: )`)
+
+// DefaultCoalesceFlush is how long a coalesced entry may sit idle before delivery
+const DefaultCoalesceFlush = 200 * time.Millisecond
+
+// LogCoalescer joins continuation lines onto the preceding entry so that
+// multi-line output like stack traces is delivered as a single callback
+type LogCoalescer struct {
+	callback     LogCallback
+	continuation *regexp.Regexp
+	flushAfter   time.Duration
+	pending      []string
+	timer        *time.Timer
+	stopped      bool // set by Stop; nothing is delivered afterwards
+	mu           sync.Mutex
+}
+
+// NewLogCoalescer wraps callback with coalescing.
+// A nil continuation uses DefaultContinuation and a zero flushAfter uses DefaultCoalesceFlush.
+func NewLogCoalescer(callback LogCallback, continuation *regexp.Regexp, flushAfter time.Duration) *LogCoalescer {
+	if continuation == nil {
+		continuation = DefaultContinuation
+	}
+	if flushAfter <= 0 {
+		flushAfter = DefaultCoalesceFlush
+	}
+	return &LogCoalescer{
+		callback:     callback,
+		continuation: continuation,
+		flushAfter:   flushAfter,
+	}
+}
+
+// Callback returns a LogCallback that feeds lines into the coalescer
+func (c *LogCoalescer) Callback() LogCallback {
+	return c.add
+}
+
+// add appends a line to the pending entry or starts a new one
+func (c *LogCoalescer) add(line string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.stopped {
+		return
+	}
+	if len(c.pending) > 0 && !c.continuation.MatchString(line) {
+		c.flushLocked()
+	}
+	c.pending = append(c.pending, line)
+
+	// Restart the idle timer so the last entry is never held indefinitely
+	if c.timer == nil {
+		c.timer = time.AfterFunc(c.flushAfter, c.flushIdle)
+	} else {
+		c.timer.Reset(c.flushAfter)
+	}
+}
+
+// Flush delivers the pending entry, if any
+func (c *LogCoalescer) Flush() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.flushLocked()
+}
+
+// Stop delivers the pending entry and drops every line added afterwards. The
+// idle timer is stopped too, so once Stop returns no callback is made.
+func (c *LogCoalescer) Stop() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.flushLocked()
+	c.stopped = true
+}
+
+// flushIdle is the idle timer's flush. A timer that fired while Stop held
+// c.mu finds the coalescer stopped and delivers nothing.
+func (c *LogCoalescer) flushIdle() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if !c.stopped {
+		c.flushLocked()
+	}
+}
+
+// flushLocked delivers the pending entry and stops the idle timer, which
+// add restarts for the next entry; c.mu must be held
+func (c *LogCoalescer) flushLocked() {
+	if c.timer != nil {
+		c.timer.Stop()
+	}
+	if c.stopped || len(c.pending) == 0 {
+		return
+	}
+	entry := strings.Join(c.pending, "\n")
+	c.pending = nil
+	c.callback(entry)
+}
+
 // LogStreamType indicates the type of log stream
 type LogStreamType int
 
@@ -28,59 +184,513 @@ const (
 	LogStreamTypeFile
 )
 
+func (t LogStreamType) String() string {
+	if t == LogStreamTypeFile {
+		return "file"
+	}
+	return "command"
+}
+
 // LogStream represents an active log stream
 type LogStream struct {
 	ID         string
 	Service    string
 	StreamType LogStreamType
-	cmd        *exec.Cmd
+	proc       LogProcess
 	file       *os.File
-	callback   LogCallback
+	callback   TaggedLogCallback
+	recent     *lineRing
+	readers    sync.WaitGroup // tracks goroutines that deliver lines
+	flush      func()         // delivers output still buffered by the callback
 	done       chan struct{}
 	stopped    bool
+	paused     bool
+	resumed    chan struct{} // closed by Resume
 	mu         sync.Mutex
+
+	// Limits (see SetLimits) and what has been delivered against them
+	limits     StreamLimits
+	lines      int
+	bytes      int64
+	onComplete func(StreamEndReason)
+	completed  bool
+
+	// Sequence numbering (see SetSequenced). deliverMu keeps callbacks in
+	// sequence order although stdout and stderr are read concurrently.
+	seq         uint64
+	seqCallback SequencedLogCallback
+	deliverMu   sync.Mutex
+}
+
+// StreamLimits bounds how much a stream delivers before it stops itself.
+// Zero means unlimited.
+type StreamLimits struct {
+	MaxLines int
+	MaxBytes int64
+}
+
+// StreamEndReason says why a stream ended without being stopped
+type StreamEndReason int
+
+const (
+	// StreamEndExited means the command exited or a compressed file was fully read
+	StreamEndExited StreamEndReason = iota
+	// StreamEndLimit means MaxLines or MaxBytes was reached
+	StreamEndLimit
+)
+
+// String returns "exited" or "limit"
+func (r StreamEndReason) String() string {
+	if r == StreamEndLimit {
+		return "limit"
+	}
+	return "exited"
+}
+
+// StreamSetup configures a stream when it is created, before its source
+// starts, so its first lines are handled like the rest. The zero value
+// leaves the stream unconfigured.
+type StreamSetup struct {
+	// Sequenced receives each line with its sequence number instead of the
+	// stream's LogCallback (see SetSequenced)
+	Sequenced SequencedLogCallback
+
+	// Limits and OnComplete bound the stream and report how it ended (see
+	// SetLimits)
+	Limits     StreamLimits
+	OnComplete func(StreamEndReason)
+
+	// Flush delivers output the LogCallback is still buffering when the
+	// stream ends (see SetFlush)
+	Flush func()
+}
+
+// apply configures stream, which has not started yet
+func (c StreamSetup) apply(stream *LogStream) {
+	stream.seqCallback = c.Sequenced
+	stream.limits = c.Limits
+	stream.onComplete = c.OnComplete
+	stream.flush = c.Flush
+}
+
+// DefaultRecentLines is how many delivered lines each stream retains for GetRecent
+const DefaultRecentLines = 200
+
+// deliver records a line in the stream's recent buffer and passes it to the
+// callback, tagged with the output it came from. It reports whether the line
+// reached the stream's limits. While the stream is paused it blocks.
+func (s *LogStream) deliver(tag, line string) bool {
+	s.deliverMu.Lock()
+	defer s.deliverMu.Unlock()
+
+	s.mu.Lock()
+	for s.paused {
+		// Hold the line, and with it the reader, until resumed or stopped
+		resumed := s.resumed
+		s.mu.Unlock()
+		select {
+		case <-resumed:
+		case <-s.done:
+			return false
+		}
+		s.mu.Lock()
+	}
+	if s.recent == nil {
+		s.recent = newLineRing(DefaultRecentLines)
+	}
+	s.recent.add(line)
+	// A restarted stream starts with a non-zero seq; mark the boundary first
+	restarted := s.lines == 0 && s.seq > 0
+	if restarted {
+		s.seq++
+	}
+	markerSeq := s.seq
+	s.seq++
+	seq := s.seq
+	s.lines++
+	s.bytes += int64(len(line)) + 1 // count the newline the reader stripped
+	limited := (s.limits.MaxLines > 0 && s.lines >= s.limits.MaxLines) ||
+		(s.limits.MaxBytes > 0 && s.bytes >= s.limits.MaxBytes)
+	seqCallback := s.seqCallback
+	s.mu.Unlock()
+
+	if seqCallback == nil {
+		s.callback(tag, line)
+		return limited
+	}
+	if restarted {
+		seqCallback(markerSeq, StreamRestartMarker)
+	}
+	seqCallback(seq, line)
+	return limited
+}
+
+// complete fires the stream's onComplete hook at most once
+func (s *LogStream) complete(reason StreamEndReason) {
+	s.mu.Lock()
+	onComplete := s.onComplete
+	if s.completed {
+		onComplete = nil
+	}
+	s.completed = true
+	s.mu.Unlock()
+
+	if onComplete != nil {
+		onComplete(reason)
+	}
+}
+
+// flushPending runs the stream's flush hook, if one was set
+func (s *LogStream) flushPending() {
+	s.mu.Lock()
+	flush := s.flush
+	s.mu.Unlock()
+
+	if flush != nil {
+		flush()
+	}
+}
+
+// lineRing is a fixed-size ring buffer of the most recent lines
+type lineRing struct {
+	lines []string
+	next  int
+	full  bool
+}
+
+func newLineRing(size int) *lineRing {
+	return &lineRing{lines: make([]string, size)}
+}
+
+func (r *lineRing) add(line string) {
+	if len(r.lines) == 0 {
+		return
+	}
+	r.lines[r.next] = line
+	r.next = (r.next + 1) % len(r.lines)
+	if r.next == 0 {
+		r.full = true
+	}
+}
+
+// last returns up to n of the most recent lines, oldest first
+func (r *lineRing) last(n int) []string {
+	count := r.next
+	if r.full {
+		count = len(r.lines)
+	}
+	if n <= 0 || n > count {
+		n = count
+	}
+
+	out := make([]string, n)
+	start := r.next - n
+	if start < 0 {
+		start += len(r.lines)
+	}
+	for i := 0; i < n; i++ {
+		out[i] = r.lines[(start+i)%len(r.lines)]
+	}
+	return out
+}
+
+// Errors returned by LogStreamer, wrapped with the stream ID; compare with errors.Is
+var (
+	// ErrNoLogBackend is returned when neither journalctl nor any fallback log source is available
+	ErrNoLogBackend = errors.New("no log backend available")
+
+	// ErrStreamExists is returned when starting a stream whose ID is in use
+	ErrStreamExists = errors.New("stream already exists")
+
+	// ErrStreamNotFound is returned for an ID with no running stream
+	ErrStreamNotFound = errors.New("stream not found")
+
+	// ErrJournalPermission is returned when journalctl cannot read the
+	// requested journal, e.g. a non-root client without --user
+	ErrJournalPermission = errors.New("permission denied reading the journal")
+
+	// ErrCommandNotAllowed is returned by StartCommandStream for a command
+	// the host has not allowed with SetAllowedCommands
+	ErrCommandNotAllowed = errors.New("command not allowed")
+)
+
+// syslogPaths are tailed, in order, when journalctl is not installed
+var syslogPaths = []string{"/var/log/messages", "/var/log/syslog"}
+
+var (
+	journalctlAvailable bool
+	journalctlProbeOnce sync.Once
+)
+
+// HasJournalctl reports whether journalctl is installed.
+// The result is probed once and cached for the life of the process.
+func HasJournalctl() bool {
+	journalctlProbeOnce.Do(func() {
+		_, err := exec.LookPath("journalctl")
+		journalctlAvailable = err == nil
+	})
+	return journalctlAvailable
+}
+
+// DefaultJournalFormat is the journalctl -o value used when none is given
+const DefaultJournalFormat = "short-precise"
+
+// journalFormats are the journalctl -o values accepted by JournalOptions
+var journalFormats = map[string]bool{
+	"short":             true,
+	"short-precise":     true,
+	"short-iso":         true,
+	"short-iso-precise": true,
+	"short-full":        true,
+	"short-monotonic":   true,
+	"short-unix":        true,
+	"with-unit":         true,
+	"verbose":           true,
+	"cat":               true,
+	"json":              true,
+}
+
+// JournalOptions configures the journalctl-based starters
+type JournalOptions struct {
+	// OutputFormat is the journalctl -o value (default "short-precise")
+	OutputFormat string
+
+	// Boot selects a boot relative to the current one (-b <n>), e.g. -1 for the
+	// previous boot. Zero means no boot filter, except for the early log stream,
+	// which always reads a single boot (the current one by default).
+	Boot int
+
+	// KernelOnly restricts output to kernel messages (-k)
+	KernelOnly bool
+
+	// Transport restricts output to one journal transport, e.g. "stdout" or "kernel"
+	Transport string
+
+	// UserJournal reads the calling user's journal (--user) instead of the
+	// system journal, for clients that don't run as root
+	UserJournal bool
+
+	// Machine reads the journal of a local container (-M name)
+	Machine string
+}
+
+// journalTransports are the _TRANSPORT values accepted by JournalOptions
+var journalTransports = map[string]bool{
+	"audit":   true,
+	"driver":  true,
+	"syslog":  true,
+	"journal": true,
+	"stdout":  true,
+	"kernel":  true,
+}
+
+// args validates the options and returns the journalctl arguments they produce
+func (o JournalOptions) args() ([]string, error) {
+	format := o.OutputFormat
+	if format == "" {
+		format = DefaultJournalFormat
+	}
+	if !journalFormats[format] {
+		return nil, fmt.Errorf("unsupported journalctl output format: %s", format)
+	}
+	if o.Boot > 0 {
+		return nil, fmt.Errorf("boot offset must be zero or negative, got %d", o.Boot)
+	}
+	if o.Transport != "" && !journalTransports[o.Transport] {
+		return nil, fmt.Errorf("unsupported journal transport: %s", o.Transport)
+	}
+	if o.Machine != "" {
+		if err := validateID("machine", o.Machine); err != nil {
+			return nil, err
+		}
+	}
+
+	args := []string{"--no-pager", "-o", format}
+	if o.KernelOnly {
+		args = append(args, "-k")
+	}
+	// -k implies the current boot, so -b must come after it to take effect
+	if o.Boot != 0 {
+		args = append(args, "-b", strconv.Itoa(o.Boot))
+	}
+	if o.UserJournal {
+		args = append(args, "--user")
+	}
+	if o.Machine != "" {
+		args = append(args, "-M", o.Machine)
+	}
+	// Matches go last, after every option
+	if o.Transport != "" {
+		args = append(args, "_TRANSPORT="+o.Transport)
+	}
+	return args, nil
+}
+
+// DefaultTailPollInterval is how often tailed files are checked for new
+// lines where inotify is unavailable
+const DefaultTailPollInterval = 100 * time.Millisecond
+
+// inotifyRecheckInterval bounds how long a tailed file waits on inotify,
+// as a safety net for changes the watch does not report
+const inotifyRecheckInterval = 5 * time.Second
+
+// fileWatcher wakes a tailed stream when its file changes
+type fileWatcher interface {
+	// Wait blocks until the file changes or timeout passes
+	Wait(timeout time.Duration) error
+
+	// Close releases the watch and wakes a pending Wait
+	Close() error
 }
 
 // LogStreamer manages log streams
 type LogStreamer struct {
-	streams map[string]*LogStream
-	mu      sync.Mutex
-	logger  *Logger
+	streams      map[string]*LogStream
+	lastSeq      map[string]uint64 // last sequence number of streams that ended by themselves
+	source       LogSource
+	pollInterval time.Duration
+	fileWait     Backoff
+	appLogPath   string
+	cageLogPath  string
+	allowed      map[string]bool // commands StartCommandStream may run
+	dmesgFollow  *bool           // whether source's dmesg supports -w, once probed
+	mu           sync.Mutex
+	logger       *Logger
 }
 
+// Default paths of the log files written by the app and by Cage
+const (
+	DefaultAppLogPath  = "/tmp/strux-backend.log"
+	DefaultCageLogPath = "/tmp/strux-cage.log"
+)
+
 // NewLogStreamer creates a new log streamer
 func NewLogStreamer() *LogStreamer {
 	return &LogStreamer{
-		streams: make(map[string]*LogStream),
-		logger:  NewLogger("LogStreamer"),
+		streams:      make(map[string]*LogStream),
+		lastSeq:      make(map[string]uint64),
+		source:       execLogSource{},
+		pollInterval: DefaultTailPollInterval,
+		fileWait:     DefaultFileWaitBackoff,
+		appLogPath:   DefaultAppLogPath,
+		cageLogPath:  DefaultCageLogPath,
+		logger:       NewLogger("LogStreamer"),
 	}
 }
 
+// SetFileWaitBackoff sets how file streams started afterwards space out their
+// checks for a file that does not exist yet. The wait still ends after
+// fileWaitTimeout; a Backoff without Jitter makes the checks deterministic.
+func (l *LogStreamer) SetFileWaitBackoff(backoff Backoff) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.fileWait = backoff
+}
+
+// SetLogPaths changes the files StartAppLogStream and StartCageLogStream
+// tail, for images that keep logs off /tmp. An empty path keeps the current one.
+func (l *LogStreamer) SetLogPaths(appLog, cageLog string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if appLog != "" {
+		l.appLogPath = appLog
+	}
+	if cageLog != "" {
+		l.cageLogPath = cageLog
+	}
+}
+
+// SetAllowedCommands replaces the commands StartCommandStream may run. Names
+// match exactly, so "ping" does not allow "/bin/ping". No command is allowed
+// until the host opts in, since the dev server can request command streams.
+func (l *LogStreamer) SetAllowedCommands(names []string) {
+	allowed := make(map[string]bool, len(names))
+	for _, name := range names {
+		if name != "" {
+			allowed[name] = true
+		}
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.allowed = allowed
+}
+
+// SetPollInterval sets how often tailed files are checked for new lines when
+// inotify is unavailable, for file streams started afterwards. Where inotify
+// works, streams wake on writes instead.
+func (l *LogStreamer) SetPollInterval(interval time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if interval > 0 {
+		l.pollInterval = interval
+	}
+}
+
+// SetSource replaces the source used to start journalctl and dmesg, so tests
+// can substitute scripted output. It affects streams started afterwards.
+func (l *LogStreamer) SetSource(source LogSource) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.source = source
+	l.dmesgFollow = nil
+}
+
 // StartJournalctlStream starts streaming all journalctl logs
 func (l *LogStreamer) StartJournalctlStream(streamID string, callback LogCallback) error {
+	return l.StartJournalctlStreamWithOptions(streamID, JournalOptions{}, callback, StreamSetup{})
+}
+
+// StartJournalctlStreamWithOptions starts streaming all journalctl logs using opts
+func (l *LogStreamer) StartJournalctlStreamWithOptions(streamID string, opts JournalOptions, callback LogCallback, setup StreamSetup) error {
+	return l.startJournalctlStream(streamID, opts, untagged(callback), setup)
+}
+
+// StartJournalctlStreamTagged is StartJournalctlStreamWithOptions with each
+// line tagged by the output it was read from
+func (l *LogStreamer) StartJournalctlStreamTagged(streamID string, opts JournalOptions, callback TaggedLogCallback) error {
+	return l.startJournalctlStream(streamID, opts, callback, StreamSetup{})
+}
+
+// startJournalctlStream starts an all-journal stream delivering to callback
+func (l *LogStreamer) startJournalctlStream(streamID string, opts JournalOptions, callback TaggedLogCallback, setup StreamSetup) error {
+	if err := validateID("stream", streamID); err != nil {
+		return err
+	}
+	args, err := opts.args()
+	if err != nil {
+		return err
+	}
+	probe, err := l.probeJournal(streamID, args)
+	if err != nil {
+		return err
+	}
+
 	l.mu.Lock()
 	defer l.mu.Unlock()
 
 	if _, exists := l.streams[streamID]; exists {
-		return fmt.Errorf("stream %s already exists", streamID)
+		return fmt.Errorf("%w: %s", ErrStreamExists, streamID)
 	}
 
 	l.logger.Info("Starting journalctl stream: %s", streamID)
 
-	// Create the journalctl command
-	cmd := exec.Command("journalctl", "-f", "--no-pager", "-o", "short-precise")
-
 	// Create the stream
 	stream := &LogStream{
 		ID:         streamID,
 		StreamType: LogStreamTypeCommand,
-		cmd:        cmd,
 		callback:   callback,
 		done:       make(chan struct{}),
+		seq:        l.resumeSeq(streamID),
 	}
+	setup.apply(stream)
 
-	// Start the command and stream output
-	if err := l.startCommandStream(stream); err != nil {
+	if probe.available {
+		// Start the journalctl command and stream output
+		if err := l.startJournalStream(stream, probe, args, "-f"); err != nil {
+			return err
+		}
+	} else if err := l.startFallbackStream(stream, true); err != nil {
 		return err
 	}
 
@@ -90,187 +700,630 @@ func (l *LogStreamer) StartJournalctlStream(streamID string, callback LogCallbac
 
 // StartServiceStream starts streaming logs for a specific systemd service
 func (l *LogStreamer) StartServiceStream(streamID, serviceName string, callback LogCallback) error {
+	return l.StartServiceStreamWithOptions(streamID, serviceName, JournalOptions{}, callback, StreamSetup{})
+}
+
+// StartServiceStreamWithOptions starts streaming logs for a specific systemd service using opts
+func (l *LogStreamer) StartServiceStreamWithOptions(streamID, serviceName string, opts JournalOptions, callback LogCallback, setup StreamSetup) error {
+	return l.startServiceStream(streamID, serviceName, opts, untagged(callback), setup)
+}
+
+// StartServiceStreamTagged is StartServiceStreamWithOptions with each line
+// tagged by the output it was read from
+func (l *LogStreamer) StartServiceStreamTagged(streamID, serviceName string, opts JournalOptions, callback TaggedLogCallback) error {
+	return l.startServiceStream(streamID, serviceName, opts, callback, StreamSetup{})
+}
+
+// startServiceStream starts a service stream delivering to callback
+func (l *LogStreamer) startServiceStream(streamID, serviceName string, opts JournalOptions, callback TaggedLogCallback, setup StreamSetup) error {
+	if err := validateID("stream", streamID); err != nil {
+		return err
+	}
+	args, err := opts.args()
+	if err != nil {
+		return err
+	}
+	probe, err := l.probeJournal(streamID, args)
+	if err != nil {
+		return err
+	}
+
 	l.mu.Lock()
 	defer l.mu.Unlock()
 
 	if _, exists := l.streams[streamID]; exists {
-		return fmt.Errorf("stream %s already exists", streamID)
+		return fmt.Errorf("%w: %s", ErrStreamExists, streamID)
 	}
 
 	l.logger.Info("Starting service stream: %s for %s", streamID, serviceName)
 
-	// Create the journalctl command for the specific service
-	cmd := exec.Command("journalctl", "-f", "--no-pager", "-u", serviceName, "-o", "short-precise")
-
 	// Create the stream
 	stream := &LogStream{
 		ID:         streamID,
 		Service:    serviceName,
 		StreamType: LogStreamTypeCommand,
-		cmd:        cmd,
 		callback:   callback,
 		done:       make(chan struct{}),
+		seq:        l.resumeSeq(streamID),
 	}
+	setup.apply(stream)
 
-	// Start the command and stream output
-	if err := l.startCommandStream(stream); err != nil {
+	if probe.available {
+		// Create the journalctl command for the specific service
+		if err := l.startJournalStream(stream, probe, args, "-f", "-u", serviceName); err != nil {
+			return err
+		}
+	} else {
+		// Syslog has no unit field, so keep only lines mentioning the service
+		stream.callback = func(tag, line string) {
+			if strings.Contains(line, serviceName) {
+				callback(tag, line)
+			}
+		}
+		if err := l.startFallbackStream(stream, false); err != nil {
+			return err
+		}
+	}
+
+	l.streams[streamID] = stream
+	return nil
+}
+
+// StartJournalctlSnapshot reads the journal once, without following it, and
+// delivers lines until setup.Limits are reached or journalctl runs out. The
+// stream then ends by itself and setup.OnComplete is called with
+// StreamEndLimit or StreamEndExited. serviceName restricts output to one unit; empty reads the
+// whole journal. Unlike the follow-mode starters there is no syslog fallback.
+func (l *LogStreamer) StartJournalctlSnapshot(streamID, serviceName string, opts JournalOptions, callback LogCallback, setup StreamSetup) error {
+	if err := validateID("stream", streamID); err != nil {
+		return err
+	}
+	args, err := opts.args()
+	if err != nil {
+		return err
+	}
+	if serviceName != "" {
+		args = append([]string{"-u", serviceName}, args...)
+	}
+	probe, err := l.probeJournal(streamID, args)
+	if err != nil {
+		return err
+	}
+	if !probe.available {
+		return ErrNoLogBackend
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if _, exists := l.streams[streamID]; exists {
+		return fmt.Errorf("%w: %s", ErrStreamExists, streamID)
+	}
+
+	l.logger.Info("Starting journalctl snapshot: %s", streamID)
+
+	stream := &LogStream{
+		ID:         streamID,
+		Service:    serviceName,
+		StreamType: LogStreamTypeCommand,
+		callback:   untagged(callback),
+		done:       make(chan struct{}),
+		seq:        l.resumeSeq(streamID),
+	}
+	setup.apply(stream)
+
+	if err := l.startJournalStream(stream, probe, args); err != nil {
+		return err
+	}
+
+	l.streams[streamID] = stream
+	return nil
+}
+
+// StartServiceStreamWriter streams logs for a specific systemd service into w
+func (l *LogStreamer) StartServiceStreamWriter(streamID, serviceName string, w io.Writer) error {
+	return l.StartServiceStream(streamID, serviceName, WriterCallback(w))
+}
+
+// StartServiceStreamChan streams logs for a specific systemd service into ch
+func (l *LogStreamer) StartServiceStreamChan(streamID, serviceName string, ch chan<- string) error {
+	return l.StartServiceStream(streamID, serviceName, ChannelCallback(ch))
+}
+
+// StartAppLogStream starts streaming the application log file, where the
+// user's Go app output is written (DefaultAppLogPath unless changed with SetLogPaths)
+func (l *LogStreamer) StartAppLogStream(streamID string, callback LogCallback, setup StreamSetup) error {
+	l.mu.Lock()
+	path := l.appLogPath
+	l.mu.Unlock()
+	return l.startFileLogStream(streamID, "app", path, callback, setup)
+}
+
+// StartCageLogStream starts streaming the Cage compositor log file, where
+// Cage/Cog output is written (DefaultCageLogPath unless changed with SetLogPaths)
+func (l *LogStreamer) StartCageLogStream(streamID string, callback LogCallback, setup StreamSetup) error {
+	l.mu.Lock()
+	path := l.cageLogPath
+	l.mu.Unlock()
+	return l.startFileLogStream(streamID, "cage", path, callback, setup)
+}
+
+// StartFileLogStream starts tailing the file at path, which must be
+// absolute. Like the app and cage streams, the file may appear later: the
+// stream waits for it, and a .gz file is read once instead of tailed.
+func (l *LogStreamer) StartFileLogStream(streamID, path string, callback LogCallback, setup StreamSetup) error {
+	if !filepath.IsAbs(path) {
+		return fmt.Errorf("log file path must be absolute: %q", path)
+	}
+	return l.startFileLogStream(streamID, "file", filepath.Clean(path), callback, setup)
+}
+
+// startFileLogStream starts tailing path; kind names the stream in logs
+func (l *LogStreamer) startFileLogStream(streamID, kind, path string, callback LogCallback, setup StreamSetup) error {
+	if err := validateID("stream", streamID); err != nil {
+		return err
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if _, exists := l.streams[streamID]; exists {
+		return fmt.Errorf("%w: %s", ErrStreamExists, streamID)
+	}
+
+	l.logger.Info("Starting %s log stream: %s (%s)", kind, streamID, path)
+
+	// Create the stream
+	stream := &LogStream{
+		ID:         streamID,
+		StreamType: LogStreamTypeFile,
+		callback:   untagged(callback),
+		done:       make(chan struct{}),
+		seq:        l.resumeSeq(streamID),
+	}
+	setup.apply(stream)
+
+	// Start tailing the log file
+	if err := l.startFileStream(context.Background(), stream, path); err != nil {
+		return err
+	}
+
+	l.streams[streamID] = stream
+	return nil
+}
+
+// StartCommandStream runs name with args and streams its stdout and stderr
+// until it exits or the stream is stopped, like the journalctl streams. name
+// must have been allowed with SetAllowedCommands.
+func (l *LogStreamer) StartCommandStream(streamID string, name string, args []string, callback LogCallback, setup StreamSetup) error {
+	if err := validateID("stream", streamID); err != nil {
+		return err
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if _, exists := l.streams[streamID]; exists {
+		return fmt.Errorf("%w: %s", ErrStreamExists, streamID)
+	}
+	if !l.allowed[name] {
+		return fmt.Errorf("%w: %q", ErrCommandNotAllowed, name)
+	}
+
+	l.logger.Info("Starting command stream: %s (%s)", streamID, name)
+
+	stream := &LogStream{
+		ID:         streamID,
+		StreamType: LogStreamTypeCommand,
+		callback:   untagged(callback),
+		done:       make(chan struct{}),
+		seq:        l.resumeSeq(streamID),
+	}
+	setup.apply(stream)
+
+	if err := l.startCommandStream(stream, name, args...); err != nil {
+		return err
+	}
+
+	l.streams[streamID] = stream
+	return nil
+}
+
+// StartEarlyLogStream starts streaming best-effort early boot logs
+// Prefers journalctl -b, falls back to dmesg -w
+func (l *LogStreamer) StartEarlyLogStream(streamID string, callback LogCallback) error {
+	return l.StartEarlyLogStreamWithOptions(streamID, JournalOptions{}, callback, StreamSetup{})
+}
+
+// StartEarlyLogStreamWithOptions starts streaming early boot logs using opts for journalctl
+func (l *LogStreamer) StartEarlyLogStreamWithOptions(streamID string, opts JournalOptions, callback LogCallback, setup StreamSetup) error {
+	return l.startEarlyLogStream(streamID, opts, untagged(callback), setup)
+}
+
+// StartEarlyLogStreamTagged is StartEarlyLogStreamWithOptions with each line
+// tagged by the output it was read from
+func (l *LogStreamer) StartEarlyLogStreamTagged(streamID string, opts JournalOptions, callback TaggedLogCallback) error {
+	return l.startEarlyLogStream(streamID, opts, callback, StreamSetup{})
+}
+
+// startEarlyLogStream starts an early boot stream delivering to callback
+func (l *LogStreamer) startEarlyLogStream(streamID string, opts JournalOptions, callback TaggedLogCallback, setup StreamSetup) error {
+	if err := validateID("stream", streamID); err != nil {
+		return err
+	}
+	args, err := opts.args()
+	if err != nil {
 		return err
 	}
-
-	l.streams[streamID] = stream
-	return nil
+
+	// Always a single boot; args carries -b <n> when one was chosen
+	if opts.Boot == 0 {
+		args = append([]string{"-b"}, args...)
+	}
+	probe, err := l.probeJournal(streamID, args)
+	if err != nil {
+		return err
+	}
+	journalErr := ErrNoLogBackend
+	if probe.available {
+		journalErr = probe.access
+	}
+	var fallback dmesgProbe
+	if journalErr != nil {
+		fallback = l.probeDmesg(probe.source)
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if _, exists := l.streams[streamID]; exists {
+		return fmt.Errorf("%w: %s", ErrStreamExists, streamID)
+	}
+
+	l.logger.Info("Starting early log stream: %s", streamID)
+
+	stream := &LogStream{
+		ID:         streamID,
+		StreamType: LogStreamTypeCommand,
+		callback:   callback,
+		done:       make(chan struct{}),
+		seq:        l.resumeSeq(streamID),
+	}
+	setup.apply(stream)
+
+	if journalErr == nil {
+		journalErr = l.startJournalStream(stream, probe, args, "-f")
+	}
+	if journalErr != nil {
+		l.logger.Warn("Early log stream %s: journalctl unavailable: %v", streamID, journalErr)
+		if err := l.startEarlyFallback(stream, fallback); err != nil {
+			return err
+		}
+	}
+
+	l.streams[streamID] = stream
+	return nil
+}
+
+// dmesgProbe is what the early stream learned about dmesg before taking
+// l.mu, since running it can be slow
+type dmesgProbe struct {
+	source    LogSource
+	available bool
+	follows   bool   // supports -w
+	output    string // the first read, made when it does not follow
+	err       error  // why that read failed
+}
+
+// probeDmesg checks dmesg for startEarlyFallback; l.mu must not be held
+func (l *LogStreamer) probeDmesg(source LogSource) dmesgProbe {
+	probe := dmesgProbe{source: source, available: source.Available("dmesg")}
+	if !probe.available {
+		return probe
+	}
+	probe.follows = l.dmesgFollows(source)
+	if !probe.follows {
+		probe.output, probe.err = readDmesg(source)
+	}
+	return probe
+}
+
+// startEarlyFallback tries, in order, dmesg -w, polling dmesg and the
+// syslog files, logging each attempt, and returns ErrNoLogBackend if none
+// of them starts
+func (l *LogStreamer) startEarlyFallback(stream *LogStream, dmesg dmesgProbe) error {
+	if dmesg.available {
+		if dmesg.follows {
+			err := l.startCommandStream(stream, "dmesg", "-w")
+			if err == nil {
+				l.logger.Info("Early log stream %s: following dmesg -w", stream.ID)
+				return nil
+			}
+			l.logger.Warn("Early log stream %s: dmesg -w failed: %v", stream.ID, err)
+		} else {
+			l.logger.Warn("Early log stream %s: dmesg does not support -w", stream.ID)
+		}
+
+		// Without a first read (dmesg -w failed to start), the first poll
+		// delivers the whole buffer
+		if dmesg.err == nil {
+			l.startDmesgPoll(stream, dmesg.source, dmesg.output)
+			l.logger.Info("Early log stream %s: polling dmesg every %v", stream.ID, dmesgPollInterval)
+			return nil
+		}
+		l.logger.Warn("Early log stream %s: dmesg failed: %v", stream.ID, dmesg.err)
+	} else {
+		l.logger.Warn("Early log stream %s: dmesg not available", stream.ID)
+	}
+
+	if err := l.startFallbackStream(stream, false); err != nil {
+		l.logger.Warn("Early log stream %s: no syslog file to tail", stream.ID)
+		return err
+	}
+	return nil
+}
+
+// dmesgPollInterval is how often dmesg is re-run when it cannot follow
+const dmesgPollInterval = 2 * time.Second
+
+// dmesgFollows reports whether source's dmesg supports -w. BusyBox's dmesg
+// does not, and rejecting it only after starting would end the stream at
+// once, so the usage text is checked first, once per source. l.mu must not
+// be held.
+func (l *LogStreamer) dmesgFollows(source LogSource) bool {
+	l.mu.Lock()
+	cached := l.dmesgFollow
+	l.mu.Unlock()
+	if cached != nil {
+		return *cached
+	}
+
+	// util-linux lists "-w, --follow"; BusyBox mentions neither
+	stdout, stderr, _ := runOutput(source, "dmesg", "--help")
+	usage := stdout + stderr
+	follows := strings.Contains(usage, "--follow") || strings.Contains(usage, "-w,")
+
+	l.mu.Lock()
+	l.dmesgFollow = &follows
+	l.mu.Unlock()
+	return follows
+}
+
+// readDmesg returns the kernel ring buffer, or dmesg's first error line
+func readDmesg(source LogSource) (string, error) {
+	output, stderr, err := runOutput(source, "dmesg")
+	if err != nil {
+		if message := strings.TrimSpace(stderr); message != "" {
+			return "", errors.New(firstLine(message))
+		}
+		return "", err
+	}
+	return output, nil
+}
+
+// startDmesgPoll streams the kernel ring buffer by running dmesg every
+// dmesgPollInterval and delivering the lines after the last one seen,
+// starting with output, the caller's first read. Failures are logged and
+// retried.
+func (l *LogStreamer) startDmesgPoll(stream *LogStream, source LogSource, output string) {
+	stream.readers.Add(1)
+	go func() {
+		defer stream.readers.Done()
+
+		last := ""
+		for {
+			var limited bool
+			if last, limited = deliverNewLines(stream, output, last); limited {
+				l.stopAtLimit(stream)
+				return
+			}
+
+			select {
+			case <-stream.done:
+				return
+			case <-time.After(dmesgPollInterval):
+			}
+
+			var err error
+			if output, err = readDmesg(source); err != nil {
+				l.logger.Warn("dmesg failed for stream %s: %v", stream.ID, err)
+			}
+		}
+	}()
+}
+
+// deliverNewLines delivers the lines of output that follow last, the final
+// line of the previous run, or every line if last is no longer present (the
+// ring buffer wrapped). It returns the new final line and whether the
+// stream's limits were reached.
+func deliverNewLines(stream *LogStream, output, last string) (string, bool) {
+	lines := strings.Split(strings.TrimRight(output, "\n"), "\n")
+	start := 0
+	if last != "" {
+		for i := len(lines) - 1; i >= 0; i-- {
+			if lines[i] == last {
+				start = i + 1
+				break
+			}
+		}
+	}
+
+	for _, line := range lines[start:] {
+		if line == "" {
+			continue
+		}
+		stream.mu.Lock()
+		stopped := stream.stopped
+		stream.mu.Unlock()
+		if stopped {
+			return last, false
+		}
+		last = line
+		if stream.deliver(OutputStdout, line) {
+			return last, true
+		}
+	}
+	return last, false
 }
 
-// StartAppLogStream starts streaming the application log file
-// This tails /tmp/strux-backend.log where the user's Go app output is written
-func (l *LogStreamer) StartAppLogStream(streamID string, callback LogCallback) error {
-	l.mu.Lock()
-	defer l.mu.Unlock()
-
-	if _, exists := l.streams[streamID]; exists {
-		return fmt.Errorf("stream %s already exists", streamID)
+// startFallbackStream streams from a syslog file, or dmesg when allowed,
+// for systems without journalctl
+func (l *LogStreamer) startFallbackStream(stream *LogStream, allowDmesg bool) error {
+	for _, path := range syslogPaths {
+		if fileExists(path) {
+			l.logger.Warn("journalctl not available, tailing %s for stream %s", path, stream.ID)
+			stream.StreamType = LogStreamTypeFile
+			return l.startFileStream(context.Background(), stream, path)
+		}
 	}
 
-	l.logger.Info("Starting app log stream: %s", streamID)
-
-	// Create the stream
-	stream := &LogStream{
-		ID:         streamID,
-		StreamType: LogStreamTypeFile,
-		callback:   callback,
-		done:       make(chan struct{}),
+	if allowDmesg {
+		if l.source.Available("dmesg") {
+			l.logger.Warn("journalctl not available, using dmesg for stream %s", stream.ID)
+			return l.startCommandStream(stream, "dmesg", "-w")
+		}
 	}
 
-	// Start tailing the log file
-	if err := l.startFileStream(stream, "/tmp/strux-backend.log"); err != nil {
-		return err
-	}
+	return ErrNoLogBackend
+}
 
-	l.streams[streamID] = stream
-	return nil
+// journalProbe is what a journal stream learned before taking l.mu, since
+// journalctl can be slow to answer on a large or busy journal
+type journalProbe struct {
+	source    LogSource
+	available bool  // journalctl exists
+	access    error // why it cannot read the selected journal, if it can't
 }
 
-// StartCageLogStream starts streaming the Cage compositor log file
-// This tails /tmp/strux-cage.log where Cage/Cog output is written
-func (l *LogStreamer) StartCageLogStream(streamID string, callback LogCallback) error {
+// probeJournal fails if streamID is taken, and otherwise checks whether
+// journalctl exists and can read the journal selected by args. l.mu must not
+// be held; callers check streamID again once they take it.
+func (l *LogStreamer) probeJournal(streamID string, args []string) (journalProbe, error) {
 	l.mu.Lock()
-	defer l.mu.Unlock()
+	_, exists := l.streams[streamID]
+	probe := journalProbe{source: l.source}
+	l.mu.Unlock()
 
-	if _, exists := l.streams[streamID]; exists {
-		return fmt.Errorf("stream %s already exists", streamID)
+	if exists {
+		return probe, fmt.Errorf("%w: %s", ErrStreamExists, streamID)
 	}
-
-	l.logger.Info("Starting cage log stream: %s", streamID)
-
-	// Create the stream
-	stream := &LogStream{
-		ID:         streamID,
-		StreamType: LogStreamTypeFile,
-		callback:   callback,
-		done:       make(chan struct{}),
+	probe.available = probe.source.Available("journalctl")
+	if probe.available {
+		probe.access = checkJournalAccess(probe.source, args)
 	}
+	return probe, nil
+}
 
-	// Start tailing the log file
-	if err := l.startFileStream(stream, "/tmp/strux-cage.log"); err != nil {
-		return err
+// startJournalStream streams journalctl with extra followed by args, unless
+// probe found it cannot read that journal. Without the check, a permission
+// problem would only show up as an empty stream.
+func (l *LogStreamer) startJournalStream(stream *LogStream, probe journalProbe, args []string, extra ...string) error {
+	if probe.access != nil {
+		return probe.access
 	}
-
-	l.streams[streamID] = stream
-	return nil
+	return l.startCommandStream(stream, "journalctl", append(extra, args...)...)
 }
 
-// StartEarlyLogStream starts streaming best-effort early boot logs
-// Prefers journalctl -b, falls back to dmesg -w
-func (l *LogStreamer) StartEarlyLogStream(streamID string, callback LogCallback) error {
-	l.mu.Lock()
-	defer l.mu.Unlock()
-
-	if _, exists := l.streams[streamID]; exists {
-		return fmt.Errorf("stream %s already exists", streamID)
+// checkJournalAccess runs journalctl with args and -n 0, which prints no
+// entries but reports the same access problems the stream would hit
+func checkJournalAccess(source LogSource, args []string) error {
+	proc, err := source.Start("journalctl", append([]string{"-n", "0"}, args...)...)
+	if err != nil {
+		return fmt.Errorf("failed to start command: %w", err)
 	}
 
-	l.logger.Info("Starting early log stream: %s", streamID)
+	var stderr strings.Builder
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		io.Copy(io.Discard, proc.Stdout())
+	}()
+	io.Copy(&stderr, proc.Stderr())
+	wg.Wait()
+	waitErr := proc.Wait()
 
-	cmd := exec.Command("journalctl", "-b", "-f", "--no-pager", "-o", "short-precise")
-	stream := &LogStream{
-		ID:         streamID,
-		StreamType: LogStreamTypeCommand,
-		cmd:        cmd,
-		callback:   callback,
-		done:       make(chan struct{}),
+	message := strings.TrimSpace(stderr.String())
+	if strings.Contains(message, "insufficient permissions") || strings.Contains(strings.ToLower(message), "permission denied") {
+		return fmt.Errorf("%w: %s", ErrJournalPermission, firstLine(message))
 	}
-
-	if err := l.startCommandStream(stream); err != nil {
-		l.logger.Warn("journalctl not available, falling back to dmesg: %v", err)
-		cmd = exec.Command("dmesg", "-w")
-		stream.cmd = cmd
-		if err := l.startCommandStream(stream); err != nil {
-			return err
+	if waitErr != nil {
+		if message == "" {
+			return fmt.Errorf("journalctl failed: %w", waitErr)
 		}
+		return fmt.Errorf("journalctl failed: %s", firstLine(message))
 	}
-
-	l.streams[streamID] = stream
 	return nil
 }
 
-// startCommandStream starts a command and reads its output
-func (l *LogStreamer) startCommandStream(stream *LogStream) error {
-	// Get stdout pipe
-	stdout, err := stream.cmd.StdoutPipe()
-	if err != nil {
-		return fmt.Errorf("failed to get stdout pipe: %w", err)
+// firstLine returns s up to its first newline
+func firstLine(s string) string {
+	if i := strings.IndexByte(s, '\n'); i >= 0 {
+		return s[:i]
 	}
+	return s
+}
 
-	// Also capture stderr
-	stderr, err := stream.cmd.StderrPipe()
+// startCommandStream starts a command through the stream source and reads its output
+func (l *LogStreamer) startCommandStream(stream *LogStream, name string, args ...string) error {
+	proc, err := l.source.Start(name, args...)
 	if err != nil {
-		return fmt.Errorf("failed to get stderr pipe: %w", err)
-	}
-
-	// Start the command
-	if err := stream.cmd.Start(); err != nil {
 		return fmt.Errorf("failed to start command: %w", err)
 	}
+	stream.proc = proc
 
-	// Read stdout in a goroutine
-	go l.readPipe(stream, stdout)
-
-	// Read stderr in a goroutine
-	go l.readPipe(stream, stderr)
+	// Read stdout and stderr in goroutines
+	stream.readers.Add(2)
+	go func() {
+		defer stream.readers.Done()
+		l.readPipe(stream, proc.Stdout(), OutputStdout)
+	}()
+	go func() {
+		defer stream.readers.Done()
+		l.readPipe(stream, proc.Stderr(), OutputStderr)
+	}()
 
-	// Wait for command in background and cleanup
+	// Wait for command in background and cleanup.
+	// Readers must drain the pipes before Wait closes them, and cleanup only
+	// happens once every buffered line has been delivered.
 	go func() {
-		stream.cmd.Wait()
-		// Give readers a moment to finish
-		time.Sleep(100 * time.Millisecond)
-		l.cleanupStream(stream.ID)
+		stream.readers.Wait()
+		proc.Wait()
+		proc.Close()
+		l.endStream(stream)
 	}()
 
 	return nil
 }
 
-// startFileStream starts tailing a log file
-func (l *LogStreamer) startFileStream(stream *LogStream, filePath string) error {
+// fileWaitTimeout bounds how long file streams wait for their file to appear
+const fileWaitTimeout = 60 * time.Second
+
+// DefaultFileWaitBackoff spaces out the checks for a file stream's file
+var DefaultFileWaitBackoff = Backoff{Base: 100 * time.Millisecond, Max: 2 * time.Second, Jitter: 0.2}
+
+// errFileWaitExhausted is returned by waitForFile when the backoff runs out of attempts
+var errFileWaitExhausted = errors.New("gave up waiting for file")
+
+// startFileStream starts tailing a log file, or reads it once if it is gzip-compressed.
+// Cancelling ctx, like stopping the stream, abandons the wait for the file.
+func (l *LogStreamer) startFileStream(ctx context.Context, stream *LogStream, filePath string) error {
 	// Wait for the file to exist (it may not exist immediately on boot)
+	stream.readers.Add(1)
 	go func() {
-		maxWait := 60 * time.Second
-		waitInterval := 500 * time.Millisecond
-		elapsed := time.Duration(0)
+		defer stream.readers.Done()
 
-		for elapsed < maxWait {
-			select {
-			case <-stream.done:
-				return
-			default:
-			}
+		ctx, cancel := context.WithTimeout(ctx, fileWaitTimeout)
+		defer cancel()
+		l.mu.Lock()
+		backoff := l.fileWait
+		l.mu.Unlock()
 
-			// Check if file exists
-			if _, err := os.Stat(filePath); err == nil {
-				break
+		if err := waitForFile(ctx, stream.done, filePath, backoff); err != nil {
+			if errors.Is(err, context.Canceled) {
+				return
 			}
-
-			time.Sleep(waitInterval)
-			elapsed += waitInterval
+			// Timed out; the open below reports the missing file
 		}
 
 		// Check if we're still running
@@ -292,6 +1345,12 @@ func (l *LogStreamer) startFileStream(stream *LogStream, filePath string) error
 		stream.file = file
 		stream.mu.Unlock()
 
+		// Rotated, compressed logs never grow, so read them once instead of tailing
+		if strings.HasSuffix(filePath, ".gz") {
+			l.readCompressedFile(stream, file)
+			return
+		}
+
 		// Seek to end of file (we only want new content)
 		file.Seek(0, io.SeekEnd)
 
@@ -302,8 +1361,33 @@ func (l *LogStreamer) startFileStream(stream *LogStream, filePath string) error
 	return nil
 }
 
-// readPipe reads from a pipe and calls the callback for each line
-func (l *LogStreamer) readPipe(stream *LogStream, pipe io.ReadCloser) {
+// waitForFile checks for path with backoff between checks. It returns nil
+// once the file is there, ctx.Err() when ctx ends first, context.Canceled
+// when done is closed, and errFileWaitExhausted after backoff.MaxAttempts.
+func waitForFile(ctx context.Context, done <-chan struct{}, path string, backoff Backoff) error {
+	for attempt := 0; ; attempt++ {
+		if _, err := os.Stat(path); err == nil {
+			return nil
+		}
+		if backoff.Exhausted(attempt) {
+			return errFileWaitExhausted
+		}
+
+		timer := time.NewTimer(backoff.Delay(attempt))
+		select {
+		case <-done:
+			timer.Stop()
+			return context.Canceled
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// readPipe reads from a pipe and calls the callback for each line, tagged with tag
+func (l *LogStreamer) readPipe(stream *LogStream, pipe io.Reader, tag string) {
 	// Use a larger buffer for long lines (1MB)
 	scanner := bufio.NewScanner(pipe)
 	buf := make([]byte, 0, 64*1024)
@@ -330,21 +1414,41 @@ func (l *LogStreamer) readPipe(stream *LogStream, pipe io.ReadCloser) {
 			if stopped {
 				return
 			}
-			stream.callback(line)
+			if stream.deliver(tag, line) {
+				l.stopAtLimit(stream)
+				return
+			}
 		}
 	}
 
-	if err := scanner.Err(); err != nil {
+	// Stopping closes the pipe under the scanner; that is not worth reporting
+	if err := scanner.Err(); err != nil && !errors.Is(err, os.ErrClosed) {
 		l.logger.Error("Scanner error for stream %s: %v", stream.ID, err)
 	}
 }
 
+// readCompressedFile streams every line of a gzip-compressed file, then ends the stream
+func (l *LogStreamer) readCompressedFile(stream *LogStream, file *os.File) {
+	defer file.Close()
+
+	gz, err := gzip.NewReader(file)
+	if err != nil {
+		l.logger.Error("Failed to open compressed log file %s: %v", file.Name(), err)
+		return
+	}
+	defer gz.Close()
+
+	l.readPipe(stream, gz, OutputStdout)
+	l.endStream(stream)
+}
+
 // tailFile continuously reads new content from a file
 func (l *LogStreamer) tailFile(stream *LogStream, file *os.File) {
 	defer file.Close()
 
 	reader := bufio.NewReader(file)
-	pollInterval := 100 * time.Millisecond
+	wait, release := l.tailWaiter(stream, file.Name())
+	defer release()
 
 	for {
 		select {
@@ -360,7 +1464,7 @@ func (l *LogStreamer) tailFile(stream *LogStream, file *os.File) {
 				return
 			}
 			// EOF - wait for more content
-			time.Sleep(pollInterval)
+			wait()
 			continue
 		}
 
@@ -376,16 +1480,101 @@ func (l *LogStreamer) tailFile(stream *LogStream, file *os.File) {
 			if stopped {
 				return
 			}
-			stream.callback(line)
+			if stream.deliver(OutputStdout, line) {
+				l.stopAtLimit(stream)
+				return
+			}
+		}
+	}
+}
+
+// tailWaiter returns how tailFile waits at the end of the file: on an
+// inotify watch where available, else by waiting out the poll interval.
+// Stopping the stream wakes a pending wait either way; the watch is closed
+// by release or when the stream stops.
+func (l *LogStreamer) tailWaiter(stream *LogStream, path string) (wait func(), release func()) {
+	l.mu.Lock()
+	pollInterval := l.pollInterval
+	l.mu.Unlock()
+
+	watcher, err := newFileWatcher(path)
+	if err != nil {
+		l.logger.Info("Polling %s every %v: %v", path, pollInterval, err)
+		wait = func() {
+			select {
+			case <-stream.done:
+			case <-time.After(pollInterval):
+			}
+		}
+		return wait, func() {}
+	}
+
+	released := make(chan struct{})
+	go func() {
+		select {
+		case <-stream.done:
+		case <-released:
+		}
+		watcher.Close()
+	}()
+	release = func() { close(released) }
+	wait = func() {
+		if err := watcher.Wait(inotifyRecheckInterval); err != nil {
+			// Closed by Stop, or broken; keep the tail loop from spinning
+			select {
+			case <-stream.done:
+			case <-time.After(pollInterval):
+			}
 		}
 	}
+	return wait, release
 }
 
-// cleanupStream removes a stream from the map
-func (l *LogStreamer) cleanupStream(streamID string) {
+// endStream finishes a stream whose source ran out. onComplete only fires
+// if the stream was not stopped (by Stop or by reaching its limits).
+func (l *LogStreamer) endStream(stream *LogStream) {
+	stream.flushPending()
+	l.cleanupStream(stream)
+
+	stream.mu.Lock()
+	stopped := stream.stopped
+	stream.mu.Unlock()
+	if !stopped {
+		l.mu.Lock()
+		stream.mu.Lock()
+		if stream.seq > 0 {
+			l.lastSeq[stream.ID] = stream.seq
+		}
+		stream.mu.Unlock()
+		l.mu.Unlock()
+		stream.complete(StreamEndExited)
+	}
+}
+
+// resumeSeq returns the sequence number a new stream with streamID continues
+// from: where the previous stream with that ID ended by itself, or 0.
+// Callers hold l.mu.
+func (l *LogStreamer) resumeSeq(streamID string) uint64 {
+	seq := l.lastSeq[streamID]
+	delete(l.lastSeq, streamID)
+	return seq
+}
+
+// stopAtLimit stops a stream that reached its limits and reports it as complete
+func (l *LogStreamer) stopAtLimit(stream *LogStream) {
+	l.logger.Info("Stream %s reached its limit, stopping", stream.ID)
+	l.cleanupStream(stream)
+	l.stopStream(stream)
+	stream.complete(StreamEndLimit)
+}
+
+// cleanupStream removes a stream from the map, unless its ID has since been reused
+func (l *LogStreamer) cleanupStream(stream *LogStream) {
 	l.mu.Lock()
 	defer l.mu.Unlock()
-	delete(l.streams, streamID)
+	if l.streams[stream.ID] == stream {
+		delete(l.streams, stream.ID)
+	}
 }
 
 // Stop stops a specific log stream
@@ -401,24 +1590,52 @@ func (l *LogStreamer) Stop(streamID string) {
 	l.mu.Unlock()
 
 	l.logger.Info("Stopping stream: %s", streamID)
+	l.stopStream(stream)
+}
 
-	// Mark as stopped first
+// stopStream signals a stream's goroutines, releases its process or file
+// and flushes any output its callback is still holding
+func (l *LogStreamer) stopStream(stream *LogStream) {
+	// Mark as stopped first; a stream stopped at its limit may be stopped again
 	stream.mu.Lock()
+	if stream.stopped {
+		stream.mu.Unlock()
+		return
+	}
 	stream.stopped = true
 	stream.mu.Unlock()
 
 	// Close the done channel to signal goroutines
 	close(stream.done)
 
-	// Kill the process if it's a command stream
-	if stream.cmd != nil && stream.cmd.Process != nil {
-		stream.cmd.Process.Kill()
+	// Kill the process if it's a command stream, and close its pipes so the
+	// readers return now rather than when every holder of the pipes exits
+	if stream.proc != nil {
+		stream.proc.Kill()
+		stream.proc.Close()
 	}
 
 	// Close the file if it's a file stream
 	if stream.file != nil {
 		stream.file.Close()
 	}
+
+	// Deliver whatever was read but not yet passed on
+	stream.flushPending()
+}
+
+// StopAndWait stops a specific log stream and blocks until its reader
+// goroutines have exited, so no further callbacks will be made.
+// Callbacks that block (such as an undrained ChannelCallback) will block this call too.
+func (l *LogStreamer) StopAndWait(streamID string) {
+	l.mu.Lock()
+	stream, exists := l.streams[streamID]
+	l.mu.Unlock()
+
+	l.Stop(streamID)
+	if exists {
+		stream.readers.Wait()
+	}
 }
 
 // StopAll stops all active log streams
@@ -438,19 +1655,188 @@ func (l *LogStreamer) StopAll() {
 
 	for i, stream := range streams {
 		l.logger.Info("Stopping stream: %s", ids[i])
+		l.stopStream(stream)
+	}
+}
 
-		stream.mu.Lock()
-		stream.stopped = true
-		stream.mu.Unlock()
+// SetFlush registers a function that delivers output the stream's callback is
+// still buffering, such as a LogCoalescer's pending entry. It runs when the
+// stream is stopped and when its command exits; a stream that already ended
+// is not found, so set StreamSetup.Flush when starting it instead.
+func (l *LogStreamer) SetFlush(streamID string, flush func()) error {
+	l.mu.Lock()
+	stream, exists := l.streams[streamID]
+	l.mu.Unlock()
 
-		close(stream.done)
-		if stream.cmd != nil && stream.cmd.Process != nil {
-			stream.cmd.Process.Kill()
-		}
-		if stream.file != nil {
-			stream.file.Close()
+	if !exists {
+		return fmt.Errorf("%w: %s", ErrStreamNotFound, streamID)
+	}
+
+	stream.mu.Lock()
+	stream.flush = flush
+	stream.mu.Unlock()
+	return nil
+}
+
+// SetSequenced delivers the stream's lines to callback with their sequence
+// numbers instead of to the stream's LogCallback. Lines delivered before the
+// call went to the LogCallback but still used up their numbers; set
+// StreamSetup.Sequenced when starting the stream to number every line.
+func (l *LogStreamer) SetSequenced(streamID string, callback SequencedLogCallback) error {
+	l.mu.Lock()
+	stream, exists := l.streams[streamID]
+	l.mu.Unlock()
+
+	if !exists {
+		return fmt.Errorf("%w: %s", ErrStreamNotFound, streamID)
+	}
+
+	stream.mu.Lock()
+	stream.seqCallback = callback
+	stream.mu.Unlock()
+	return nil
+}
+
+// SetLimits bounds how many lines or bytes a stream delivers. When either limit
+// is reached the stream stops itself and onComplete is called with
+// StreamEndLimit; if its command exits first, onComplete gets StreamEndExited.
+// onComplete is not called for streams ended with Stop. Lines delivered before
+// SetLimits count toward the limits, but a stream that already ended is not
+// found; set StreamSetup.Limits when starting the stream to bound every line.
+func (l *LogStreamer) SetLimits(streamID string, limits StreamLimits, onComplete func(StreamEndReason)) error {
+	l.mu.Lock()
+	stream, exists := l.streams[streamID]
+	l.mu.Unlock()
+
+	if !exists {
+		return fmt.Errorf("%w: %s", ErrStreamNotFound, streamID)
+	}
+
+	stream.mu.Lock()
+	stream.limits = limits
+	stream.onComplete = onComplete
+	stream.mu.Unlock()
+	return nil
+}
+
+// SetRecentSize changes how many lines a stream retains for GetRecent.
+// Lines already buffered are kept up to the new size.
+func (l *LogStreamer) SetRecentSize(streamID string, size int) error {
+	l.mu.Lock()
+	stream, exists := l.streams[streamID]
+	l.mu.Unlock()
+
+	if !exists {
+		return fmt.Errorf("%w: %s", ErrStreamNotFound, streamID)
+	}
+
+	if size < 0 {
+		size = 0
+	}
+
+	stream.mu.Lock()
+	defer stream.mu.Unlock()
+
+	resized := newLineRing(size)
+	if stream.recent != nil {
+		for _, line := range stream.recent.last(size) {
+			resized.add(line)
 		}
 	}
+	stream.recent = resized
+	return nil
+}
+
+// GetRecent returns up to n of the most recent lines delivered on a stream, oldest first.
+// A non-positive n returns everything retained.
+func (l *LogStreamer) GetRecent(streamID string, n int) []string {
+	l.mu.Lock()
+	stream, exists := l.streams[streamID]
+	l.mu.Unlock()
+
+	if !exists {
+		return nil
+	}
+
+	stream.mu.Lock()
+	defer stream.mu.Unlock()
+
+	if stream.recent == nil {
+		return []string{}
+	}
+	return stream.recent.last(n)
+}
+
+// StreamStats describes an active stream and what it has delivered
+type StreamStats struct {
+	ID      string `json:"id"`
+	Type    string `json:"type"` // "command" or "file"
+	Service string `json:"service,omitempty"`
+	Lines   int    `json:"lines"`
+	Bytes   int64  `json:"bytes"`
+	Paused  bool   `json:"paused"`
+}
+
+// Stats returns the stream's current StreamStats
+func (l *LogStreamer) Stats(streamID string) (StreamStats, error) {
+	l.mu.Lock()
+	stream, exists := l.streams[streamID]
+	l.mu.Unlock()
+
+	if !exists {
+		return StreamStats{}, fmt.Errorf("%w: %s", ErrStreamNotFound, streamID)
+	}
+
+	stream.mu.Lock()
+	defer stream.mu.Unlock()
+	return StreamStats{
+		ID:      stream.ID,
+		Type:    stream.StreamType.String(),
+		Service: stream.Service,
+		Lines:   stream.lines,
+		Bytes:   stream.bytes,
+		Paused:  stream.paused,
+	}, nil
+}
+
+// Pause stops delivering a stream's lines without stopping the stream. Its
+// readers block on the next line, so a command's output backs up in its pipe
+// and a tailed file is simply read later. Pausing a paused stream is a no-op.
+func (l *LogStreamer) Pause(streamID string) error {
+	l.mu.Lock()
+	stream, exists := l.streams[streamID]
+	l.mu.Unlock()
+
+	if !exists {
+		return fmt.Errorf("%w: %s", ErrStreamNotFound, streamID)
+	}
+
+	stream.mu.Lock()
+	defer stream.mu.Unlock()
+	if !stream.paused {
+		stream.paused = true
+		stream.resumed = make(chan struct{})
+	}
+	return nil
+}
+
+// Resume continues delivering a paused stream's lines
+func (l *LogStreamer) Resume(streamID string) error {
+	l.mu.Lock()
+	stream, exists := l.streams[streamID]
+	l.mu.Unlock()
+
+	if !exists {
+		return fmt.Errorf("%w: %s", ErrStreamNotFound, streamID)
+	}
+
+	stream.mu.Lock()
+	defer stream.mu.Unlock()
+	if stream.paused {
+		stream.paused = false
+		close(stream.resumed)
+	}
+	return nil
 }
 
 // GetActiveStreams returns the IDs of all active streams
@@ -0,0 +1,542 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+// newTestStreamer returns a LogStreamer running commands through source
+func newTestStreamer(source *fakeSource) *LogStreamer {
+	l := NewLogStreamer()
+	l.SetSource(source)
+	return l
+}
+
+// within fails the test if fn does not return in a few seconds
+func within(t *testing.T, what string, fn func()) {
+	t.Helper()
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		fn()
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("%s did not return", what)
+	}
+}
+
+func TestSlowJournalProbeDoesNotBlockStreamer(t *testing.T) {
+	gate := make(chan struct{})
+	source := &fakeSource{script: func(name string, args []string) fakeRun {
+		if len(args) > 0 && args[0] == "-n" {
+			return fakeRun{gate: gate} // the access check hangs until released
+		}
+		return fakeRun{stdout: "line\n", follow: true}
+	}}
+	l := newTestStreamer(source)
+	t.Cleanup(l.StopAll)
+	release := sync.OnceFunc(func() { close(gate) })
+	t.Cleanup(release) // runs before StopAll, which a failure would leave blocked
+
+	started := make(chan error, 1)
+	go func() {
+		started <- l.StartJournalctlStream("journal", func(string) {})
+	}()
+	waitFor(t, "the access check", func() bool { return len(source.starts()) == 1 })
+
+	// Every other LogStreamer call goes through l.mu
+	within(t, "GetActiveStreams", func() { l.GetActiveStreams() })
+	within(t, "Stats", func() { l.Stats("journal") })
+	within(t, "Stop", func() { l.Stop("other") })
+
+	release()
+	if err := <-started; err != nil {
+		t.Fatalf("StartJournalctlStream: %v", err)
+	}
+}
+
+// waitFor polls cond until it holds or a few seconds pass
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestEarlyStreamPicksDmesgMode(t *testing.T) {
+	tests := []struct {
+		name  string
+		usage string
+		want  string // the command that streams
+	}{
+		{"util-linux", "Usage:\n dmesg [options]\n\n -w, --follow                wait for new messages\n", "dmesg -w"},
+		{"busybox", "BusyBox v1.36.1 multi-call binary.\n\nUsage: dmesg [-cr] [-n LEVEL] [-s SIZE]\n\nPrint or control the kernel-wide ring buffer\n", "dmesg"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			source := &fakeSource{
+				missing: map[string]bool{"journalctl": true},
+				script: func(name string, args []string) fakeRun {
+					switch {
+					case len(args) == 0:
+						return fakeRun{stdout: "[0.0] boot\n[0.1] init\n"}
+					case args[0] == "--help":
+						return fakeRun{stderr: tt.usage}
+					default:
+						return fakeRun{stdout: "[0.0] boot\n[0.1] init\n", follow: true}
+					}
+				},
+			}
+			l := newTestStreamer(source)
+			t.Cleanup(l.StopAll)
+
+			var mu sync.Mutex
+			var lines []string
+			collect := func(line string) {
+				mu.Lock()
+				defer mu.Unlock()
+				lines = append(lines, line)
+			}
+			for _, id := range []string{"early1", "early2"} {
+				if err := l.StartEarlyLogStream(id, collect); err != nil {
+					t.Fatalf("StartEarlyLogStream(%s): %v", id, err)
+				}
+			}
+			waitFor(t, "both streams' lines", func() bool {
+				mu.Lock()
+				defer mu.Unlock()
+				return len(lines) == 4
+			})
+
+			var helps, streams int
+			for _, cmd := range source.starts() {
+				switch cmd {
+				case "dmesg --help":
+					helps++
+				case tt.want:
+					streams++
+				}
+			}
+			if helps != 1 {
+				t.Errorf("dmesg --help ran %d times, want once", helps)
+			}
+			if streams != 2 {
+				t.Errorf("started %q %d times, want 2; ran %q", tt.want, streams, source.starts())
+			}
+		})
+	}
+}
+
+// lineLog collects delivered lines for a test
+type lineLog struct {
+	mu    sync.Mutex
+	lines []string
+}
+
+func (c *lineLog) add(line string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.lines = append(c.lines, line)
+}
+
+func (c *lineLog) get() []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]string(nil), c.lines...)
+}
+
+func TestServiceStreamFollowsUnit(t *testing.T) {
+	source := &fakeSource{script: func(name string, args []string) fakeRun {
+		if args[0] == "-n" {
+			return fakeRun{}
+		}
+		return fakeRun{stdout: "started\nready\n", stderr: "warning\n", follow: true}
+	}}
+	l := newTestStreamer(source)
+	t.Cleanup(l.StopAll)
+
+	var got lineLog
+	if err := l.StartServiceStream("app", "app.service", got.add); err != nil {
+		t.Fatal(err)
+	}
+	waitFor(t, "three lines", func() bool { return len(got.get()) == 3 })
+
+	cmd := "journalctl -f -u app.service --no-pager -o " + DefaultJournalFormat
+	proc := source.proc(cmd)
+	if proc == nil {
+		t.Fatalf("%q not run; ran %q", cmd, source.starts())
+	}
+	if err := l.StartServiceStream("app", "app.service", got.add); !errors.Is(err, ErrStreamExists) {
+		t.Fatalf("second start: got %v, want ErrStreamExists", err)
+	}
+
+	l.StopAndWait("app")
+	if !proc.wasKilled() {
+		t.Error("journalctl still running after StopAndWait")
+	}
+	if ids := l.GetActiveStreams(); len(ids) != 0 {
+		t.Errorf("active streams after stop: %q", ids)
+	}
+}
+
+func TestJournalPermissionErrorIsReported(t *testing.T) {
+	source := &fakeSource{script: func(name string, args []string) fakeRun {
+		return fakeRun{
+			stderr: "Hint: You are currently not seeing messages from other users and the system.\n" +
+				"No journal files were opened due to insufficient permissions.\n",
+			exit: errors.New("exit status 1"),
+		}
+	}}
+	l := newTestStreamer(source)
+	t.Cleanup(l.StopAll)
+
+	err := l.StartJournalctlStream("journal", func(string) {})
+	if !errors.Is(err, ErrJournalPermission) {
+		t.Fatalf("got %v, want ErrJournalPermission", err)
+	}
+	if ids := l.GetActiveStreams(); len(ids) != 0 {
+		t.Errorf("a failed start left streams %q", ids)
+	}
+	if starts := source.starts(); len(starts) != 1 {
+		t.Errorf("journalctl started %q after the access check failed", starts)
+	}
+}
+
+func TestCommandStreamEndsWhenCommandExits(t *testing.T) {
+	source := &fakeSource{script: func(name string, args []string) fakeRun {
+		return fakeRun{stdout: "one\ntwo\n"}
+	}}
+	l := newTestStreamer(source)
+	l.SetAllowedCommands([]string{"app-status"})
+	t.Cleanup(l.StopAll)
+
+	var got lineLog
+	if err := l.StartCommandStream("cmd", "app-status", nil, got.add, StreamSetup{}); err != nil {
+		t.Fatal(err)
+	}
+	waitFor(t, "the stream to end", func() bool { return len(l.GetActiveStreams()) == 0 })
+	if lines := got.get(); len(lines) != 2 || lines[0] != "one" || lines[1] != "two" {
+		t.Errorf("got %q", lines)
+	}
+}
+
+// endReasons records each onComplete call
+type endReasons struct {
+	mu      sync.Mutex
+	reasons []StreamEndReason
+}
+
+func (e *endReasons) add(reason StreamEndReason) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.reasons = append(e.reasons, reason)
+}
+
+func (e *endReasons) get() []StreamEndReason {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return append([]StreamEndReason(nil), e.reasons...)
+}
+
+// snapshotSource answers the access check and then prints output, following
+// it like journalctl -f when follow is set
+func snapshotSource(output string, follow bool) *fakeSource {
+	return &fakeSource{script: func(name string, args []string) fakeRun {
+		if args[0] == "-n" {
+			return fakeRun{}
+		}
+		return fakeRun{stdout: output, follow: follow}
+	}}
+}
+
+func TestSnapshotLimits(t *testing.T) {
+	tests := []struct {
+		name   string
+		follow bool
+		limits StreamLimits
+		want   int // lines delivered
+		reason StreamEndReason
+	}{
+		{"lines", true, StreamLimits{MaxLines: 2}, 2, StreamEndLimit},
+		// Each line counts its newline: 5 + 5 reaches 10
+		{"bytes", true, StreamLimits{MaxBytes: 10}, 2, StreamEndLimit},
+		{"exited first", false, StreamLimits{MaxLines: 10}, 4, StreamEndExited},
+		{"unlimited", false, StreamLimits{}, 4, StreamEndExited},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			source := snapshotSource("aaaa\nbbbb\ncccc\ndddd\n", tt.follow)
+			l := newTestStreamer(source)
+			t.Cleanup(l.StopAll)
+
+			var got lineLog
+			var ended endReasons
+			if err := l.StartJournalctlSnapshot("snap", "", JournalOptions{}, got.add, StreamSetup{Limits: tt.limits, OnComplete: ended.add}); err != nil {
+				t.Fatal(err)
+			}
+			waitFor(t, "onComplete", func() bool { return len(ended.get()) > 0 })
+
+			if lines := got.get(); len(lines) != tt.want {
+				t.Errorf("delivered %q, want %d lines", lines, tt.want)
+			}
+			if reasons := ended.get(); len(reasons) != 1 || reasons[0] != tt.reason {
+				t.Errorf("onComplete got %v, want [%v]", reasons, tt.reason)
+			}
+			waitFor(t, "the stream to be removed", func() bool { return len(l.GetActiveStreams()) == 0 })
+			if tt.reason == StreamEndLimit {
+				if proc := source.proc(source.starts()[1]); !proc.wasKilled() {
+					t.Error("journalctl still running after the limit")
+				}
+			}
+		})
+	}
+}
+
+func TestStoppedStreamDoesNotComplete(t *testing.T) {
+	l := newTestStreamer(snapshotSource("one\n", true))
+	t.Cleanup(l.StopAll)
+
+	var got lineLog
+	var ended endReasons
+	if err := l.StartJournalctlStream("journal", got.add); err != nil {
+		t.Fatal(err)
+	}
+	if err := l.SetLimits("journal", StreamLimits{MaxLines: 100}, ended.add); err != nil {
+		t.Fatal(err)
+	}
+	waitFor(t, "a line", func() bool { return len(got.get()) == 1 })
+
+	l.StopAndWait("journal")
+	time.Sleep(10 * time.Millisecond) // endStream runs after the readers
+	if reasons := ended.get(); len(reasons) != 0 {
+		t.Errorf("onComplete called with %v after Stop", reasons)
+	}
+	if err := l.SetLimits("journal", StreamLimits{}, nil); !errors.Is(err, ErrStreamNotFound) {
+		t.Errorf("SetLimits on a stopped stream: got %v, want ErrStreamNotFound", err)
+	}
+}
+
+// startGated starts a journal stream delivering to callback, whose output is
+// held until the returned func is called
+func startGated(t *testing.T, output string, callback LogCallback) (*LogStreamer, func()) {
+	t.Helper()
+	gate := make(chan struct{})
+	source := &fakeSource{script: func(name string, args []string) fakeRun {
+		if args[0] == "-n" {
+			return fakeRun{}
+		}
+		return fakeRun{stdout: output, follow: true, gate: gate}
+	}}
+	l := newTestStreamer(source)
+	t.Cleanup(l.StopAll)
+	release := sync.OnceFunc(func() { close(gate) })
+	t.Cleanup(release)
+
+	if err := l.StartJournalctlStream("journal", callback); err != nil {
+		t.Fatal(err)
+	}
+	return l, release
+}
+
+func TestGetRecent(t *testing.T) {
+	var got lineLog
+	l, release := startGated(t, "a\nb\nc\nd\ne\n", got.add)
+	release()
+	waitFor(t, "five lines", func() bool { return len(got.get()) == 5 })
+
+	tests := []struct {
+		n    int
+		want []string
+	}{
+		{2, []string{"d", "e"}},
+		{5, []string{"a", "b", "c", "d", "e"}},
+		{50, []string{"a", "b", "c", "d", "e"}},
+		// A non-positive n returns everything retained
+		{0, []string{"a", "b", "c", "d", "e"}},
+		{-1, []string{"a", "b", "c", "d", "e"}},
+	}
+	for _, tt := range tests {
+		if recent := l.GetRecent("journal", tt.n); !equalLines(recent, tt.want) {
+			t.Errorf("GetRecent(%d) = %q, want %q", tt.n, recent, tt.want)
+		}
+	}
+	if recent := l.GetRecent("missing", 0); recent != nil {
+		t.Errorf("GetRecent on a missing stream = %q, want nil", recent)
+	}
+}
+
+func TestSetRecentSize(t *testing.T) {
+	var got lineLog
+	l, release := startGated(t, "a\nb\nc\nd\ne\n", got.add)
+
+	// Set before any line arrives, so the buffer wraps
+	if err := l.SetRecentSize("journal", 3); err != nil {
+		t.Fatal(err)
+	}
+	release()
+	waitFor(t, "five lines", func() bool { return len(got.get()) == 5 })
+
+	steps := []struct {
+		size int
+		want []string
+	}{
+		{3, []string{"c", "d", "e"}},
+		{2, []string{"d", "e"}}, // shrinking keeps the newest
+		{10, []string{"d", "e"}},
+		{-4, []string{}}, // a negative size retains nothing
+		{5, []string{}},
+	}
+	for _, step := range steps {
+		if err := l.SetRecentSize("journal", step.size); err != nil {
+			t.Fatal(err)
+		}
+		if recent := l.GetRecent("journal", 0); !equalLines(recent, step.want) {
+			t.Errorf("after SetRecentSize(%d): GetRecent = %q, want %q", step.size, recent, step.want)
+		}
+	}
+	if err := l.SetRecentSize("missing", 1); !errors.Is(err, ErrStreamNotFound) {
+		t.Errorf("SetRecentSize on a missing stream: got %v, want ErrStreamNotFound", err)
+	}
+}
+
+func equalLines(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestStartStopDoesNotLeakFDs(t *testing.T) {
+	if _, err := os.ReadDir("/proc/self/fd"); err != nil {
+		t.Skip("needs /proc/self/fd")
+	}
+	if !(execLogSource{}).Available("sleep") {
+		t.Skip("needs sleep")
+	}
+	l := NewLogStreamer()
+	l.SetAllowedCommands([]string{"sleep"})
+	t.Cleanup(l.StopAll)
+
+	cycle := func() {
+		if err := l.StartCommandStream("sleep", "sleep", []string{"30"}, func(string) {}, StreamSetup{}); err != nil {
+			t.Fatal(err)
+		}
+		l.StopAndWait("sleep")
+	}
+	cycle() // let one-time descriptors open first
+	before := openFDs(t)
+	for i := 0; i < 50; i++ {
+		cycle()
+	}
+	// Process exits are reaped in the background, so allow them to finish
+	waitFor(t, "descriptors to be released", func() bool { return openFDs(t) <= before })
+}
+
+func openFDs(t *testing.T) int {
+	t.Helper()
+	entries, err := os.ReadDir("/proc/self/fd")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return len(entries)
+}
+
+func TestSequencedFromFirstLine(t *testing.T) {
+	source := &fakeSource{script: func(name string, args []string) fakeRun {
+		return fakeRun{stdout: "one\ntwo\nthree\n"}
+	}}
+	l := newTestStreamer(source)
+	l.SetAllowedCommands([]string{"app-status"})
+	t.Cleanup(l.StopAll)
+
+	var plain, numbered lineLog
+	setup := StreamSetup{Sequenced: func(seq uint64, line string) {
+		numbered.add(fmt.Sprintf("%d %s", seq, line))
+	}}
+	if err := l.StartCommandStream("cmd", "app-status", nil, plain.add, setup); err != nil {
+		t.Fatal(err)
+	}
+	waitFor(t, "the stream to end", func() bool { return len(l.GetActiveStreams()) == 0 })
+
+	if lines := plain.get(); len(lines) != 0 {
+		t.Errorf("unnumbered lines delivered: %q", lines)
+	}
+	if lines, want := numbered.get(), []string{"1 one", "2 two", "3 three"}; !equalLines(lines, want) {
+		t.Errorf("got %q, want %q", lines, want)
+	}
+}
+
+func TestLimitsApplyFromFirstLine(t *testing.T) {
+	tests := []struct {
+		name   string
+		limits StreamLimits
+		want   int // lines delivered
+		reason StreamEndReason
+	}{
+		{"limit", StreamLimits{MaxLines: 2}, 2, StreamEndLimit},
+		{"exited", StreamLimits{MaxLines: 10}, 3, StreamEndExited},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			source := &fakeSource{script: func(name string, args []string) fakeRun {
+				return fakeRun{stdout: "one\ntwo\nthree\n"}
+			}}
+			l := newTestStreamer(source)
+			l.SetAllowedCommands([]string{"app-status"})
+			t.Cleanup(l.StopAll)
+
+			var got lineLog
+			var ended endReasons
+			setup := StreamSetup{Limits: tt.limits, OnComplete: ended.add}
+			if err := l.StartCommandStream("cmd", "app-status", nil, got.add, setup); err != nil {
+				t.Fatal(err)
+			}
+			waitFor(t, "onComplete", func() bool { return len(ended.get()) > 0 })
+
+			if lines := got.get(); len(lines) != tt.want {
+				t.Errorf("delivered %q, want %d lines", lines, tt.want)
+			}
+			if reasons := ended.get(); len(reasons) != 1 || reasons[0] != tt.reason {
+				t.Errorf("onComplete got %v, want [%v]", reasons, tt.reason)
+			}
+		})
+	}
+}
+
+func TestPollingWaitEndsOnStop(t *testing.T) {
+	l := NewLogStreamer()
+	l.SetPollInterval(time.Hour)
+	stream := &LogStream{ID: "file", done: make(chan struct{})}
+
+	// A missing path has no inotify watch, so the waiter polls
+	wait, release := l.tailWaiter(stream, filepath.Join(t.TempDir(), "missing.log"))
+	defer release()
+
+	returned := make(chan struct{})
+	go func() {
+		wait()
+		close(returned)
+	}()
+	close(stream.done)
+	select {
+	case <-returned:
+	case <-time.After(5 * time.Second):
+		t.Fatal("wait still sleeping after the stream stopped")
+	}
+}
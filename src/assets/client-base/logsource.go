@@ -0,0 +1,128 @@
+//
+// Strux Client - Log Sources
+//
+// Command-based log streams (journalctl, dmesg) start their processes
+// through a LogSource rather than calling os/exec directly. The default
+// source runs real commands; a fake can be injected with
+// LogStreamer.SetSource to script output and exit behavior without the
+// tools being installed.
+//
+
+package main
+
+import (
+	"fmt"
+	"io"
+	"os/exec"
+	"strings"
+	"sync"
+)
+
+// LogProcess is a running log command
+type LogProcess interface {
+	// Stdout and Stderr are the process output streams. Both must be read
+	// to EOF before Wait is called.
+	Stdout() io.Reader
+	Stderr() io.Reader
+
+	// Wait blocks until the process exits
+	Wait() error
+
+	// Kill stops the process; its output streams then reach EOF
+	Kill() error
+
+	// Close releases the output streams. Reads blocked on them return, even
+	// if a child of the process still holds them open.
+	Close() error
+}
+
+// LogSource creates the processes behind command-based log streams
+type LogSource interface {
+	// Available reports whether the named command can be started
+	Available(name string) bool
+
+	// Start runs name with args and returns the running process
+	Start(name string, args ...string) (LogProcess, error)
+}
+
+// runOutput runs name through source to completion and returns its stdout,
+// its stderr and the error Wait returned
+func runOutput(source LogSource, name string, args ...string) (stdout, stderr string, err error) {
+	proc, err := source.Start(name, args...)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to start %s: %w", name, err)
+	}
+
+	var out, errOut strings.Builder
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		io.Copy(&errOut, proc.Stderr())
+	}()
+	io.Copy(&out, proc.Stdout())
+	wg.Wait()
+
+	return out.String(), errOut.String(), proc.Wait()
+}
+
+// execLogSource runs real commands with os/exec
+type execLogSource struct{}
+
+// Available reports whether name is on PATH. journalctl is probed once per process.
+func (execLogSource) Available(name string) bool {
+	if name == "journalctl" {
+		return HasJournalctl()
+	}
+	_, err := exec.LookPath(name)
+	return err == nil
+}
+
+// Start starts name with its stdout and stderr piped
+func (execLogSource) Start(name string, args ...string) (LogProcess, error) {
+	cmd := exec.Command(name, args...)
+
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	stderr, err := cmd.StderrPipe()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+
+	return &execLogProcess{cmd: cmd, stdout: stdout, stderr: stderr}, nil
+}
+
+// execLogProcess is a LogProcess backed by an *exec.Cmd
+type execLogProcess struct {
+	cmd    *exec.Cmd
+	stdout io.ReadCloser
+	stderr io.ReadCloser
+}
+
+func (p *execLogProcess) Stdout() io.Reader { return p.stdout }
+func (p *execLogProcess) Stderr() io.Reader { return p.stderr }
+func (p *execLogProcess) Wait() error       { return p.cmd.Wait() }
+
+func (p *execLogProcess) Kill() error {
+	if p.cmd.Process == nil {
+		return nil
+	}
+	return p.cmd.Process.Kill()
+}
+
+// Close closes the read ends of both pipes. Wait closes them too, so either
+// may run first; the second close is a harmless error.
+func (p *execLogProcess) Close() error {
+	errOut := p.stdout.Close()
+	errErr := p.stderr.Close()
+	if errOut != nil {
+		return errOut
+	}
+	return errErr
+}
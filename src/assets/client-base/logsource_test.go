@@ -0,0 +1,152 @@
+package main
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"sync"
+)
+
+// fakeRun scripts one run of a fake command
+type fakeRun struct {
+	stdout string
+	stderr string
+	exit   error // returned by Wait unless the process was killed
+
+	// follow keeps stdout open after its output until the process is
+	// killed, like journalctl -f
+	follow bool
+
+	// gate, if set, holds back all output until it is closed
+	gate chan struct{}
+}
+
+// fakeSource is a LogSource whose commands are answered by script
+type fakeSource struct {
+	missing map[string]bool                          // commands Available reports absent
+	script  func(name string, args []string) fakeRun // decides each run
+
+	mu      sync.Mutex
+	started []string       // "name arg arg" for each Start
+	procs   []*fakeProcess // in the same order
+}
+
+func (s *fakeSource) Available(name string) bool {
+	return !s.missing[name]
+}
+
+func (s *fakeSource) Start(name string, args ...string) (LogProcess, error) {
+	proc := startFakeProcess(s.script(name, args))
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.started = append(s.started, strings.TrimSpace(name+" "+strings.Join(args, " ")))
+	s.procs = append(s.procs, proc)
+	return proc, nil
+}
+
+// starts returns the commands started so far
+func (s *fakeSource) starts() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.started...)
+}
+
+// proc returns the process started for cmd, or nil
+func (s *fakeSource) proc(cmd string) *fakeProcess {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i, started := range s.started {
+		if started == cmd {
+			return s.procs[i]
+		}
+	}
+	return nil
+}
+
+// fakeProcess plays back a fakeRun through pipes
+type fakeProcess struct {
+	outR, errR *io.PipeReader
+	run        fakeRun
+	killed     chan struct{}
+	killOnce   sync.Once
+	exited     chan struct{}
+}
+
+func startFakeProcess(run fakeRun) *fakeProcess {
+	outR, outW := io.Pipe()
+	errR, errW := io.Pipe()
+	p := &fakeProcess{outR: outR, errR: errR, run: run, killed: make(chan struct{}), exited: make(chan struct{})}
+
+	var writers sync.WaitGroup
+	writers.Add(2)
+	go func() {
+		defer writers.Done()
+		defer outW.Close()
+		if !p.wait(run.gate) {
+			return
+		}
+		io.WriteString(outW, run.stdout)
+		if run.follow {
+			<-p.killed
+		}
+	}()
+	go func() {
+		defer writers.Done()
+		defer errW.Close()
+		if p.wait(run.gate) {
+			io.WriteString(errW, run.stderr)
+		}
+	}()
+	go func() {
+		writers.Wait()
+		close(p.exited)
+	}()
+	return p
+}
+
+// wait blocks on gate, reporting false if the process was killed first
+func (p *fakeProcess) wait(gate chan struct{}) bool {
+	if gate == nil {
+		return true
+	}
+	select {
+	case <-gate:
+		return true
+	case <-p.killed:
+		return false
+	}
+}
+
+// wasKilled reports whether Kill was called
+func (p *fakeProcess) wasKilled() bool {
+	select {
+	case <-p.killed:
+		return true
+	default:
+		return false
+	}
+}
+
+func (p *fakeProcess) Stdout() io.Reader { return p.outR }
+func (p *fakeProcess) Stderr() io.Reader { return p.errR }
+
+func (p *fakeProcess) Wait() error {
+	<-p.exited
+	select {
+	case <-p.killed:
+		return errors.New("signal: killed")
+	default:
+		return p.run.exit
+	}
+}
+
+func (p *fakeProcess) Kill() error {
+	p.killOnce.Do(func() { close(p.killed) })
+	return nil
+}
+
+func (p *fakeProcess) Close() error {
+	p.outR.Close()
+	p.errR.Close()
+	return nil
+}
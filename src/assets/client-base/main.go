@@ -12,6 +12,7 @@ package main
 import (
 	"os"
 	"os/signal"
+	"strconv"
 	"strings"
 	"syscall"
 	"time"
@@ -59,6 +60,19 @@ func main() {
 	// Attempt to connect via WebSocket
 	logger.Info("Attempting to connect to dev server via WebSocket...")
 	socket := NewSocketClient(config.ClientKey)
+	if config.Keepalive != nil {
+		socket.SetKeepalive(
+			time.Duration(config.Keepalive.IntervalSeconds)*time.Second,
+			time.Duration(config.Keepalive.TimeoutSeconds)*time.Second,
+		)
+	}
+	if config.Heartbeat != nil {
+		socket.SetHeartbeatInterval(time.Duration(config.Heartbeat.IntervalSeconds) * time.Second)
+	}
+	if config.LogPaths != nil {
+		socket.SetLogPaths(config.LogPaths.AppLog, config.LogPaths.CageLog)
+	}
+	socket.SetAllowedCommands(config.AllowedCommands)
 
 	connected := false
 	var connectedHost Host
@@ -133,7 +147,11 @@ func main() {
 	time.Sleep(2 * time.Second)
 
 	// Launch Cage and Cog with inspector if enabled
-	if err := launchDevMode(cogURL, &config.Inspector); err != nil {
+	cageLog := ""
+	if config.LogPaths != nil {
+		cageLog = config.LogPaths.CageLog
+	}
+	if err := launchDevMode(cogURL, &config.Inspector, cageLog); err != nil {
 		logger.Error("Failed to launch dev mode: %v", err)
 		socket.Disconnect()
 		launchProduction()
@@ -175,15 +193,19 @@ func launchProduction() error {
 
 	// Launch Cage with backend URL (no inspector in production)
 	return cage.Launch(LaunchOptions{
-		CogURL:      "http://localhost:8080",
-		Resolution:  resolution,
-		SplashImage: splashImage,
-		Inspector:   nil,
+		CogURL:       "http://localhost:8080",
+		Resolution:   resolution,
+		HideCursor:   fileExists("/strux/.hide-cursor"),
+		BlankTimeout: readBlankTimeout(),
+		SplashImage:  splashImage,
+		Inspector:    nil,
+		EnvAllowlist: readEnvAllowlist(),
 	})
 }
 
-// launchDevMode launches Cage in dev mode with the specified URL
-func launchDevMode(cogURL string, inspector *InspectorConfig) error {
+// launchDevMode launches Cage in dev mode with the specified URL, writing its
+// output to cageLog (DefaultCageLogPath if empty)
+func launchDevMode(cogURL string, inspector *InspectorConfig, cageLog string) error {
 	logger := NewLogger("DevMode")
 
 	// Read display resolution
@@ -208,13 +230,51 @@ func launchDevMode(cogURL string, inspector *InspectorConfig) error {
 
 	// Launch Cage with inspector if enabled
 	return cage.Launch(LaunchOptions{
-		CogURL:      cogURL,
-		Resolution:  resolution,
-		SplashImage: splashImage,
-		Inspector:   inspector,
+		CogURL:       cogURL,
+		Resolution:   resolution,
+		HideCursor:   fileExists("/strux/.hide-cursor"),
+		BlankTimeout: readBlankTimeout(),
+		SplashImage:  splashImage,
+		Inspector:    inspector,
+		EnvAllowlist: readEnvAllowlist(),
+		LogPath:      cageLog,
 	})
 }
 
+// readBlankTimeout reads the display blanking timeout in seconds from
+// /strux/.blank-timeout. A missing or invalid file means never blank.
+func readBlankTimeout() int {
+	content, err := readFileIntoString("/strux/.blank-timeout")
+	if err != nil {
+		return 0
+	}
+	seconds, err := strconv.Atoi(strings.TrimSpace(content))
+	if err != nil || seconds < 0 {
+		NewLogger("Display").Warn("Ignoring invalid blank timeout %q", strings.TrimSpace(content))
+		return 0
+	}
+	return seconds
+}
+
+// readEnvAllowlist reads the names of environment variables to forward to Cog
+// from /strux/.cog-env-allowlist, one per line. Blank lines and # comments are ignored.
+func readEnvAllowlist() []string {
+	content, err := readFileIntoString("/strux/.cog-env-allowlist")
+	if err != nil {
+		return nil
+	}
+
+	names := []string{}
+	for _, line := range strings.Split(content, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		names = append(names, line)
+	}
+	return names
+}
+
 // waitForShutdown blocks until SIGINT or SIGTERM is received
 func waitForShutdown() {
 	sigChan := make(chan os.Signal, 1)
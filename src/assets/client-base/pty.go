@@ -0,0 +1,90 @@
+//
+// Strux Client - PTY Factory
+//
+// ExecManager starts shells through a ptyFactory instead of calling
+// os/exec and creack/pty directly, so session lifecycle, resize and
+// signal handling can be driven against a fake PTY.
+//
+
+package main
+
+import (
+	"io"
+	"os"
+	"os/exec"
+
+	"github.com/creack/pty"
+)
+
+// ptyProcess is a process attached to a pseudo-terminal. Reads and writes
+// go to the PTY master.
+type ptyProcess interface {
+	io.ReadWriteCloser
+
+	// Setsize resizes the terminal
+	Setsize(rows, cols uint16) error
+
+	// Pid is the process ID of the shell
+	Pid() int
+
+	// Kill stops the process
+	Kill() error
+
+	// Wait blocks until the process exits and returns its exit code
+	Wait() int
+}
+
+// ptySize is a terminal size; zero leaves the PTY at its default
+type ptySize struct {
+	Rows, Cols uint16
+}
+
+// ptyFactory starts processes on a new pseudo-terminal of the given size
+type ptyFactory interface {
+	Start(path string, args []string, env []string, size ptySize) (ptyProcess, error)
+}
+
+// creackPTYFactory starts real processes with creack/pty
+type creackPTYFactory struct{}
+
+// Start runs path with args and env on a new PTY. The size is set before the
+// process starts, so its first frame is drawn at that size.
+func (creackPTYFactory) Start(path string, args []string, env []string, size ptySize) (ptyProcess, error) {
+	cmd := exec.Command(path, args...)
+	cmd.Env = env
+
+	var winsize *pty.Winsize
+	if size != (ptySize{}) {
+		winsize = &pty.Winsize{Rows: size.Rows, Cols: size.Cols}
+	}
+	ptmx, err := pty.StartWithSize(cmd, winsize)
+	if err != nil {
+		return nil, err
+	}
+	return &creackPTY{File: ptmx, cmd: cmd}, nil
+}
+
+// creackPTY is a ptyProcess backed by an *exec.Cmd and its PTY master
+type creackPTY struct {
+	*os.File
+	cmd *exec.Cmd
+}
+
+func (p *creackPTY) Setsize(rows, cols uint16) error {
+	return pty.Setsize(p.File, &pty.Winsize{Rows: rows, Cols: cols})
+}
+
+func (p *creackPTY) Pid() int { return p.cmd.Process.Pid }
+
+func (p *creackPTY) Kill() error { return p.cmd.Process.Kill() }
+
+func (p *creackPTY) Wait() int {
+	err := p.cmd.Wait()
+	if err == nil {
+		return 0
+	}
+	if exitErr, ok := err.(*exec.ExitError); ok {
+		return exitErr.ExitCode()
+	}
+	return 1
+}
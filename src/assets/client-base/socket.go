@@ -7,22 +7,45 @@
 // Events:
 // - Client emits: "request-binary" to request the current binary
 // - Server emits: "new-binary" with { data: Buffer } for binary updates
-// - Server emits: "start-logs" with { streamId, type, service? }
+// - Server emits: "start-logs" with { streamId, type, service?, path?, command?, args?, coalesce?, format?, boot?, kernelOnly?, transport?, userJournal?, machine?, maxLines?, maxBytes?, sequenced? }
 // - Server emits: "stop-logs" with { streamId }
-// - Client emits: "log-line" with { streamId, line, service?, timestamp }
+// - Server emits: "pause-logs" / "resume-logs" with { streamId }
+// - Server emits: "list-streams" to request the active log streams
+// - Client emits: "streams" with { streams } (id, type, service?, lines, bytes, paused)
+// - Server emits: "get-recent-logs" with { streamId, count? }
+// - Client emits: "recent-logs" with { streamId, lines, error?, code? }
+// - Client emits: "log-line" with { streamId, line, service?, timestamp, seq?, restart? }
 // - Client emits: "log-stream-error" with { streamId, error }
-// - Server emits: "exec-start" with { sessionId, shell? }
-// - Server emits: "exec-input" with { sessionId, data }
+// - Client emits: "log-stream-complete" with { streamId, reason } when a stream with limits ends by itself
+// - Server emits: "list-units" to request the device's systemd services
+// - Client emits: "units" with { units, error?, code? }
+// - Server emits: "collect-diagnostics" with { journalLines?, maxBytes? }
+// - Client emits: "diagnostics" with { data, error? } (data is a base64 tar.gz of the journal, app and cage logs)
+// - Server emits: "exec-start" with { sessionId, shell?, initCommand?, respawn?, lang?, lcAll?, trueColor?, cols?, rows? }
+// - Server emits: "exec-input" with { sessionId, data, encoding? } (encoding "base64" for raw bytes)
+// - Server emits: "exec-pause" / "exec-resume" with { sessionId }
+// - Server emits: "exec-probe" to ask which shells the device has
+// - Client emits: "exec-shells" with { shells, error?, code? }
+// - Client emits: "exec-started" with { sessionId, pid }
 // - Client emits: "exec-output" with { sessionId, stream, data }
 // - Client emits: "exec-exit" with { sessionId, code }
 // - Client emits: "exec-error" with { sessionId, error }
+// - Server emits: "ipc-open" / "ipc-close" with { channelId }
+// - Server and client emit: "ipc-frame" with { channelId, frame }
+// - Client emits: "ipc-closed" with { channelId, error? }
+// - Client emits: "client-resume" with { streams, sessions, channels } after reconnecting
+// - Client emits: "heartbeat" with { interval, uptime, processUptime, memTotal, memAvailable, heapAlloc, goroutines, streams, sessions, channels } every few seconds
+// - Server emits: "ack" with { seq } for each sequenced client message
 //
 
 package main
 
 import (
+	"bytes"
 	"encoding/base64"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"sync"
 	"time"
 )
@@ -34,9 +57,32 @@ type BinaryPayload struct {
 
 // StartLogsPayload represents the payload for starting log streams
 type StartLogsPayload struct {
+	StreamID string   `json:"streamId"`
+	Type     string   `json:"type"`               // "journalctl", "service", "app", "cage", "early", "snapshot", "file" or "command"
+	Service  string   `json:"service"`            // service name if type is "service"
+	Path     string   `json:"path,omitempty"`     // absolute file path if type is "file"
+	Command  string   `json:"command,omitempty"`  // command to run if type is "command"; see SetAllowedCommands
+	Args     []string `json:"args,omitempty"`     // its arguments
+	Coalesce bool     `json:"coalesce,omitempty"` // join stack trace continuation lines into one entry
+	Format   string   `json:"format,omitempty"`   // journalctl output format (default "short-precise")
+	MaxLines int      `json:"maxLines,omitempty"` // stop after this many lines
+	MaxBytes int64    `json:"maxBytes,omitempty"` // stop after this many bytes
+
+	// Sequenced numbers each line (see SetSequenced); it replaces Coalesce
+	Sequenced bool `json:"sequenced,omitempty"`
+
+	// journalctl filters (see JournalOptions)
+	Boot        int    `json:"boot,omitempty"`
+	KernelOnly  bool   `json:"kernelOnly,omitempty"`
+	Transport   string `json:"transport,omitempty"`
+	UserJournal bool   `json:"userJournal,omitempty"`
+	Machine     string `json:"machine,omitempty"`
+}
+
+// LogCompletePayload reports that a stream ended without being stopped
+type LogCompletePayload struct {
 	StreamID string `json:"streamId"`
-	Type     string `json:"type"`    // "journalctl", "service", "app", "cage", or "early"
-	Service  string `json:"service"` // service name if type is "service"
+	Reason   string `json:"reason"` // "limit" or "exited"
 }
 
 // StopLogsPayload represents the payload for stopping log streams
@@ -44,30 +90,130 @@ type StopLogsPayload struct {
 	StreamID string `json:"streamId"`
 }
 
+// StreamsPayload answers list-streams
+type StreamsPayload struct {
+	Streams []StreamStats `json:"streams"`
+}
+
+// RecentLogsRequestPayload asks for a stream's most recent lines; Count 0
+// means everything retained
+type RecentLogsRequestPayload struct {
+	StreamID string `json:"streamId"`
+	Count    int    `json:"count,omitempty"`
+}
+
+// RecentLogsPayload answers get-recent-logs
+type RecentLogsPayload struct {
+	StreamID string   `json:"streamId"`
+	Lines    []string `json:"lines"`
+	Error    string   `json:"error,omitempty"`
+	Code     string   `json:"code,omitempty"` // see errorCode
+}
+
 // LogLinePayload represents a log line to send to the server
 type LogLinePayload struct {
 	StreamID  string `json:"streamId"`
 	Line      string `json:"line"`
 	Service   string `json:"service,omitempty"`
 	Timestamp string `json:"timestamp"`
+
+	// Seq is the line's sequence number on sequenced streams. Restart marks
+	// the boundary where a restarted stream continued the numbering.
+	Seq     uint64 `json:"seq,omitempty"`
+	Restart bool   `json:"restart,omitempty"`
 }
 
 // LogErrorPayload represents a log stream error
 type LogErrorPayload struct {
 	StreamID string `json:"streamId"`
 	Error    string `json:"error"`
+	Code     string `json:"code,omitempty"` // see errorCode
+}
+
+// UnitsPayload answers list-units; Code is "no_systemd" when the device
+// has no systemd and service logs should be hidden
+type UnitsPayload struct {
+	Units []UnitInfo `json:"units"`
+	Error string     `json:"error,omitempty"`
+	Code  string     `json:"code,omitempty"` // see errorCode
+}
+
+// CollectDiagnosticsPayload asks for a diagnostics archive; zero values
+// use the DiagnosticsOptions defaults
+type CollectDiagnosticsPayload struct {
+	JournalLines int   `json:"journalLines,omitempty"`
+	MaxBytes     int64 `json:"maxBytes,omitempty"` // per source
+}
+
+// DiagnosticsPayload answers collect-diagnostics with the archive from
+// CollectDiagnostics. Sources that could not be read are listed in its
+// errors.txt; Error is set only when no archive could be made.
+type DiagnosticsPayload struct {
+	Data  string `json:"data"` // base64 encoded tar.gz
+	Error string `json:"error,omitempty"`
 }
 
 // ExecStartPayload starts an interactive shell session
 type ExecStartPayload struct {
-	SessionID string `json:"sessionId"`
-	Shell     string `json:"shell,omitempty"`
+	SessionID   string `json:"sessionId"`
+	Shell       string `json:"shell,omitempty"`
+	InitCommand string `json:"initCommand,omitempty"` // run before the interactive shell starts
+	Respawn     bool   `json:"respawn,omitempty"`     // relaunch the shell when it exits cleanly
+
+	// Terminal locale and color (see ExecOptions)
+	Lang      string `json:"lang,omitempty"`
+	LCAll     string `json:"lcAll,omitempty"`
+	TrueColor bool   `json:"trueColor,omitempty"`
+
+	// Terminal size to start the shell at (see ExecOptions)
+	Cols uint16 `json:"cols,omitempty"`
+	Rows uint16 `json:"rows,omitempty"`
+}
+
+// ExecShellsPayload answers exec-probe; Code is "no_shell" when the device
+// has no shell and the terminal should be disabled
+type ExecShellsPayload struct {
+	Shells []string `json:"shells"`
+	Error  string   `json:"error,omitempty"`
+	Code   string   `json:"code,omitempty"` // see errorCode
+}
+
+// IPCChannelPayload opens or closes an IPC channel
+type IPCChannelPayload struct {
+	ChannelID string `json:"channelId"`
+}
+
+// IPCFramePayload carries one IPC bridge frame, in either direction
+type IPCFramePayload struct {
+	ChannelID string          `json:"channelId"`
+	Frame     json.RawMessage `json:"frame"`
+}
+
+// IPCClosedPayload tells the server a channel ended, with why if it failed
+type IPCClosedPayload struct {
+	ChannelID string `json:"channelId"`
+	Error     string `json:"error,omitempty"`
 }
 
 // ExecInputPayload sends input to an interactive shell session
 type ExecInputPayload struct {
 	SessionID string `json:"sessionId"`
 	Data      string `json:"data"`
+
+	// Encoding "base64" marks Data as base64-encoded raw bytes, for input
+	// that is not valid UTF-8 and would not survive JSON as text
+	Encoding string `json:"encoding,omitempty"`
+}
+
+// ExecFlowPayload pauses or resumes a session's output
+type ExecFlowPayload struct {
+	SessionID string `json:"sessionId"`
+}
+
+// ExecStartedPayload notifies the server that a session's shell is running
+type ExecStartedPayload struct {
+	SessionID string `json:"sessionId"`
+	PID       int    `json:"pid"`
 }
 
 // ExecOutputPayload sends console output back to the server
@@ -87,6 +233,14 @@ type ExecExitPayload struct {
 type ExecErrorPayload struct {
 	SessionID string `json:"sessionId"`
 	Error     string `json:"error"`
+	Code      string `json:"code,omitempty"` // see errorCode
+}
+
+// ResumePayload lists the log streams, exec sessions and IPC channels that survived a reconnect
+type ResumePayload struct {
+	Streams  []string `json:"streams"`
+	Sessions []string `json:"sessions"`
+	Channels []string `json:"channels"`
 }
 
 // BinaryAckPayload represents the acknowledgment of a binary update
@@ -106,7 +260,18 @@ type SocketClient struct {
 	connected  bool
 	host       Host
 	logStreams *LogStreamer
+	streams    *StreamController
 	exec       *ExecManager
+	ipc        *IPCManager
+	ipcQueue   *channelQueue
+
+	// Keepalive settings applied to each connection
+	pingInterval time.Duration
+	pongTimeout  time.Duration
+
+	// Heartbeat period and the channel that stops the running loop
+	heartbeatInterval time.Duration
+	heartbeatDone     chan struct{}
 }
 
 // NewSocketClient creates a new WebSocket client
@@ -115,9 +280,18 @@ func NewSocketClient(clientKey string) *SocketClient {
 		clientKey:  clientKey,
 		logger:     NewLogger("SocketClient"),
 		logStreams: NewLogStreamer(),
+
+		pingInterval: DefaultPingInterval,
+		pongTimeout:  DefaultPongTimeout,
+
+		heartbeatInterval: DefaultHeartbeatInterval,
 	}
+	client.streams = NewStreamController(client.logStreams)
 
 	client.exec = NewExecManager(
+		func(sessionID string, pid int) {
+			client.SendExecStarted(sessionID, pid)
+		},
 		func(sessionID, stream, data string) {
 			client.SendExecOutput(sessionID, stream, data)
 		},
@@ -125,13 +299,52 @@ func NewSocketClient(clientKey string) *SocketClient {
 			client.SendExecExit(sessionID, code)
 		},
 		func(sessionID string, err error) {
-			client.SendExecError(sessionID, err.Error())
+			client.SendExecError(sessionID, err)
+		},
+	)
+
+	client.ipcQueue = newChannelQueue()
+	client.ipc = NewIPCManager(
+		func(channelID string, frame json.RawMessage) {
+			client.SendIPCFrame(channelID, frame)
+		},
+		func(channelID string, err error) {
+			client.SendIPCClosed(channelID, err)
 		},
 	)
 
 	return client
 }
 
+// SetKeepalive configures WebSocket ping/pong keepalive for subsequent
+// connections; see WSClient.SetKeepalive
+func (s *SocketClient) SetKeepalive(interval, timeout time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.pingInterval = interval
+	s.pongTimeout = timeout
+}
+
+// SetHeartbeatInterval sets how often vitals are sent to the dev server for
+// subsequent connections; zero or less disables heartbeats
+func (s *SocketClient) SetHeartbeatInterval(interval time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.heartbeatInterval = interval
+}
+
+// SetLogPaths changes the app and Cage log files streamed by "app" and
+// "cage" log streams; see LogStreamer.SetLogPaths
+func (s *SocketClient) SetLogPaths(appLog, cageLog string) {
+	s.logStreams.SetLogPaths(appLog, cageLog)
+}
+
+// SetAllowedCommands sets the commands "command" log streams may run; see
+// LogStreamer.SetAllowedCommands
+func (s *SocketClient) SetAllowedCommands(names []string) {
+	s.logStreams.SetAllowedCommands(names)
+}
+
 // Connect establishes a WebSocket connection to the specified host
 func (s *SocketClient) Connect(host Host) error {
 	s.mu.Lock()
@@ -147,20 +360,44 @@ func (s *SocketClient) Connect(host Host) error {
 		ws.SetHeader("X-Client-Key", s.clientKey)
 	}
 
+	// Keep log and exec output across short outages; it is replayed on reconnect
+	ws.SetOutbox(DefaultOutboxSize)
+
+	// Notice links silently dropped by NAT or firewalls
+	ws.SetKeepalive(s.pingInterval, s.pongTimeout)
+
 	// Set up connection lifecycle callbacks
+	connects := 0
 	ws.OnConnect(func() {
 		s.mu.Lock()
 		s.connected = true
+		connects++
+		reconnected := connects > 1
 		s.mu.Unlock()
 		s.logger.Info("WebSocket connected")
+
+		if reconnected {
+			s.SendResume()
+			s.SendHeartbeat(s.currentHeartbeatInterval())
+		}
 	})
 
+	// Streams, sessions and IPC channels keep running while disconnected so they can resume
 	ws.OnDisconnect(func() {
 		s.mu.Lock()
 		s.connected = false
 		s.mu.Unlock()
 		s.logger.Warn("WebSocket disconnected")
+	})
+
+	ws.OnReconnectFailed(func() {
+		s.logger.Error("Giving up on reconnecting, stopping log streams, exec sessions and IPC channels")
 		s.logStreams.StopAll()
+		s.exec.StopAll()
+		s.ipc.CloseAll()
+		s.mu.Lock()
+		s.stopHeartbeatLocked()
+		s.mu.Unlock()
 	})
 
 	ws.OnError(func(err error) {
@@ -188,9 +425,31 @@ func (s *SocketClient) Connect(host Host) error {
 	// Request the current binary
 	s.RequestBinary()
 
+	// Report vitals so the server can tell a hung client from a lost one
+	s.stopHeartbeatLocked()
+	if s.heartbeatInterval > 0 {
+		s.heartbeatDone = make(chan struct{})
+		go s.heartbeatLoop(s.heartbeatDone, s.heartbeatInterval)
+	}
+
 	return nil
 }
 
+// stopHeartbeatLocked ends the heartbeat loop if one is running; s.mu must be held
+func (s *SocketClient) stopHeartbeatLocked() {
+	if s.heartbeatDone != nil {
+		close(s.heartbeatDone)
+		s.heartbeatDone = nil
+	}
+}
+
+// currentHeartbeatInterval returns the configured heartbeat period
+func (s *SocketClient) currentHeartbeatInterval() time.Duration {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.heartbeatInterval
+}
+
 // setupEventHandlers registers all WebSocket event handlers
 func (s *SocketClient) setupEventHandlers(ws *WSClient) {
 	// Handle binary updates from server
@@ -223,6 +482,68 @@ func (s *SocketClient) setupEventHandlers(ws *WSClient) {
 		s.handleStopLogs(stopPayload)
 	})
 
+	// Handle pause-logs event
+	ws.On("pause-logs", func(payload json.RawMessage) {
+		var flowPayload StopLogsPayload
+		if err := json.Unmarshal(payload, &flowPayload); err != nil {
+			s.logger.Error("Failed to parse pause-logs payload: %v", err)
+			return
+		}
+		if err := s.streams.Pause(flowPayload.StreamID); err != nil {
+			s.SendLogError(flowPayload.StreamID, err)
+		}
+	})
+
+	// Handle resume-logs event
+	ws.On("resume-logs", func(payload json.RawMessage) {
+		var flowPayload StopLogsPayload
+		if err := json.Unmarshal(payload, &flowPayload); err != nil {
+			s.logger.Error("Failed to parse resume-logs payload: %v", err)
+			return
+		}
+		if err := s.streams.Resume(flowPayload.StreamID); err != nil {
+			s.SendLogError(flowPayload.StreamID, err)
+		}
+	})
+
+	// Handle list-streams event
+	ws.On("list-streams", func(payload json.RawMessage) {
+		s.SendStreams(s.streams.List())
+	})
+
+	// Handle get-recent-logs event
+	ws.On("get-recent-logs", func(payload json.RawMessage) {
+		var recentPayload RecentLogsRequestPayload
+		if err := json.Unmarshal(payload, &recentPayload); err != nil {
+			s.logger.Error("Failed to parse get-recent-logs payload: %v", err)
+			return
+		}
+		lines, err := s.streams.Recent(recentPayload.StreamID, recentPayload.Count)
+		s.SendRecentLogs(recentPayload.StreamID, lines, err)
+	})
+
+	// Handle list-units event; systemctl can be slow, so answer off the
+	// read loop
+	ws.On("list-units", func(payload json.RawMessage) {
+		go s.SendUnits(s.logStreams.ListUnits())
+	})
+
+	// Handle collect-diagnostics event; journalctl can be slow, so answer
+	// off the read loop
+	ws.On("collect-diagnostics", func(payload json.RawMessage) {
+		var diagPayload CollectDiagnosticsPayload
+		if len(payload) > 0 {
+			if err := json.Unmarshal(payload, &diagPayload); err != nil {
+				s.logger.Error("Failed to parse collect-diagnostics payload: %v", err)
+				return
+			}
+		}
+		go s.SendDiagnostics(DiagnosticsOptions{
+			JournalLines: diagPayload.JournalLines,
+			MaxBytes:     diagPayload.MaxBytes,
+		})
+	})
+
 	// Handle exec-start event
 	ws.On("exec-start", func(payload json.RawMessage) {
 		var execPayload ExecStartPayload
@@ -233,6 +554,11 @@ func (s *SocketClient) setupEventHandlers(ws *WSClient) {
 		s.handleExecStart(execPayload)
 	})
 
+	// Handle exec-probe event
+	ws.On("exec-probe", func(payload json.RawMessage) {
+		s.SendExecShells(s.exec.Probe())
+	})
+
 	// Handle exec-input event
 	ws.On("exec-input", func(payload json.RawMessage) {
 		var inputPayload ExecInputPayload
@@ -242,6 +568,67 @@ func (s *SocketClient) setupEventHandlers(ws *WSClient) {
 		}
 		s.handleExecInput(inputPayload)
 	})
+
+	// Handle exec-pause event
+	ws.On("exec-pause", func(payload json.RawMessage) {
+		var flowPayload ExecFlowPayload
+		if err := json.Unmarshal(payload, &flowPayload); err != nil {
+			s.logger.Error("Failed to parse exec-pause payload: %v", err)
+			return
+		}
+		if err := s.exec.Pause(flowPayload.SessionID); err != nil {
+			s.SendExecError(flowPayload.SessionID, err)
+		}
+	})
+
+	// Handle exec-resume event
+	ws.On("exec-resume", func(payload json.RawMessage) {
+		var flowPayload ExecFlowPayload
+		if err := json.Unmarshal(payload, &flowPayload); err != nil {
+			s.logger.Error("Failed to parse exec-resume payload: %v", err)
+			return
+		}
+		if err := s.exec.Resume(flowPayload.SessionID); err != nil {
+			s.SendExecError(flowPayload.SessionID, err)
+		}
+	})
+
+	// IPC events are taken in arrival order and run in that order per
+	// channel, so a frame sent right after ipc-open finds its channel
+	ws.OnInOrder("ipc-open", func(payload json.RawMessage) {
+		var channelPayload IPCChannelPayload
+		if err := json.Unmarshal(payload, &channelPayload); err != nil {
+			s.logger.Error("Failed to parse ipc-open payload: %v", err)
+			return
+		}
+		s.ipcQueue.push(channelPayload.ChannelID, func() {
+			s.handleIPCOpen(channelPayload)
+		})
+	})
+
+	// Handle ipc-frame event
+	ws.OnInOrder("ipc-frame", func(payload json.RawMessage) {
+		var framePayload IPCFramePayload
+		if err := json.Unmarshal(payload, &framePayload); err != nil {
+			s.logger.Error("Failed to parse ipc-frame payload: %v", err)
+			return
+		}
+		s.ipcQueue.push(framePayload.ChannelID, func() {
+			s.handleIPCFrame(framePayload)
+		})
+	})
+
+	// Handle ipc-close event
+	ws.OnInOrder("ipc-close", func(payload json.RawMessage) {
+		var channelPayload IPCChannelPayload
+		if err := json.Unmarshal(payload, &channelPayload); err != nil {
+			s.logger.Error("Failed to parse ipc-close payload: %v", err)
+			return
+		}
+		s.ipcQueue.push(channelPayload.ChannelID, func() {
+			s.ipc.Close(channelPayload.ChannelID)
+		})
+	})
 }
 
 // Disconnect closes the WebSocket connection
@@ -251,8 +638,10 @@ func (s *SocketClient) Disconnect() {
 
 	if s.ws != nil {
 		s.logger.Info("Disconnecting...")
+		s.stopHeartbeatLocked()
 		s.logStreams.StopAll()
 		s.exec.StopAll()
+		s.ipc.CloseAll()
 		s.ws.Disconnect()
 		s.ws = nil
 		s.connected = false
@@ -290,31 +679,72 @@ func (s *SocketClient) RequestBinary() {
 
 // SendLogLine sends a log line to the server
 func (s *SocketClient) SendLogLine(streamID, line, service string) {
+	s.sendLogLine(LogLinePayload{StreamID: streamID, Line: line, Service: service})
+}
+
+// SendSequencedLogLine sends a numbered log line, or the restart marker
+func (s *SocketClient) SendSequencedLogLine(streamID string, seq uint64, line, service string) {
+	payload := LogLinePayload{StreamID: streamID, Line: line, Service: service, Seq: seq}
+	if line == StreamRestartMarker {
+		payload.Restart = true
+	}
+	s.sendLogLine(payload)
+}
+
+// sendLogLine stamps and emits a log-line event
+func (s *SocketClient) sendLogLine(payload LogLinePayload) {
 	if s.ws == nil {
 		return
 	}
 
-	payload := LogLinePayload{
-		StreamID:  streamID,
-		Line:      line,
-		Service:   service,
-		Timestamp: time.Now().Format(time.RFC3339),
-	}
+	payload.Timestamp = time.Now().Format(time.RFC3339)
 
 	if err := s.ws.Emit("log-line", payload); err != nil {
 		s.logger.Error("Failed to send log line: %v", err)
 	}
 }
 
+// Error codes sent with log, exec and unit listing errors, so the server can
+// branch without matching messages
+const (
+	errorCodeExists     = "exists"
+	errorCodeNotFound   = "not_found"
+	errorCodeNoBackend  = "no_backend"
+	errorCodePermission = "permission_denied"
+	errorCodeNoSystemd  = "no_systemd"
+	errorCodeNoShell    = "no_shell"
+)
+
+// errorCode maps the LogStreamer and ExecManager sentinel errors to a code,
+// or "" for other errors
+func errorCode(err error) string {
+	switch {
+	case errors.Is(err, ErrStreamExists), errors.Is(err, ErrSessionExists):
+		return errorCodeExists
+	case errors.Is(err, ErrStreamNotFound), errors.Is(err, ErrSessionNotFound):
+		return errorCodeNotFound
+	case errors.Is(err, ErrNoLogBackend):
+		return errorCodeNoBackend
+	case errors.Is(err, ErrJournalPermission), errors.Is(err, ErrCommandNotAllowed):
+		return errorCodePermission
+	case errors.Is(err, ErrNoSystemd):
+		return errorCodeNoSystemd
+	case errors.Is(err, ErrNoShell):
+		return errorCodeNoShell
+	}
+	return ""
+}
+
 // SendLogError sends a log stream error to the server
-func (s *SocketClient) SendLogError(streamID string, errMsg string) {
+func (s *SocketClient) SendLogError(streamID string, err error) {
 	if s.ws == nil {
 		return
 	}
 
 	payload := LogErrorPayload{
 		StreamID: streamID,
-		Error:    errMsg,
+		Error:    err.Error(),
+		Code:     errorCode(err),
 	}
 
 	if err := s.ws.Emit("log-stream-error", payload); err != nil {
@@ -322,6 +752,112 @@ func (s *SocketClient) SendLogError(streamID string, errMsg string) {
 	}
 }
 
+// SendLogComplete tells the server a stream ended by itself and why
+func (s *SocketClient) SendLogComplete(streamID string, reason StreamEndReason) {
+	if s.ws == nil {
+		return
+	}
+
+	payload := LogCompletePayload{
+		StreamID: streamID,
+		Reason:   reason.String(),
+	}
+
+	if err := s.ws.Emit("log-stream-complete", payload); err != nil {
+		s.logger.Error("Failed to send log completion: %v", err)
+	}
+}
+
+// SendUnits sends the result of ListUnits to the server
+func (s *SocketClient) SendUnits(units []UnitInfo, err error) {
+	if s.ws == nil {
+		return
+	}
+
+	payload := UnitsPayload{Units: units}
+	if payload.Units == nil {
+		payload.Units = []UnitInfo{}
+	}
+	if err != nil {
+		payload.Error = err.Error()
+		payload.Code = errorCode(err)
+	}
+
+	if err := s.ws.Emit("units", payload); err != nil {
+		s.logger.Error("Failed to send units: %v", err)
+	}
+}
+
+// SendDiagnostics collects a diagnostics archive and sends it to the server
+func (s *SocketClient) SendDiagnostics(opts DiagnosticsOptions) {
+	if s.ws == nil {
+		return
+	}
+
+	var archive bytes.Buffer
+	var payload DiagnosticsPayload
+	if err := s.logStreams.CollectDiagnostics(&archive, opts); err != nil {
+		payload.Error = err.Error()
+	} else {
+		payload.Data = base64.StdEncoding.EncodeToString(archive.Bytes())
+	}
+
+	if err := s.ws.Emit("diagnostics", payload); err != nil {
+		s.logger.Error("Failed to send diagnostics: %v", err)
+	}
+}
+
+// SendStreams sends the active log streams to the server
+func (s *SocketClient) SendStreams(streams []StreamStats) {
+	if s.ws == nil {
+		return
+	}
+
+	if err := s.ws.Emit("streams", StreamsPayload{Streams: streams}); err != nil {
+		s.logger.Error("Failed to send streams: %v", err)
+	}
+}
+
+// SendRecentLogs sends a stream's recent lines, or why they could not be read
+func (s *SocketClient) SendRecentLogs(streamID string, lines []string, err error) {
+	if s.ws == nil {
+		return
+	}
+
+	payload := RecentLogsPayload{StreamID: streamID, Lines: lines}
+	if payload.Lines == nil {
+		payload.Lines = []string{}
+	}
+	if err != nil {
+		payload.Error = err.Error()
+		payload.Code = errorCode(err)
+	}
+
+	if err := s.ws.Emit("recent-logs", payload); err != nil {
+		s.logger.Error("Failed to send recent logs: %v", err)
+	}
+}
+
+// SendExecShells sends the shells Probe found to the server
+func (s *SocketClient) SendExecShells(shells []string, err error) {
+	if s.ws == nil {
+		return
+	}
+
+	payload := ExecShellsPayload{Shells: shells}
+	if payload.Shells == nil {
+		payload.Shells = []string{}
+	}
+	if err != nil {
+		payload.Error = err.Error()
+		payload.Code = errorCode(err)
+	}
+
+	if err := s.ws.Emit("exec-shells", payload); err != nil {
+		s.logger.Error("Failed to send exec shells: %v", err)
+	}
+}
+
 // SendBinaryAck sends a binary update acknowledgment to the server
 func (s *SocketClient) SendBinaryAck(status, message, currentChecksum, receivedChecksum string) {
 	if s.ws == nil {
@@ -340,6 +876,55 @@ func (s *SocketClient) SendBinaryAck(status, message, currentChecksum, receivedC
 	}
 }
 
+// SendResume tells the server which log streams and exec sessions are still active
+func (s *SocketClient) SendResume() {
+	if s.ws == nil {
+		return
+	}
+
+	payload := ResumePayload{
+		Streams:  s.logStreams.GetActiveStreams(),
+		Sessions: s.exec.SessionIDs(),
+		Channels: s.ipc.ChannelIDs(),
+	}
+
+	s.logger.Info("Resuming %d log streams, %d exec sessions and %d IPC channels", len(payload.Streams), len(payload.Sessions), len(payload.Channels))
+
+	if err := s.ws.Emit("client-resume", payload); err != nil {
+		s.logger.Error("Failed to send resume: %v", err)
+	}
+}
+
+// SendHeartbeat sends the client's vitals. It is skipped while disconnected;
+// a heartbeat replayed after a reconnect would report stale figures.
+func (s *SocketClient) SendHeartbeat(interval time.Duration) {
+	// Read once: the loop can race with Disconnect clearing s.ws
+	ws := s.ws
+	if ws == nil || interval <= 0 {
+		return
+	}
+
+	if err := ws.EmitVolatile("heartbeat", s.collectVitals(interval)); err != nil && ws.IsConnected() {
+		s.logger.Warn("Failed to send heartbeat: %v", err)
+	}
+}
+
+// SendExecStarted tells the server a session is up and which PID its shell has
+func (s *SocketClient) SendExecStarted(sessionID string, pid int) {
+	if s.ws == nil {
+		return
+	}
+
+	payload := ExecStartedPayload{
+		SessionID: sessionID,
+		PID:       pid,
+	}
+
+	if err := s.ws.Emit("exec-started", payload); err != nil {
+		s.logger.Error("Failed to send exec started: %v", err)
+	}
+}
+
 // SendExecOutput streams console output to the server
 func (s *SocketClient) SendExecOutput(sessionID, stream, data string) {
 	if s.ws == nil {
@@ -374,14 +959,15 @@ func (s *SocketClient) SendExecExit(sessionID string, code int) {
 }
 
 // SendExecError sends exec error to the server
-func (s *SocketClient) SendExecError(sessionID string, errMsg string) {
+func (s *SocketClient) SendExecError(sessionID string, err error) {
 	if s.ws == nil {
 		return
 	}
 
 	payload := ExecErrorPayload{
 		SessionID: sessionID,
-		Error:     errMsg,
+		Error:     err.Error(),
+		Code:      errorCode(err),
 	}
 
 	if err := s.ws.Emit("exec-error", payload); err != nil {
@@ -389,6 +975,38 @@ func (s *SocketClient) SendExecError(sessionID string, errMsg string) {
 	}
 }
 
+// SendIPCFrame relays a frame from the runtime to the server
+func (s *SocketClient) SendIPCFrame(channelID string, frame json.RawMessage) {
+	if s.ws == nil {
+		return
+	}
+
+	payload := IPCFramePayload{
+		ChannelID: channelID,
+		Frame:     frame,
+	}
+
+	if err := s.ws.Emit("ipc-frame", payload); err != nil {
+		s.logger.Error("Failed to send IPC frame: %v", err)
+	}
+}
+
+// SendIPCClosed tells the server an IPC channel ended
+func (s *SocketClient) SendIPCClosed(channelID string, err error) {
+	if s.ws == nil {
+		return
+	}
+
+	payload := IPCClosedPayload{ChannelID: channelID}
+	if err != nil {
+		payload.Error = err.Error()
+	}
+
+	if err := s.ws.Emit("ipc-closed", payload); err != nil {
+		s.logger.Error("Failed to send IPC closed: %v", err)
+	}
+}
+
 // handleBinaryUpdate handles a binary update from the server
 func (s *SocketClient) handleBinaryUpdate(data string) {
 	s.logger.Info("Received binary update")
@@ -416,59 +1034,161 @@ func (s *SocketClient) handleBinaryUpdate(data string) {
 
 // handleStartLogs starts a log stream
 func (s *SocketClient) handleStartLogs(payload StartLogsPayload) {
+	// Validate before the ID reaches any log line
+	if err := validateID("stream", payload.StreamID); err != nil {
+		s.logger.Error("Rejected log stream: %v", err)
+		s.SendLogError(payload.StreamID, err)
+		return
+	}
+
 	s.logger.Info("Starting log stream: %s (type: %s, service: %s)", payload.StreamID, payload.Type, payload.Service)
 
 	// Create callback to send log lines
-	callback := func(line string) {
+	var callback LogCallback = func(line string) {
 		s.SendLogLine(payload.StreamID, line, payload.Service)
 	}
+	var coalescer *LogCoalescer
+	if payload.Coalesce && payload.Sequenced {
+		s.logger.Warn("Stream %s is sequenced; ignoring coalesce", payload.StreamID)
+	} else if payload.Coalesce {
+		coalescer = NewLogCoalescer(callback, nil, 0)
+		callback = coalescer.Callback()
+	}
+
+	journalOpts := JournalOptions{
+		OutputFormat: payload.Format,
+		Boot:         payload.Boot,
+		KernelOnly:   payload.KernelOnly,
+		Transport:    payload.Transport,
+		UserJournal:  payload.UserJournal,
+		Machine:      payload.Machine,
+	}
+
+	// Configured before the stream starts, so its first lines are numbered
+	// and counted too, and a stream that ends at once still reports it
+	var setup StreamSetup
+	if payload.Sequenced {
+		setup.Sequenced = func(seq uint64, line string) {
+			s.SendSequencedLogLine(payload.StreamID, seq, line, payload.Service)
+		}
+	}
+	if payload.Type == "snapshot" || payload.MaxLines > 0 || payload.MaxBytes > 0 {
+		setup.Limits = StreamLimits{MaxLines: payload.MaxLines, MaxBytes: payload.MaxBytes}
+		setup.OnComplete = func(reason StreamEndReason) {
+			s.SendLogComplete(payload.StreamID, reason)
+		}
+	}
+
+	// Deliver the last coalesced entry when the stream ends instead of
+	// dropping it, and nothing after
+	if coalescer != nil {
+		setup.Flush = coalescer.Stop
+	}
 
 	var err error
 	switch payload.Type {
 	case "service":
 		if payload.Service != "" {
-			err = s.logStreams.StartServiceStream(payload.StreamID, payload.Service, callback)
+			err = s.logStreams.StartServiceStreamWithOptions(payload.StreamID, payload.Service, journalOpts, callback, setup)
 		} else {
-			err = s.logStreams.StartJournalctlStream(payload.StreamID, callback)
+			err = s.logStreams.StartJournalctlStreamWithOptions(payload.StreamID, journalOpts, callback, setup)
 		}
 	case "app":
-		// Stream the user's Go app output from /tmp/strux-backend.log
-		err = s.logStreams.StartAppLogStream(payload.StreamID, callback)
+		// Stream the user's Go app output (DefaultAppLogPath)
+		err = s.logStreams.StartAppLogStream(payload.StreamID, callback, setup)
 	case "cage":
-		// Stream Cage/Cog output from /tmp/strux-cage.log
-		err = s.logStreams.StartCageLogStream(payload.StreamID, callback)
+		// Stream Cage/Cog output (DefaultCageLogPath)
+		err = s.logStreams.StartCageLogStream(payload.StreamID, callback, setup)
+	case "file":
+		err = s.logStreams.StartFileLogStream(payload.StreamID, payload.Path, callback, setup)
+	case "command":
+		err = s.logStreams.StartCommandStream(payload.StreamID, payload.Command, payload.Args, callback, setup)
 	case "journalctl":
-		err = s.logStreams.StartJournalctlStream(payload.StreamID, callback)
+		err = s.logStreams.StartJournalctlStreamWithOptions(payload.StreamID, journalOpts, callback, setup)
 	case "early":
-		err = s.logStreams.StartEarlyLogStream(payload.StreamID, callback)
+		err = s.logStreams.StartEarlyLogStreamWithOptions(payload.StreamID, journalOpts, callback, setup)
+	case "snapshot":
+		// Reads the journal once and always reports how it ended
+		err = s.logStreams.StartJournalctlSnapshot(payload.StreamID, payload.Service, journalOpts, callback, setup)
 	default:
-		err = s.logStreams.StartJournalctlStream(payload.StreamID, callback)
+		err = s.logStreams.StartJournalctlStreamWithOptions(payload.StreamID, journalOpts, callback, setup)
 	}
 
 	if err != nil {
 		s.logger.Error("Failed to start log stream: %v", err)
-		s.SendLogError(payload.StreamID, err.Error())
+		s.SendLogError(payload.StreamID, err)
+		return
 	}
 }
 
 // handleStopLogs stops a log stream
 func (s *SocketClient) handleStopLogs(payload StopLogsPayload) {
 	s.logger.Info("Stopping log stream: %s", payload.StreamID)
-	s.logStreams.Stop(payload.StreamID)
+	if err := s.streams.Stop(payload.StreamID); err != nil {
+		s.logger.Warn("Failed to stop log stream: %v", err)
+	}
 }
 
 func (s *SocketClient) handleExecStart(payload ExecStartPayload) {
+	if err := validateID("session", payload.SessionID); err != nil {
+		s.logger.Error("Rejected exec session: %v", err)
+		s.SendExecError(payload.SessionID, err)
+		return
+	}
+
 	s.logger.Info("Starting exec session: %s", payload.SessionID)
 
-	if err := s.exec.Start(payload.SessionID, payload.Shell); err != nil {
+	host := s.GetHost()
+	opts := ExecOptions{
+		Shell:       payload.Shell,
+		InitCommand: payload.InitCommand,
+		Respawn:     payload.Respawn,
+		Lang:        payload.Lang,
+		LCAll:       payload.LCAll,
+		TrueColor:   payload.TrueColor,
+		InitialCols: payload.Cols,
+		InitialRows: payload.Rows,
+		Client:      fmt.Sprintf("%s:%d", host.Host, host.Port),
+	}
+	if err := s.exec.StartWithOptions(payload.SessionID, opts); err != nil {
 		s.logger.Error("Failed to start exec session: %v", err)
-		s.SendExecError(payload.SessionID, err.Error())
+		s.SendExecError(payload.SessionID, err)
 	}
 }
 
 func (s *SocketClient) handleExecInput(payload ExecInputPayload) {
-	if err := s.exec.SendInput(payload.SessionID, payload.Data); err != nil {
+	data := []byte(payload.Data)
+	if payload.Encoding == "base64" {
+		decoded, err := base64.StdEncoding.DecodeString(payload.Data)
+		if err != nil {
+			s.logger.Error("Failed to decode exec input: %v", err)
+			s.SendExecError(payload.SessionID, fmt.Errorf("invalid base64 input: %w", err))
+			return
+		}
+		data = decoded
+	}
+
+	if err := s.exec.SendInputBytes(payload.SessionID, data); err != nil {
 		s.logger.Error("Failed to send exec input: %v", err)
-		s.SendExecError(payload.SessionID, err.Error())
+		s.SendExecError(payload.SessionID, err)
+	}
+}
+
+func (s *SocketClient) handleIPCOpen(payload IPCChannelPayload) {
+	if err := s.ipc.Open(payload.ChannelID); err != nil {
+		s.logger.Error("Failed to open IPC channel: %v", err)
+		s.SendIPCClosed(payload.ChannelID, err)
+	}
+}
+
+func (s *SocketClient) handleIPCFrame(payload IPCFramePayload) {
+	err := s.ipc.Send(payload.ChannelID, payload.Frame)
+	if err == nil {
+		return
+	}
+	s.logger.Error("Failed to send IPC frame: %v", err)
+	// A channel that exists reports its own end through onClose
+	if errors.Is(err, ErrChannelNotFound) {
+		s.SendIPCClosed(payload.ChannelID, err)
 	}
 }
@@ -0,0 +1,68 @@
+//
+// Strux Client - Stream Control
+//
+// The control surface the dev server drives log streams through: listing
+// with stats, stopping, pausing and resuming, and reading recent lines.
+// It is built from LogStreamer's public methods only.
+//
+
+package main
+
+import (
+	"fmt"
+	"sort"
+)
+
+// StreamController lists and controls the streams of a LogStreamer
+type StreamController struct {
+	streams *LogStreamer
+}
+
+// NewStreamController creates a controller for streams
+func NewStreamController(streams *LogStreamer) *StreamController {
+	return &StreamController{streams: streams}
+}
+
+// List returns the stats of every active stream, sorted by ID
+func (c *StreamController) List() []StreamStats {
+	ids := c.streams.GetActiveStreams()
+	sort.Strings(ids)
+
+	list := make([]StreamStats, 0, len(ids))
+	for _, id := range ids {
+		// Skip streams that ended since GetActiveStreams
+		if stats, err := c.streams.Stats(id); err == nil {
+			list = append(list, stats)
+		}
+	}
+	return list
+}
+
+// Stop stops a stream, returning ErrStreamNotFound if it is not running
+func (c *StreamController) Stop(streamID string) error {
+	if _, err := c.streams.Stats(streamID); err != nil {
+		return err
+	}
+	c.streams.Stop(streamID)
+	return nil
+}
+
+// Pause stops delivering a stream's lines until Resume
+func (c *StreamController) Pause(streamID string) error {
+	return c.streams.Pause(streamID)
+}
+
+// Resume continues delivering a paused stream's lines
+func (c *StreamController) Resume(streamID string) error {
+	return c.streams.Resume(streamID)
+}
+
+// Recent returns up to n of the stream's most recent lines, oldest first;
+// a non-positive n returns everything retained
+func (c *StreamController) Recent(streamID string, n int) ([]string, error) {
+	lines := c.streams.GetRecent(streamID, n)
+	if lines == nil {
+		return nil, fmt.Errorf("%w: %s", ErrStreamNotFound, streamID)
+	}
+	return lines, nil
+}
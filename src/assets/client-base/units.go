@@ -0,0 +1,128 @@
+//
+// Strux Client - Unit Discovery
+//
+// Lists the systemd services on the device so the dev server's log UI can
+// offer them for service log streams.
+//
+
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// ErrNoSystemd is returned by ListUnits on systems not booted with systemd
+var ErrNoSystemd = errors.New("systemd is not available")
+
+// systemdRuntimeDir exists only while systemd is PID 1 (see sd_booted(3))
+const systemdRuntimeDir = "/run/systemd/system"
+
+// UnitInfo describes one systemd service
+type UnitInfo struct {
+	Name        string `json:"name"`   // e.g. "sshd.service"
+	Load        string `json:"load"`   // "loaded", "not-found", ...
+	Active      string `json:"active"` // "active", "inactive", "failed", ...
+	Sub         string `json:"sub"`    // "running", "exited", "dead", ...
+	Description string `json:"description"`
+}
+
+// ListUnits returns every service unit systemd knows about, sorted by name.
+// It returns ErrNoSystemd where systemd is not running, so callers can hide
+// service selection.
+func (l *LogStreamer) ListUnits() ([]UnitInfo, error) {
+	l.mu.Lock()
+	source := l.source
+	l.mu.Unlock()
+
+	if !fileExists(systemdRuntimeDir) || !source.Available("systemctl") {
+		return nil, ErrNoSystemd
+	}
+
+	// JSON output needs systemd 246; older versions reject it, so fall back
+	// to the plain table
+	output, err := runListUnits(source, "--output=json")
+	var units []UnitInfo
+	if err == nil {
+		units, err = parseUnitsJSON(output)
+	}
+	if err != nil {
+		l.logger.Info("systemctl JSON output unavailable, parsing the table: %v", err)
+		if output, err = runListUnits(source, "--plain", "--no-legend"); err != nil {
+			return nil, err
+		}
+		units = parseUnitsTable(output)
+	}
+
+	sort.Slice(units, func(i, j int) bool { return units[i].Name < units[j].Name })
+	return units, nil
+}
+
+// runListUnits runs systemctl list-units for services with extra arguments
+// and returns its output
+func runListUnits(source LogSource, extra ...string) (string, error) {
+	args := append([]string{"list-units", "--type=service", "--all", "--no-pager"}, extra...)
+	stdout, stderr, err := runOutput(source, "systemctl", args...)
+	if err != nil {
+		if message := strings.TrimSpace(stderr); message != "" {
+			return "", fmt.Errorf("systemctl failed: %s", firstLine(message))
+		}
+		return "", fmt.Errorf("systemctl failed: %w", err)
+	}
+	return stdout, nil
+}
+
+// parseUnitsJSON parses systemctl list-units --output=json
+func parseUnitsJSON(output string) ([]UnitInfo, error) {
+	var entries []struct {
+		Unit        string `json:"unit"`
+		Load        string `json:"load"`
+		Active      string `json:"active"`
+		Sub         string `json:"sub"`
+		Description string `json:"description"`
+	}
+	if err := json.Unmarshal([]byte(output), &entries); err != nil {
+		return nil, err
+	}
+
+	units := make([]UnitInfo, len(entries))
+	for i, entry := range entries {
+		units[i] = UnitInfo{
+			Name:        entry.Unit,
+			Load:        entry.Load,
+			Active:      entry.Active,
+			Sub:         entry.Sub,
+			Description: entry.Description,
+		}
+	}
+	return units, nil
+}
+
+// parseUnitsTable parses systemctl list-units --plain --no-legend: one unit
+// per line as UNIT LOAD ACTIVE SUB DESCRIPTION, the description possibly
+// containing spaces. Failed units may be prefixed with a status marker.
+func parseUnitsTable(output string) []UnitInfo {
+	var units []UnitInfo
+	scanner := bufio.NewScanner(strings.NewReader(output))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) > 0 && !strings.Contains(fields[0], ".") {
+			fields = fields[1:] // "●" marker
+		}
+		if len(fields) < 4 {
+			continue
+		}
+		units = append(units, UnitInfo{
+			Name:        fields[0],
+			Load:        fields[1],
+			Active:      fields[2],
+			Sub:         fields[3],
+			Description: strings.Join(fields[4:], " "),
+		})
+	}
+	return units
+}
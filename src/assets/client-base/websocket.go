@@ -20,14 +20,31 @@
 //	ws.Connect("ws://host:port/ws")
 //	ws.Emit("request-binary", nil)
 //
+// With an outbox enabled (SetOutbox), outbound messages also carry a "seq"
+// number. They are kept until the server answers with {"type": "ack",
+// "payload": {"seq": N}}, queued while disconnected, and replayed in order
+// after a reconnect, so a flaky link loses nothing that fits in the outbox.
+//
+// The client pings the server every keepalive interval. If nothing (pong or
+// message) arrives within interval+timeout, the connection is treated as lost
+// and the reconnect logic takes over, so links silently dropped by NAT or a
+// firewall are noticed. See SetKeepalive.
+//
+// The client offers permessage-deflate during the handshake. Compression is
+// only used if the server accepts it, and applies to text and binary frames
+// alike. See SetCompression.
+//
 
 package main
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
+	"net"
 	"net/http"
 	"net/url"
+	"strings"
 	"sync"
 	"time"
 
@@ -38,8 +55,30 @@ import (
 type Message struct {
 	Type    string          `json:"type"`
 	Payload json.RawMessage `json:"payload,omitempty"`
+	Seq     uint64          `json:"seq,omitempty"`
 }
 
+// ackPayload acknowledges every outbound message up to and including Seq
+type ackPayload struct {
+	Seq uint64 `json:"seq"`
+}
+
+// Default keepalive settings
+const (
+	DefaultPingInterval = 30 * time.Second
+	DefaultPongTimeout  = 10 * time.Second
+)
+
+// Reconnect delays double from the configured delay up to maxReconnectDelay,
+// each shortened by up to reconnectJitter so clients don't retry in lockstep
+const (
+	maxReconnectDelay = 30 * time.Second
+	reconnectJitter   = 0.2
+)
+
+// DefaultOutboxSize is a reasonable number of unacknowledged messages to retain
+const DefaultOutboxSize = 1000
+
 // EventHandler is a function that handles an event with its payload
 type EventHandler func(payload json.RawMessage)
 
@@ -47,6 +86,7 @@ type EventHandler func(payload json.RawMessage)
 type WSClient struct {
 	conn     *websocket.Conn
 	handlers map[string][]EventHandler
+	ordered  map[string][]EventHandler // run on the read loop, see OnInOrder
 	mu       sync.RWMutex
 	connMu   sync.Mutex
 	done     chan struct{}
@@ -58,12 +98,20 @@ type WSClient struct {
 	headers   http.Header
 
 	// Callbacks for connection lifecycle
-	onConnect    func()
-	onDisconnect func()
-	onError      func(error)
+	onConnect         func()
+	onDisconnect      func()
+	onError           func(error)
+	onReconnectFailed func()
+
+	// Sequenced outbound messages awaiting an ack (guarded by connMu)
+	seq        uint64
+	outbox     []Message
+	outboxSize int
 
 	// Configuration
 	pingInterval    time.Duration
+	pongTimeout     time.Duration
+	compression     bool
 	reconnect       bool
 	reconnectDelay  time.Duration
 	maxReconnectTry int
@@ -73,8 +121,11 @@ type WSClient struct {
 func NewWSClient() *WSClient {
 	return &WSClient{
 		handlers:        make(map[string][]EventHandler),
+		ordered:         make(map[string][]EventHandler),
 		logger:          NewLogger("WSClient"),
-		pingInterval:    30 * time.Second,
+		pingInterval:    DefaultPingInterval,
+		pongTimeout:     DefaultPongTimeout,
+		compression:     true,
 		reconnect:       true,
 		reconnectDelay:  2 * time.Second,
 		maxReconnectTry: 5,
@@ -89,11 +140,21 @@ func (w *WSClient) On(eventType string, handler EventHandler) {
 	w.handlers[eventType] = append(w.handlers[eventType], handler)
 }
 
+// OnInOrder registers a handler that runs on the read loop, so events of
+// every type registered this way are handled one at a time in the order they
+// arrived. The handler must not block; hand slow work to another goroutine.
+func (w *WSClient) OnInOrder(eventType string, handler EventHandler) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.ordered[eventType] = append(w.ordered[eventType], handler)
+}
+
 // Off removes all handlers for a specific event type
 func (w *WSClient) Off(eventType string) {
 	w.mu.Lock()
 	defer w.mu.Unlock()
 	delete(w.handlers, eventType)
+	delete(w.ordered, eventType)
 }
 
 // OnConnect sets a callback for when connection is established
@@ -117,6 +178,27 @@ func (w *WSClient) OnError(handler func(error)) {
 	w.onError = handler
 }
 
+// OnReconnectFailed sets a callback for when automatic reconnection gives up
+func (w *WSClient) OnReconnectFailed(handler func()) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.onReconnectFailed = handler
+}
+
+// SetOutbox enables sequencing and retention of up to size unacknowledged
+// outbound messages. When full, the oldest message is dropped. Zero disables it.
+func (w *WSClient) SetOutbox(size int) {
+	w.connMu.Lock()
+	defer w.connMu.Unlock()
+	if size < 0 {
+		size = 0
+	}
+	w.outboxSize = size
+	if len(w.outbox) > size {
+		w.outbox = w.outbox[len(w.outbox)-size:]
+	}
+}
+
 // SetReconnect configures auto-reconnection behavior
 func (w *WSClient) SetReconnect(enabled bool, delay time.Duration, maxRetries int) {
 	w.mu.Lock()
@@ -126,6 +208,31 @@ func (w *WSClient) SetReconnect(enabled bool, delay time.Duration, maxRetries in
 	w.maxReconnectTry = maxRetries
 }
 
+// SetKeepalive configures transport-level pings. A ping is sent every interval;
+// if no pong or message arrives within interval+timeout the connection is
+// dropped and reconnection starts. An interval of zero disables keepalive.
+// Takes effect on the next Connect.
+func (w *WSClient) SetKeepalive(interval, timeout time.Duration) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if interval < 0 {
+		interval = 0
+	}
+	if timeout <= 0 {
+		timeout = DefaultPongTimeout
+	}
+	w.pingInterval = interval
+	w.pongTimeout = timeout
+}
+
+// SetCompression controls whether permessage-deflate is offered during the
+// handshake (default true). The server decides whether it is used.
+func (w *WSClient) SetCompression(enabled bool) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.compression = enabled
+}
+
 // SetHeader sets a header to be sent during the WebSocket handshake
 func (w *WSClient) SetHeader(key, value string) {
 	w.mu.Lock()
@@ -162,26 +269,55 @@ func (w *WSClient) Connect(wsURL string) error {
 	w.url = u.String()
 	w.logger.Info("Connecting to %s...", w.url)
 
-	// Get headers for the connection
+	// Get headers and keepalive settings for the connection
 	w.mu.RLock()
 	headers := w.headers
+	pingInterval := w.pingInterval
+	pongTimeout := w.pongTimeout
+	compression := w.compression
 	w.mu.RUnlock()
 
 	// Dial the WebSocket server with headers
-	conn, _, err := websocket.DefaultDialer.Dial(w.url, headers)
+	dialer := *websocket.DefaultDialer
+	dialer.EnableCompression = compression
+	conn, resp, err := dialer.Dial(w.url, headers)
 	if err != nil {
 		return fmt.Errorf("failed to connect: %w", err)
 	}
 
+	// Compress outgoing messages only if the server accepted the extension
+	if compression && strings.Contains(resp.Header.Get("Sec-WebSocket-Extensions"), "permessage-deflate") {
+		conn.EnableWriteCompression(true)
+		w.logger.Info("Message compression enabled")
+	}
+
 	w.conn = conn
 	w.done = make(chan struct{})
 	w.connected = true
 
+	// Any pong or message proves the link is alive; silence past the
+	// deadline makes ReadMessage fail and triggers a reconnect
+	var readTimeout time.Duration
+	if pingInterval > 0 {
+		readTimeout = pingInterval + pongTimeout
+		conn.SetReadDeadline(time.Now().Add(readTimeout))
+		conn.SetPongHandler(func(string) error {
+			return conn.SetReadDeadline(time.Now().Add(readTimeout))
+		})
+	}
+
+	// Replay whatever the server has not acknowledged, in order
+	if err := w.replayOutboxLocked(); err != nil {
+		w.logger.Warn("Failed to replay outbox: %v", err)
+	}
+
 	// Start the read loop
-	go w.readLoop()
+	go w.readLoop(conn, readTimeout)
 
 	// Start ping loop to keep connection alive
-	go w.pingLoop()
+	if pingInterval > 0 {
+		go w.pingLoop(conn, w.done, pingInterval, pongTimeout)
+	}
 
 	w.logger.Info("Connected to WebSocket server")
 
@@ -241,12 +377,14 @@ func (w *WSClient) IsConnected() bool {
 	return w.connected && w.conn != nil
 }
 
-// Emit sends an event with payload to the server
+// Emit sends an event with payload to the server.
+// With an outbox enabled, a message that cannot be sent now is queued for
+// replay and Emit returns nil.
 func (w *WSClient) Emit(eventType string, payload interface{}) error {
 	w.connMu.Lock()
 	defer w.connMu.Unlock()
 
-	if w.conn == nil {
+	if w.conn == nil && w.outboxSize == 0 {
 		return fmt.Errorf("not connected")
 	}
 
@@ -263,6 +401,56 @@ func (w *WSClient) Emit(eventType string, payload interface{}) error {
 		msg.Payload = payloadBytes
 	}
 
+	if w.outboxSize > 0 {
+		w.seq++
+		msg.Seq = w.seq
+		w.outbox = append(w.outbox, msg)
+		if len(w.outbox) > w.outboxSize {
+			w.outbox = w.outbox[1:]
+		}
+		if w.conn == nil {
+			return nil
+		}
+	}
+
+	if err := w.writeMessageLocked(msg); err != nil {
+		if w.outboxSize > 0 {
+			// Queued; it is replayed once the connection is back
+			return nil
+		}
+		return err
+	}
+
+	return nil
+}
+
+// EmitVolatile sends an event only if the connection is up. It bypasses the
+// outbox, so the message is neither sequenced nor replayed; use it for
+// periodic status that would be stale by the time a reconnect replays it.
+func (w *WSClient) EmitVolatile(eventType string, payload interface{}) error {
+	w.connMu.Lock()
+	defer w.connMu.Unlock()
+
+	if w.conn == nil {
+		return fmt.Errorf("not connected")
+	}
+
+	msg := Message{
+		Type: eventType,
+	}
+	if payload != nil {
+		payloadBytes, err := json.Marshal(payload)
+		if err != nil {
+			return fmt.Errorf("failed to marshal payload: %w", err)
+		}
+		msg.Payload = payloadBytes
+	}
+
+	return w.writeMessageLocked(msg)
+}
+
+// writeMessageLocked marshals and sends a message; w.connMu must be held
+func (w *WSClient) writeMessageLocked(msg Message) error {
 	// Marshal the full message
 	data, err := json.Marshal(msg)
 	if err != nil {
@@ -277,6 +465,37 @@ func (w *WSClient) Emit(eventType string, payload interface{}) error {
 	return nil
 }
 
+// replayOutboxLocked resends every unacknowledged message; w.connMu must be held
+func (w *WSClient) replayOutboxLocked() error {
+	if len(w.outbox) > 0 {
+		w.logger.Info("Replaying %d unacknowledged messages", len(w.outbox))
+	}
+	for _, msg := range w.outbox {
+		if err := w.writeMessageLocked(msg); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// handleAck drops every outbox message the server has acknowledged
+func (w *WSClient) handleAck(payload json.RawMessage) {
+	var ack ackPayload
+	if err := json.Unmarshal(payload, &ack); err != nil {
+		w.logger.Warn("Failed to parse ack: %v", err)
+		return
+	}
+
+	w.connMu.Lock()
+	defer w.connMu.Unlock()
+
+	i := 0
+	for i < len(w.outbox) && w.outbox[i].Seq <= ack.Seq {
+		i++
+	}
+	w.outbox = w.outbox[i:]
+}
+
 // EmitWithAck sends an event and waits for an acknowledgment
 // The ack event type is expected to be eventType + "-ack"
 func (w *WSClient) EmitWithAck(eventType string, payload interface{}, timeout time.Duration) (json.RawMessage, error) {
@@ -306,8 +525,10 @@ func (w *WSClient) EmitWithAck(eventType string, payload interface{}, timeout ti
 	}
 }
 
-// readLoop reads messages from the WebSocket and dispatches to handlers
-func (w *WSClient) readLoop() {
+// readLoop reads messages from the WebSocket and dispatches to handlers.
+// readTimeout is the keepalive deadline extended after every message (zero when
+// keepalive is disabled).
+func (w *WSClient) readLoop(conn *websocket.Conn, readTimeout time.Duration) {
 	defer func() {
 		w.connMu.Lock()
 		if w.conn != nil {
@@ -345,13 +566,18 @@ func (w *WSClient) readLoop() {
 		}
 
 		// Read message
-		_, data, err := w.conn.ReadMessage()
+		_, data, err := conn.ReadMessage()
 		if err != nil {
 			if websocket.IsCloseError(err, websocket.CloseNormalClosure, websocket.CloseGoingAway) {
 				w.logger.Info("Connection closed normally")
 				return
 			}
-			w.logger.Error("Read error: %v", err)
+			var netErr net.Error
+			if errors.As(err, &netErr) && netErr.Timeout() {
+				w.logger.Error("No response from server within %s, treating connection as lost", readTimeout)
+			} else {
+				w.logger.Error("Read error: %v", err)
+			}
 
 			// Trigger error callback
 			w.mu.RLock()
@@ -363,6 +589,10 @@ func (w *WSClient) readLoop() {
 			return
 		}
 
+		if readTimeout > 0 {
+			conn.SetReadDeadline(time.Now().Add(readTimeout))
+		}
+
 		// Parse message
 		var msg Message
 		if err := json.Unmarshal(data, &msg); err != nil {
@@ -370,6 +600,12 @@ func (w *WSClient) readLoop() {
 			continue
 		}
 
+		// Acks belong to the transport, not to event handlers
+		if msg.Type == "ack" {
+			w.handleAck(msg.Payload)
+			continue
+		}
+
 		// Dispatch to handlers
 		w.dispatch(msg.Type, msg.Payload)
 	}
@@ -379,34 +615,32 @@ func (w *WSClient) readLoop() {
 func (w *WSClient) dispatch(eventType string, payload json.RawMessage) {
 	w.mu.RLock()
 	handlers := w.handlers[eventType]
+	ordered := w.ordered[eventType]
 	w.mu.RUnlock()
 
-	if len(handlers) == 0 {
-		return
+	for _, handler := range ordered {
+		handler(payload)
 	}
-
 	for _, handler := range handlers {
 		go handler(payload)
 	}
 }
 
-// pingLoop sends periodic ping messages to keep the connection alive
-func (w *WSClient) pingLoop() {
-	ticker := time.NewTicker(w.pingInterval)
+// pingLoop sends periodic ping messages to keep the connection alive.
+// Pings go out as control frames, which gorilla/websocket allows concurrently
+// with other writes, each bounded by timeout.
+func (w *WSClient) pingLoop(conn *websocket.Conn, done chan struct{}, interval, timeout time.Duration) {
+	ticker := time.NewTicker(interval)
 	defer ticker.Stop()
 
 	for {
 		select {
-		case <-w.done:
+		case <-done:
 			return
 		case <-ticker.C:
-			w.connMu.Lock()
-			if w.conn != nil {
-				if err := w.conn.WriteMessage(websocket.PingMessage, nil); err != nil {
-					w.logger.Warn("Ping failed: %v", err)
-				}
+			if err := conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(timeout)); err != nil {
+				w.logger.Warn("Ping failed: %v", err)
 			}
-			w.connMu.Unlock()
 		}
 	}
 }
@@ -415,26 +649,27 @@ func (w *WSClient) pingLoop() {
 func (w *WSClient) attemptReconnect() {
 	w.mu.RLock()
 	maxRetries := w.maxReconnectTry
-	delay := w.reconnectDelay
+	backoff := Backoff{Base: w.reconnectDelay, Max: maxReconnectDelay, Jitter: reconnectJitter}
 	url := w.url
 	w.mu.RUnlock()
 
 	for i := 0; i < maxRetries; i++ {
 		w.logger.Info("Reconnection attempt %d/%d...", i+1, maxRetries)
 
-		time.Sleep(delay)
+		time.Sleep(backoff.Delay(i))
 
 		if err := w.Connect(url); err == nil {
 			w.logger.Info("Reconnected successfully")
 			return
 		}
-
-		// Exponential backoff
-		delay = delay * 2
-		if delay > 30*time.Second {
-			delay = 30 * time.Second
-		}
 	}
 
 	w.logger.Error("Failed to reconnect after %d attempts", maxRetries)
+
+	w.mu.RLock()
+	onReconnectFailed := w.onReconnectFailed
+	w.mu.RUnlock()
+	if onReconnectFailed != nil {
+		go onReconnectFailed()
+	}
 }
@@ -204,6 +204,25 @@ connect_async_ipc (void)
     return TRUE;
 }
 
+// Decode a base64 result (binary return from Go) into a Uint8Array
+static JSCValue*
+base64_to_uint8array (JSCContext *context, const gchar *base64)
+{
+    JSCValue *decoder = jsc_context_evaluate(context,
+        "(function(s) {"
+        "  const bin = atob(s);"
+        "  const bytes = new Uint8Array(bin.length);"
+        "  for (let i = 0; i < bin.length; i++) bytes[i] = bin.charCodeAt(i);"
+        "  return bytes;"
+        "})", -1);
+    JSCValue *arg = jsc_value_new_string(context, base64);
+    JSCValue *result = jsc_value_function_call(decoder, JSC_TYPE_VALUE, arg, G_TYPE_NONE);
+
+    g_object_unref(arg);
+    g_object_unref(decoder);
+    return result;
+}
+
 // Callback for async read completion
 static void
 async_read_callback (GObject *source_object, GAsyncResult *res, gpointer user_data)
@@ -276,7 +295,14 @@ async_read_callback (GObject *source_object, GAsyncResult *res, gpointer user_da
                 if (node_type == JSON_NODE_VALUE) {
                     // Handle primitive types
                     if (json_node_get_value_type(result_node) == G_TYPE_STRING) {
-                        result = jsc_value_new_string(promise->context, json_node_get_string(result_node));
+                        // Binary results ([]byte, io.Reader) arrive base64-encoded
+                        const gchar *encoding = json_object_has_member(response_obj, "encoding")
+                            ? json_object_get_string_member(response_obj, "encoding") : NULL;
+                        if (encoding && strcmp(encoding, "base64") == 0) {
+                            result = base64_to_uint8array(promise->context, json_node_get_string(result_node));
+                        } else {
+                            result = jsc_value_new_string(promise->context, json_node_get_string(result_node));
+                        }
                     } else if (json_node_get_value_type(result_node) == G_TYPE_DOUBLE ||
                                json_node_get_value_type(result_node) == G_TYPE_INT64) {
                         result = jsc_value_new_number(promise->context, json_node_get_double(result_node));
@@ -929,12 +955,16 @@ inject_bindings (JSCContext *js_context)
                                 JsonObject *method_info = json_array_get_object_element(methods, i);
                                 const gchar *method_name = json_object_get_string_member(method_info, "name");
 
+                                // Methods of objects bound with BindObject are called as "object.Method"
+                                const gchar *call_name = json_object_has_member(method_info, "call")
+                                    ? json_object_get_string_member(method_info, "call") : method_name;
+
                                 // Create JavaScript function with variadic callback
                                 JSCValue *func = jsc_value_new_function_variadic(
                                     js_context,
                                     method_name,
                                     G_CALLBACK(go_method_callback_variadic),
-                                    g_strdup(method_name),
+                                    g_strdup(call_name),
                                     (GDestroyNotify)g_free,
                                     JSC_TYPE_VALUE
                                 );
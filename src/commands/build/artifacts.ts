@@ -65,6 +65,30 @@ import clientGoExec from "../../assets/client-base/exec.go" with { type: "text"
 // @ts-ignore
 import clientGoWebsocket from "../../assets/client-base/websocket.go" with {type: "text"}
 // @ts-ignore
+import clientGoLogSource from "../../assets/client-base/logsource.go" with { type: "text" }
+// @ts-ignore
+import clientGoPTY from "../../assets/client-base/pty.go" with { type: "text" }
+// @ts-ignore
+import clientGoLogRecord from "../../assets/client-base/logrecord.go" with { type: "text" }
+// @ts-ignore
+import clientGoIPC from "../../assets/client-base/ipc.go" with { type: "text" }
+// @ts-ignore
+import clientGoFileWatchLinux from "../../assets/client-base/filewatch_linux.go" with { type: "text" }
+// @ts-ignore
+import clientGoFileWatchOther from "../../assets/client-base/filewatch_other.go" with { type: "text" }
+// @ts-ignore
+import clientGoBackoff from "../../assets/client-base/backoff.go" with { type: "text" }
+// @ts-ignore
+import clientGoUnits from "../../assets/client-base/units.go" with { type: "text" }
+// @ts-ignore
+import clientGoAudit from "../../assets/client-base/audit.go" with { type: "text" }
+// @ts-ignore
+import clientGoStreams from "../../assets/client-base/streams.go" with { type: "text" }
+// @ts-ignore
+import clientGoDiagnostics from "../../assets/client-base/diagnostics.go" with { type: "text" }
+// @ts-ignore
+import clientGoHeartbeat from "../../assets/client-base/heartbeat.go" with { type: "text" }
+// @ts-ignore
 import clientGoMod from "../../assets/client-base/go.mod" with { type: "text" }
 // @ts-ignore
 import clientGoSum from "../../assets/client-base/go.sum" with { type: "text" }
@@ -100,6 +124,14 @@ import cageIdleInhibit from "../../assets/cage-base/idle_inhibit_v1.c" with { ty
 // @ts-ignore
 import cageIdleInhibitH from "../../assets/cage-base/idle_inhibit_v1.h" with { type: "text" }
 // @ts-ignore
+import cageControl from "../../assets/cage-base/control.c" with { type: "text" }
+// @ts-ignore
+import cageControlH from "../../assets/cage-base/control.h" with { type: "text" }
+// @ts-ignore
+import cageDpms from "../../assets/cage-base/dpms.c" with { type: "text" }
+// @ts-ignore
+import cageDpmsH from "../../assets/cage-base/dpms.h" with { type: "text" }
+// @ts-ignore
 import cageSplash from "../../assets/cage-base/splash.c" with { type: "text" }
 // @ts-ignore
 import cageSplashH from "../../assets/cage-base/splash.h" with { type: "text" }
@@ -240,6 +272,18 @@ export async function copyClientBaseFiles(clientSrcPath: string): Promise<void>
         await Bun.write(join(clientSrcPath, "helpers.go"), clientGoHelpers)
         await Bun.write(join(clientSrcPath, "exec.go"), clientGoExec)
         await Bun.write(join(clientSrcPath, "websocket.go"), clientGoWebsocket)
+        await Bun.write(join(clientSrcPath, "logsource.go"), clientGoLogSource)
+        await Bun.write(join(clientSrcPath, "pty.go"), clientGoPTY)
+        await Bun.write(join(clientSrcPath, "logrecord.go"), clientGoLogRecord)
+        await Bun.write(join(clientSrcPath, "ipc.go"), clientGoIPC)
+        await Bun.write(join(clientSrcPath, "filewatch_linux.go"), clientGoFileWatchLinux)
+        await Bun.write(join(clientSrcPath, "filewatch_other.go"), clientGoFileWatchOther)
+        await Bun.write(join(clientSrcPath, "backoff.go"), clientGoBackoff)
+        await Bun.write(join(clientSrcPath, "units.go"), clientGoUnits)
+        await Bun.write(join(clientSrcPath, "audit.go"), clientGoAudit)
+        await Bun.write(join(clientSrcPath, "streams.go"), clientGoStreams)
+        await Bun.write(join(clientSrcPath, "diagnostics.go"), clientGoDiagnostics)
+        await Bun.write(join(clientSrcPath, "heartbeat.go"), clientGoHeartbeat)
         await Bun.write(join(clientSrcPath, "go.mod"), clientGoMod)
         await Bun.write(join(clientSrcPath, "go.sum"), clientGoSum)
         return
@@ -272,6 +316,10 @@ export async function copyCageSourceFiles(cageSrcPath: string): Promise<void> {
         await Bun.write(join(cageSrcPath, "xwayland.h"), cageXwaylandH)
         await Bun.write(join(cageSrcPath, "idle_inhibit_v1.c"), cageIdleInhibit)
         await Bun.write(join(cageSrcPath, "idle_inhibit_v1.h"), cageIdleInhibitH)
+        await Bun.write(join(cageSrcPath, "control.c"), cageControl)
+        await Bun.write(join(cageSrcPath, "control.h"), cageControlH)
+        await Bun.write(join(cageSrcPath, "dpms.c"), cageDpms)
+        await Bun.write(join(cageSrcPath, "dpms.h"), cageDpmsH)
         await Bun.write(join(cageSrcPath, "splash.c"), cageSplash)
         await Bun.write(join(cageSrcPath, "splash.h"), cageSplashH)
         await Bun.write(join(cageSrcPath, "server.h"), cageServerH)
@@ -105,6 +105,14 @@ import cageIdleInhibit from "../../assets/cage-base/idle_inhibit_v1.c" with { ty
 // @ts-ignore
 import cageIdleInhibitH from "../../assets/cage-base/idle_inhibit_v1.h" with { type: "text" }
 // @ts-ignore
+import cageControl from "../../assets/cage-base/control.c" with { type: "text" }
+// @ts-ignore
+import cageControlH from "../../assets/cage-base/control.h" with { type: "text" }
+// @ts-ignore
+import cageDpms from "../../assets/cage-base/dpms.c" with { type: "text" }
+// @ts-ignore
+import cageDpmsH from "../../assets/cage-base/dpms.h" with { type: "text" }
+// @ts-ignore
 import cageSplash from "../../assets/cage-base/splash.c" with { type: "text" }
 // @ts-ignore
 import cageSplashH from "../../assets/cage-base/splash.h" with { type: "text" }
@@ -193,6 +201,10 @@ export function computeInternalAssetHashes(): Record<string, string> {
             cageXwaylandH,
             cageIdleInhibit,
             cageIdleInhibitH,
+            cageControl,
+            cageControlH,
+            cageDpms,
+            cageDpmsH,
             cageSplash,
             cageSplashH,
             cageServerH,
@@ -152,6 +152,17 @@ export async function updateDevEnvConfig(bspName: string): Promise<void> {
     const bspCacheDir = join(Settings.projectPath, "dist", "cache", bspName)
     const devEnvPath = join(bspCacheDir, ".dev-env.json")
 
+    const server = Settings.main?.dev?.server
+    const keepalive = server?.keepalive_interval !== undefined || server?.keepalive_timeout !== undefined
+        ? {
+            intervalSeconds: server?.keepalive_interval ?? 30,
+            timeoutSeconds: server?.keepalive_timeout ?? 10,
+        }
+        : undefined
+    const heartbeat = server?.heartbeat_interval !== undefined
+        ? { intervalSeconds: server.heartbeat_interval }
+        : undefined
+
     const devEnvJSON = {
         clientKey: Settings.main?.dev?.server?.client_key ?? "",
         useMDNS: Settings.main?.dev?.server?.use_mdns_on_client ?? true,
@@ -161,6 +172,8 @@ export async function updateDevEnvConfig(bspName: string): Promise<void> {
             enabled: Settings.main?.dev?.inspector?.enabled ?? false,
             port: Settings.main?.dev?.inspector?.port ?? 9223,
         },
+        keepalive,
+        heartbeat,
     }
     await Bun.write(devEnvPath, JSON.stringify(devEnvJSON, null, 2))
 }
@@ -363,6 +363,7 @@ export async function dev(): Promise<void> {
     devServer = createDevServer({
         port: serverPort,
         clientKey,
+        compression: Settings.main?.dev?.server?.compression ?? false,
         onClientConnected: () => {
 
             Logger.success("Device connected to dev server")
@@ -396,6 +397,13 @@ export async function dev(): Promise<void> {
             devUI?.setConsoleSessionActive(false)
             devUI?.setConsoleInputMode(false)
 
+        },
+        onHealthChange: (health) => {
+
+            // Still connected, but heartbeats have stopped or come back
+            const state = health.healthy ? "Connected" : "Unresponsive"
+            devUI?.setStatus(`${state} | ${Settings.isRemoteOnly ? "remote" : "qemu"} | port ${serverPort}`)
+
         },
         onBinaryRequested: async () => {
 
@@ -14,18 +14,41 @@
  *
  *  Client -> Server Events:
  *  - "request-binary": Request the current binary (no payload)
- *  - "log-line": Send a log line { streamId, line, service?, timestamp }
+ *  - "log-line": Send a log line { streamId, line, service?, timestamp, seq?, restart? }
  *  - "log-stream-error": Send an error { streamId, error }
+ *  - "log-stream-complete": A limited stream ended on its own { streamId, reason: "limit" | "exited" }
  *  - "exec-output": Send console output { sessionId, stream, data }
  *  - "exec-exit": Send console exit { sessionId, code }
  *  - "exec-error": Send console error { sessionId, error }
+ *  - "ipc-frame": A frame from the app's IPC bridge { channelId, frame }
+ *  - "ipc-closed": An IPC channel ended { channelId, error? }
+ *  - "units": The device's systemd services, answering list-units { units, error?, code? }
+ *  - "exec-shells": The shells the device has, answering exec-probe { shells, error?, code? }
+ *  - "diagnostics": A tar.gz of the journal, app and cage logs, answering collect-diagnostics { data, error? } (base64 encoded)
+ *  - "streams": The active log streams, answering list-streams { streams }
+ *  - "recent-logs": A stream's recent lines, answering get-recent-logs { streamId, lines, error?, code? }
+ *  - "client-resume": Streams, sessions and IPC channels still active after a reconnect { streams, sessions, channels }
+ *  - "heartbeat": Device vitals sent every few seconds { interval, uptime, processUptime, memTotal, memAvailable, heapAlloc, goroutines, streams, sessions, channels }
+ *    Unsequenced; the client is marked unhealthy after HEARTBEAT_MISSES intervals without one
  *
  *  Server -> Client Events:
  *  - "new-binary": Send binary update { data: string } (base64 encoded)
- *  - "start-logs": Start log streaming { streamId, type, service? }
+ *  - "start-logs": Start log streaming { streamId, type, service?, path?, command?, args?, maxLines?, maxBytes?, sequenced? }
  *  - "stop-logs": Stop log streaming { streamId }
- *  - "exec-start": Start interactive shell { sessionId, shell? }
- *  - "exec-input": Send input { sessionId, data }
+ *  - "pause-logs" / "resume-logs": Hold or continue a stream's lines { streamId }
+ *  - "list-streams": List the active log streams with their stats (no payload)
+ *  - "get-recent-logs": Read a stream's most recent lines { streamId, count? }
+ *  - "list-units": List the device's systemd services (no payload)
+ *  - "collect-diagnostics": Gather recent logs into an archive for a bug report { journalLines?, maxBytes? }
+ *  - "exec-start": Start interactive shell { sessionId, shell?, cols?, rows? }
+ *  - "exec-probe": Ask which shells the device has, before offering a terminal (no payload)
+ *  - "exec-input": Send input { sessionId, data, encoding? } (encoding "base64" for raw bytes)
+ *  - "exec-pause": Hold a session's output without stopping its shell { sessionId }
+ *  - "exec-resume": Deliver held output and continue { sessionId }
+ *  - "ipc-open": Connect a channel to the app's IPC bridge { channelId }
+ *  - "ipc-frame": Send a bridge frame on a channel { channelId, frame }
+ *  - "ipc-close": Close an IPC channel { channelId }
+ *  - "ack": Acknowledge a sequenced client message { seq }
  *
  */
 
@@ -45,6 +68,14 @@ import { Logger } from "../../utils/log"
 interface Message {
     type: string
     payload?: unknown
+    seq?: number  // Set by clients that replay unacknowledged messages after reconnecting
+}
+
+
+interface ResumePayload {
+    streams: string[]
+    sessions: string[]
+    channels?: string[]  // IPC channels
 }
 
 
@@ -55,8 +86,20 @@ interface BinaryPayload {
 
 interface StartLogsPayload {
     streamId: string
-    type: "journalctl" | "service" | "app" | "cage" | "early"
+    type: "journalctl" | "service" | "app" | "cage" | "early" | "snapshot" | "file" | "command"
     service?: string
+    path?: string        // absolute path of the file to tail, for "file" streams
+    command?: string     // command to follow, for "command" streams
+    args?: string[]      // its arguments
+    maxLines?: number
+    maxBytes?: number
+    sequenced?: boolean  // number each line so gaps can be detected
+}
+
+
+interface LogCompletePayload {
+    streamId: string
+    reason: "limit" | "exited"
 }
 
 
@@ -70,23 +113,36 @@ interface LogLinePayload {
     line: string
     service?: string
     timestamp: string
+    seq?: number       // per-stream line number on sequenced streams
+    restart?: boolean  // marks where a restarted stream continued numbering
 }
 
 
 interface LogErrorPayload {
     streamId: string
     error: string
+    code?: "exists" | "not_found" | "no_backend" | "permission_denied"  // set for errors the client classifies
 }
 
 
 interface ExecStartPayload {
     sessionId: string
     shell?: string
+    lang?: string        // LANG for the shell (default: inherited, else C.UTF-8)
+    lcAll?: string       // LC_ALL for the shell
+    trueColor?: boolean  // set COLORTERM=truecolor
+    cols?: number        // terminal size to start the shell at, so the
+    rows?: number        // first frame of full-screen programs fits
 }
 
 interface ExecInputPayload {
     sessionId: string
     data: string
+    encoding?: "base64"  // data is base64-encoded raw bytes
+}
+
+interface ExecFlowPayload {
+    sessionId: string
 }
 
 interface ExecOutputPayload {
@@ -103,6 +159,96 @@ interface ExecExitPayload {
 interface ExecErrorPayload {
     sessionId: string
     error: string
+    code?: "exists" | "not_found" | "no_backend" | "permission_denied"  // set for errors the client classifies
+}
+
+interface IPCChannelPayload {
+    channelId: string
+}
+
+interface IPCFramePayload {
+    channelId: string
+    frame: unknown  // a bridge message, passed through unchanged
+}
+
+interface IPCClosedPayload {
+    channelId: string
+    error?: string
+}
+
+interface UnitInfo {
+    name: string         // e.g. "sshd.service"
+    load: string         // "loaded", "not-found", ...
+    active: string       // "active", "inactive", "failed", ...
+    sub: string          // "running", "exited", "dead", ...
+    description: string
+}
+
+interface UnitsPayload {
+    units: UnitInfo[]
+    error?: string
+    code?: "no_systemd"  // the device has no systemd; hide service logs
+}
+
+interface ExecShellsPayload {
+    shells: string[]     // available shells, most preferred first
+    error?: string
+    code?: "no_shell"    // the device has no shell; disable the terminal
+}
+
+interface CollectDiagnosticsPayload {
+    journalLines?: number   // recent journal lines to include
+    maxBytes?: number       // cap for each log in the archive
+}
+
+interface DiagnosticsPayload {
+    data: string         // base64 tar.gz; unreadable logs are listed in its errors.txt
+    error?: string       // set when no archive could be made
+}
+
+interface StreamStats {
+    id: string
+    type: "command" | "file"
+    service?: string
+    lines: number        // lines delivered so far
+    bytes: number
+    paused: boolean
+}
+
+interface StreamsPayload {
+    streams: StreamStats[]
+}
+
+interface RecentLogsRequestPayload {
+    streamId: string
+    count?: number       // omit for everything the client retains
+}
+
+interface RecentLogsPayload {
+    streamId: string
+    lines: string[]
+    error?: string
+    code?: "not_found"
+}
+
+interface HeartbeatPayload {
+    interval: number        // seconds until the next heartbeat
+    uptime: number          // device uptime in seconds (0 if unknown)
+    processUptime: number   // client uptime in seconds
+    memTotal: number        // bytes (0 if unknown)
+    memAvailable: number    // bytes (0 if unknown)
+    heapAlloc: number       // bytes allocated by the client itself
+    goroutines: number
+    streams: number         // active log streams
+    sessions: number        // active exec sessions
+    channels: number        // open IPC channels
+}
+
+interface ClientHealth {
+    connected: boolean
+    healthy: boolean                    // connected and heartbeats arriving on time
+    lastHeartbeat: HeartbeatPayload | null
+    lastHeartbeatAt: number | null      // ms since the epoch
 }
 
 interface BinaryAckPayload {
@@ -116,6 +262,8 @@ interface BinaryAckPayload {
 interface DevServerOptions {
     port: number
     clientKey: string
+    // Accept permessage-deflate from the device to shrink log and exec traffic
+    compression?: boolean
     onClientConnected?: () => void
     onClientDisconnected?: () => void
     onBinaryRequested?: () => void
@@ -125,9 +273,23 @@ interface DevServerOptions {
     onExecOutput?: (payload: ExecOutputPayload) => void
     onExecExit?: (payload: ExecExitPayload) => void
     onExecError?: (payload: ExecErrorPayload) => void
+    onIPCFrame?: (payload: IPCFramePayload) => void
+    onIPCClosed?: (payload: IPCClosedPayload) => void
+    onUnits?: (payload: UnitsPayload) => void
+    onExecShells?: (payload: ExecShellsPayload) => void
+    onDiagnostics?: (payload: DiagnosticsPayload) => void
+    onStreams?: (payload: StreamsPayload) => void
+    onRecentLogs?: (payload: RecentLogsPayload) => void
+    onHeartbeat?: (payload: HeartbeatPayload) => void
+    // Called when heartbeats start arriving or stop while still connected
+    onHealthChange?: (health: ClientHealth) => void
 }
 
 
+// Heartbeat intervals that may pass without one before the client is marked unhealthy
+const HEARTBEAT_MISSES = 3
+
+
 interface WebSocketData {
     authenticated: boolean
     clientKey: string
@@ -154,6 +316,18 @@ export class DevServer {
 
     private bonjourService: Service | null = null
 
+    private lastHeartbeat: HeartbeatPayload | null = null
+
+    private lastHeartbeatAt: number | null = null
+
+    private healthy = false
+
+    private heartbeatTimer: ReturnType<typeof setTimeout> | null = null
+
+    // Highest seq handled from the client. It outlives a connection, since a
+    // replay after reconnecting is what it guards against.
+    private lastClientSeq = 0
+
 
     constructor(options: DevServerOptions) {
 
@@ -201,9 +375,13 @@ export class DevServer {
                 // Health check endpoint
                 if (url.pathname === "/health") {
 
+                    const health = self.getClientHealth()
+
                     return new Response(JSON.stringify({
                         status: "ok",
-                        clientConnected: self.isClientConnected()
+                        clientConnected: health.connected,
+                        clientHealthy: health.healthy,
+                        clientVitals: health.lastHeartbeat
                     }), {
                         headers: { "Content-Type": "application/json" }
                     })
@@ -216,6 +394,8 @@ export class DevServer {
 
             websocket: {
 
+                perMessageDeflate: self.options.compression ?? false,
+
                 open(ws) {
 
                     self.handleOpen(ws)
@@ -251,6 +431,8 @@ export class DevServer {
         // Stop Bonjour advertisement first
         this.stopBonjourAdvertisement()
 
+        this.resetHealth()
+
         if (this.server) {
 
             this.server.stop()
@@ -329,6 +511,18 @@ export class DevServer {
     }
 
 
+    public getClientHealth(): ClientHealth {
+
+        return {
+            connected: this.client !== null,
+            healthy: this.client !== null && this.healthy,
+            lastHeartbeat: this.lastHeartbeat,
+            lastHeartbeatAt: this.lastHeartbeatAt,
+        }
+
+    }
+
+
     // -----------------------------------------
     //  WebSocket Handlers
     // -----------------------------------------
@@ -404,6 +598,29 @@ export class DevServer {
 
         }
 
+        // Acknowledge sequenced messages so the client can drop them from its replay buffer
+        if (typeof msg.seq === "number") {
+
+            this.emit("ack", { seq: msg.seq })
+
+            // A restarted client numbers from 1 again
+            if (msg.seq === 1) {
+
+                this.lastClientSeq = 0
+
+            }
+
+            // Already handled; the ack for it was lost with the connection
+            if (msg.seq <= this.lastClientSeq) {
+
+                return
+
+            }
+
+            this.lastClientSeq = msg.seq
+
+        }
+
         // Dispatch to event handler
         this.dispatchEvent(msg.type, msg.payload)
 
@@ -419,6 +636,9 @@ export class DevServer {
             // Clear all active log streams
             this.activeLogStreams.clear()
 
+            // A disconnect is reported as such, not as a health change
+            this.resetHealth()
+
             Logger.warning(`Client disconnected (code: ${code}, reason: ${reason || "none"})`)
 
             // Notify callback
@@ -453,6 +673,10 @@ export class DevServer {
                 this.handleLogError(payload as LogErrorPayload)
                 break
 
+            case "log-stream-complete":
+                this.handleLogComplete(payload as LogCompletePayload)
+                break
+
             case "binary-ack":
                 this.handleBinaryAck(payload as BinaryAckPayload)
                 break
@@ -465,6 +689,33 @@ export class DevServer {
             case "exec-error":
                 this.handleExecError(payload as ExecErrorPayload)
                 break
+            case "ipc-frame":
+                this.handleIPCFrame(payload as IPCFramePayload)
+                break
+            case "ipc-closed":
+                this.handleIPCClosed(payload as IPCClosedPayload)
+                break
+            case "units":
+                this.handleUnits(payload as UnitsPayload)
+                break
+            case "exec-shells":
+                this.handleExecShells(payload as ExecShellsPayload)
+                break
+            case "diagnostics":
+                this.handleDiagnostics(payload as DiagnosticsPayload)
+                break
+            case "streams":
+                this.handleStreams(payload as StreamsPayload)
+                break
+            case "recent-logs":
+                this.handleRecentLogs(payload as RecentLogsPayload)
+                break
+            case "client-resume":
+                this.handleClientResume(payload as ResumePayload)
+                break
+            case "heartbeat":
+                this.handleHeartbeat(payload as HeartbeatPayload)
+                break
 
             default:
                 Logger.warning(`Unknown event type: ${eventType}`)
@@ -499,6 +750,15 @@ export class DevServer {
     }
 
 
+    private handleClientResume(payload: ResumePayload): void {
+
+        const channels = payload.channels?.length ?? 0
+
+        Logger.log(`Client resumed with ${payload.streams.length} log stream(s), ${payload.sessions.length} exec session(s) and ${channels} IPC channel(s)`)
+
+    }
+
+
     private handleLogLine(payload: LogLinePayload): void {
         if (this.options.onLogLine) {
             this.options.onLogLine(payload)
@@ -533,6 +793,15 @@ export class DevServer {
     }
 
 
+    private handleLogComplete(payload: LogCompletePayload): void {
+
+        this.activeLogStreams.delete(payload.streamId)
+
+        Logger.log(`Log stream ${payload.streamId} ended (${payload.reason === "limit" ? "limit reached" : "source exited"})`)
+
+    }
+
+
     private handleLogError(payload: LogErrorPayload): void {
         if (this.options.onLogError) {
             this.options.onLogError(payload)
@@ -599,6 +868,137 @@ export class DevServer {
         Logger.error(`Console error (${payload.sessionId}): ${payload.error}`)
     }
 
+    private handleIPCFrame(payload: IPCFramePayload): void {
+        if (this.options.onIPCFrame) {
+            this.options.onIPCFrame(payload)
+        }
+    }
+
+    private handleUnits(payload: UnitsPayload): void {
+        if (this.options.onUnits) {
+            this.options.onUnits(payload)
+            return
+        }
+
+        if (payload.error && payload.code !== "no_systemd") {
+            Logger.warning(`Failed to list units: ${payload.error}`)
+        }
+    }
+
+    private async handleDiagnostics(payload: DiagnosticsPayload): Promise<void> {
+        if (this.options.onDiagnostics) {
+            this.options.onDiagnostics(payload)
+            return
+        }
+
+        if (payload.error) {
+            Logger.error(`Failed to collect diagnostics: ${payload.error}`)
+            return
+        }
+
+        // Without a handler, save the archive next to the project
+        const stamp = new Date().toISOString().replace(/[:.]/g, "-")
+        const path = `strux-diagnostics-${stamp}.tar.gz`
+        try {
+            await Bun.write(path, Buffer.from(payload.data, "base64"))
+            Logger.success(`Saved diagnostics to ${path}`)
+        } catch (err) {
+            Logger.error(`Failed to save diagnostics: ${err instanceof Error ? err.message : String(err)}`)
+        }
+    }
+
+    private handleExecShells(payload: ExecShellsPayload): void {
+        if (this.options.onExecShells) {
+            this.options.onExecShells(payload)
+            return
+        }
+
+        if (payload.error && payload.code !== "no_shell") {
+            Logger.warning(`Failed to probe shells: ${payload.error}`)
+        }
+    }
+
+    private handleStreams(payload: StreamsPayload): void {
+        if (this.options.onStreams) {
+            this.options.onStreams(payload)
+        }
+    }
+
+    private handleRecentLogs(payload: RecentLogsPayload): void {
+        if (this.options.onRecentLogs) {
+            this.options.onRecentLogs(payload)
+            return
+        }
+
+        if (payload.error) {
+            Logger.warning(`Failed to read recent logs for ${payload.streamId}: ${payload.error}`)
+        }
+    }
+
+    private handleHeartbeat(payload: HeartbeatPayload): void {
+        const recovered = !this.healthy && this.lastHeartbeatAt !== null
+
+        this.lastHeartbeat = payload
+        this.lastHeartbeatAt = Date.now()
+
+        if (this.options.onHeartbeat) {
+            this.options.onHeartbeat(payload)
+        }
+
+        if (!this.healthy) {
+            if (recovered) {
+                Logger.success("Client heartbeats resumed")
+            }
+            this.setHealthy(true)
+        }
+
+        // Expect the next one within a few intervals; the client says how long one is
+        if (this.heartbeatTimer) {
+            clearTimeout(this.heartbeatTimer)
+            this.heartbeatTimer = null
+        }
+        if (!(payload.interval > 0)) {
+            return
+        }
+        const timeout = payload.interval * HEARTBEAT_MISSES * 1000
+        this.heartbeatTimer = setTimeout(() => {
+            this.heartbeatTimer = null
+            if (this.client === null) {
+                return
+            }
+            Logger.warning(`No heartbeat from client for ${timeout / 1000}s; it may be hung`)
+            this.setHealthy(false)
+        }, timeout)
+    }
+
+    private setHealthy(healthy: boolean): void {
+        this.healthy = healthy
+        if (this.options.onHealthChange) {
+            this.options.onHealthChange(this.getClientHealth())
+        }
+    }
+
+    private resetHealth(): void {
+        if (this.heartbeatTimer) {
+            clearTimeout(this.heartbeatTimer)
+            this.heartbeatTimer = null
+        }
+        this.healthy = false
+        this.lastHeartbeat = null
+        this.lastHeartbeatAt = null
+    }
+
+    private handleIPCClosed(payload: IPCClosedPayload): void {
+        if (this.options.onIPCClosed) {
+            this.options.onIPCClosed(payload)
+            return
+        }
+
+        if (payload.error) {
+            Logger.warning(`IPC channel ${payload.channelId} closed: ${payload.error}`)
+        }
+    }
+
 
     // -----------------------------------------
     //  Server -> Client Events
@@ -670,18 +1070,81 @@ export class DevServer {
     }
 
 
+    /**
+     * Tail an arbitrary log file on the client. The file may not exist yet;
+     * the client waits for it, as it does for the app and Cage logs.
+     *
+     * @param streamId - Unique identifier for this log stream
+     * @param path - Absolute path of the file on the device
+     * @returns true if the event was sent successfully
+     */
+    public startFileLogStream(streamId: string, path: string): boolean {
+
+        if (!path.startsWith("/")) {
+
+            Logger.error("File log streams need an absolute path")
+
+            return false
+
+        }
+
+        this.activeLogStreams.set(streamId, { type: "file" })
+
+        const payload: StartLogsPayload = {
+            streamId: streamId,
+            type: "file",
+            path: path
+        }
+
+        Logger.log(`Starting log stream: ${streamId} (file: ${path})`)
+
+        return this.emit("start-logs", payload)
+
+    }
+
+
+    /**
+     * Follow the output of a long-running command on the client, such as
+     * ping or a monitor script. The client only runs commands listed in its
+     * allowedCommands config and answers others with a permission_denied
+     * log error.
+     *
+     * @param streamId - Unique identifier for this log stream
+     * @param command - Command to run, matched exactly against the allowlist
+     * @param args - Arguments to pass to the command
+     * @returns true if the event was sent successfully
+     */
+    public startCommandLogStream(streamId: string, command: string, args: string[] = []): boolean {
+
+        this.activeLogStreams.set(streamId, { type: "command" })
+
+        const payload: StartLogsPayload = {
+            streamId: streamId,
+            type: "command",
+            command: command,
+            args: args
+        }
+
+        Logger.log(`Starting log stream: ${streamId} (command: ${command})`)
+
+        return this.emit("start-logs", payload)
+
+    }
+
+
     /**
      * Start a log stream on the client.
      * Use "journalctl" type for all system logs, "service" type with a service name,
      * "app" type for the user's Go app output, "cage" type for Cage/Cog compositor logs,
-     * or "early" type for best-effort early boot logs.
+     * "early" type for best-effort early boot logs, or "snapshot" type to read the
+     * journal once (optionally for one service) and stop at maxLines or its end.
      *
      * @param streamId - Unique identifier for this log stream
      * @param type - Type of log stream: "journalctl", "service", "app", or "cage"
      * @param service - Service name (required if type is "service")
      * @returns true if the event was sent successfully
      */
-    public startLogStream(streamId: string, type: "journalctl" | "service" | "app" | "cage" | "early", service?: string): boolean {
+    public startLogStream(streamId: string, type: "journalctl" | "service" | "app" | "cage" | "early" | "snapshot", service?: string): boolean {
 
         if (type === "service" && !service) {
 
@@ -729,6 +1192,57 @@ export class DevServer {
     }
 
 
+    /**
+     * Hold a log stream's lines on the client without stopping it. Command
+     * output backs up in its pipe until resumeLogStream.
+     *
+     * @param streamId - ID of the stream to pause
+     * @returns true if the event was sent successfully
+     */
+    public pauseLogStream(streamId: string): boolean {
+        const payload: StopLogsPayload = { streamId: streamId }
+        return this.emit("pause-logs", payload)
+    }
+
+
+    /**
+     * Continue delivering a paused log stream's lines.
+     *
+     * @param streamId - ID of the stream to resume
+     * @returns true if the event was sent successfully
+     */
+    public resumeLogStream(streamId: string): boolean {
+        const payload: StopLogsPayload = { streamId: streamId }
+        return this.emit("resume-logs", payload)
+    }
+
+
+    /**
+     * Ask the client for its active log streams and their stats. The answer
+     * arrives through onStreams.
+     */
+    public listLogStreams(): boolean {
+        return this.emit("list-streams")
+    }
+
+
+    /**
+     * Ask the client for a stream's most recent lines, oldest first. The
+     * answer arrives through onRecentLogs.
+     *
+     * @param streamId - ID of the stream to read
+     * @param count - How many lines to return; omit for all retained
+     * @returns true if the event was sent successfully
+     */
+    public getRecentLogs(streamId: string, count?: number): boolean {
+        const payload: RecentLogsRequestPayload = { streamId: streamId }
+        if (count !== undefined) {
+            payload.count = count
+        }
+        return this.emit("get-recent-logs", payload)
+    }
+
+
     /**
      * Stop all active log streams.
      */
@@ -766,16 +1280,36 @@ export class DevServer {
     }
 
 
+    /**
+     * Ask the client for its systemd services. The answer arrives through
+     * onUnits; code "no_systemd" means the device has none.
+     */
+    public listUnits(): boolean {
+        return this.emit("list-units")
+    }
+
+    /**
+     * Ask the client for a diagnostics archive of its journal, app and cage
+     * logs. It arrives through onDiagnostics, or is saved to the current
+     * directory when no handler is set.
+     */
+    public collectDiagnostics(options: CollectDiagnosticsPayload = {}): boolean {
+        return this.emit("collect-diagnostics", options)
+    }
+
     /**
      * Get the server port.
      */
     /**
-     * Start an interactive exec session on the client.
+     * Start an interactive exec session on the client. Pass the terminal's
+     * size so the shell starts at it instead of the 80x24 default.
      */
-    public startExecSession(sessionId: string, shell?: string): boolean {
+    public startExecSession(sessionId: string, shell?: string, size?: { cols: number; rows: number }): boolean {
         const payload: ExecStartPayload = {
             sessionId,
-            shell
+            shell,
+            cols: size?.cols,
+            rows: size?.rows
         }
 
         Logger.log(`Starting exec session: ${sessionId}`)
@@ -783,6 +1317,15 @@ export class DevServer {
         return this.emit("exec-start", payload)
     }
 
+    /**
+     * Ask the client which shells it has without starting one. The answer
+     * arrives through onExecShells; code "no_shell" means the terminal
+     * should be disabled.
+     */
+    public probeExecShells(): boolean {
+        return this.emit("exec-probe")
+    }
+
     /**
      * Send input to an interactive exec session.
      */
@@ -795,6 +1338,69 @@ export class DevServer {
         return this.emit("exec-input", payload)
     }
 
+    /**
+     * Send raw bytes to an interactive exec session, for control sequences
+     * or pastes that are not valid UTF-8.
+     */
+    public sendExecInputBytes(sessionId: string, data: Uint8Array): boolean {
+        const payload: ExecInputPayload = {
+            sessionId,
+            data: Buffer.from(data).toString("base64"),
+            encoding: "base64"
+        }
+
+        return this.emit("exec-input", payload)
+    }
+
+    /**
+     * Stop receiving output from an exec session, e.g. while the terminal is
+     * scrolled back. The shell keeps running; its output is held on the device
+     * and the shell blocks once the PTY buffer fills.
+     */
+    public pauseExecSession(sessionId: string): boolean {
+        const payload: ExecFlowPayload = { sessionId }
+
+        return this.emit("exec-pause", payload)
+    }
+
+    /**
+     * Resume output from a paused exec session, delivering what was held.
+     */
+    public resumeExecSession(sessionId: string): boolean {
+        const payload: ExecFlowPayload = { sessionId }
+
+        return this.emit("exec-resume", payload)
+    }
+
+    /**
+     * Open a channel to the app's IPC bridge through the client, sharing
+     * this connection with log streams and exec sessions.
+     */
+    public openIPCChannel(channelId: string): boolean {
+        const payload: IPCChannelPayload = { channelId }
+
+        return this.emit("ipc-open", payload)
+    }
+
+    /**
+     * Send a bridge message (e.g. { id, method, params }) on an IPC channel.
+     * Responses and events arrive through onIPCFrame.
+     */
+    public sendIPCFrame(channelId: string, frame: unknown): boolean {
+        const payload: IPCFramePayload = { channelId, frame }
+
+        return this.emit("ipc-frame", payload)
+    }
+
+    /**
+     * Close an IPC channel.
+     */
+    public closeIPCChannel(channelId: string): boolean {
+        const payload: IPCChannelPayload = { channelId }
+
+        return this.emit("ipc-close", payload)
+    }
+
     /**
      * Get the server port.
      */
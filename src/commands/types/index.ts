@@ -88,6 +88,8 @@ export interface GenerateTypesOptions {
   outputFilename?: string;
   // Path to introspection binary (optional, will use bundled binary if not provided)
   introspectBinaryPath?: string;
+  // Method name case (default: "go"); "camel" must match the app's runtime.UseCamelCase
+  methodCase?: "go" | "camel";
 }
 
 export interface GenerateTypesResult {
@@ -104,12 +106,13 @@ export interface GenerateTypesResult {
  */
 export async function runIntrospection(
     mainGoPath: string,
-    binaryPath?: string
+    binaryPath?: string,
+    methodCase: "go" | "camel" = "go"
 ): Promise<IntrospectionOutput> {
     const binary = binaryPath ?? await getIntrospectBinaryPath()
 
     try {
-        const result = await $`${binary} ${mainGoPath}`.quiet()
+        const result = await $`${binary} -case ${methodCase} ${mainGoPath}`.quiet()
 
         if (result.exitCode !== 0) {
             const stderr = result.stderr.toString()
@@ -235,7 +238,7 @@ export function generateTypeScriptDefinitions(
         if (structDef) {
             const block: string[] = [`interface ${structName} {`]
             for (const field of structDef.fields) {
-                block.push(`  ${field.name}: ${field.tsType};`)
+                block.push(...formatField(field))
             }
             block.push("}")
             appendInterfaceBlock(block)
@@ -246,7 +249,7 @@ export function generateTypeScriptDefinitions(
     const appBlock: string[] = [`interface ${app.name} {`]
 
     for (const field of app.fields) {
-        appBlock.push(`  ${field.name}: ${field.tsType};`)
+        appBlock.push(...formatField(field))
     }
 
     if (app.fields.length > 0 && app.methods.length > 0) {
@@ -300,6 +303,7 @@ export async function generateTypes(
         outputDir = join(dirname(mainGoPath), "frontend"),
         outputFilename = "strux.d.ts",
         introspectBinaryPath,
+        methodCase = "go",
     } = options
 
     try {
@@ -313,7 +317,7 @@ export async function generateTypes(
         }
 
         // Run introspection for user's app
-        const introspection = await runIntrospection(mainGoPath, introspectBinaryPath)
+        const introspection = await runIntrospection(mainGoPath, introspectBinaryPath, methodCase)
 
         // Get runtime types string from the already-generated file (not generated on the fly)
         const runtimeTypesString = getRuntimeTypesString()
@@ -345,10 +349,47 @@ export async function generateTypes(
 
 // Helper functions
 
+// A defaulted field is optional, its default documented in JSDoc
+function formatField(field: FieldDef): string[] {
+    const modifier = field.readonly ? "readonly " : ""
+    if (field.default === undefined) {
+        return [`  ${modifier}${field.name}: ${field.tsType};`]
+    }
+
+    const value = field.tsType === "string" ? JSON.stringify(field.default) : field.default
+    return [
+        `  /** @default ${value.replaceAll("*/", "*\\/")} */`,
+        `  ${modifier}${field.name}?: ${field.tsType};`,
+    ]
+}
+
+// Only trailing params can be optional, so a required param after an
+// optional one is reported rather than rendered as an invalid signature
 function formatMethodParams(method: MethodDef): string {
+    let optionalName: string | undefined
+
     return method.params
         .map((param, index) => {
             const name = param.name ?? `arg${index}`
+            const isLast = index === method.params.length - 1
+
+            if (param.variadic && !isLast) {
+                throw new Error(`${method.name}: variadic param ${name} must be last`)
+            }
+            if (param.optional || param.variadic) {
+                if (optionalName === undefined) {
+                    optionalName = name
+                }
+            } else if (optionalName !== undefined) {
+                throw new Error(`${method.name}: required param ${name} follows optional param ${optionalName}`)
+            }
+
+            if (param.variadic) {
+                return `...${name}: ${param.tsType}`
+            }
+            if (param.optional) {
+                return `${name}?: ${param.tsType}`
+            }
             return `${name}: ${param.tsType}`
         })
         .join(", ")
@@ -70,12 +70,18 @@ program.command("init")
 
 program.command("types")
     .description("Generate TypeScript type definitions from Go structs")
-    .action(async () => {
+    .option("--case <case>", "Method name case: go (GetItems) or camel (getItems)", "go")
+    .action(async (options: {case: "go" | "camel"}) => {
+        if (options.case !== "go" && options.case !== "camel") {
+            Logger.error(`--case must be "go" or "camel", got "${options.case}"`)
+            process.exit(1)
+        }
         const { generateTypes } = await import("./commands/types")
         const cwd = process.cwd()
         const result = await generateTypes({
             mainGoPath: `${cwd}/main.go`,
             outputDir: `${cwd}/frontend/src`,
+            methodCase: options.case,
         })
         if (result.success) {
             console.log(`Generated ${result.methodCount} methods, ${result.fieldCount} fields`)
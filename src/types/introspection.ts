@@ -19,6 +19,9 @@ export const FieldDefSchema = z.object({
     name: z.string(),
     goType: z.string(),
     tsType: z.string(),
+    readonly: z.boolean().optional(),
+    // From a strux:"default=..." tag; the field may be left out
+    default: z.string().optional(),
 })
 export type FieldDef = z.infer<typeof FieldDefSchema>;
 
@@ -27,6 +30,10 @@ export const ParamDefSchema = z.object({
     name: z.string().optional(),
     goType: z.string(),
     tsType: z.string(),
+    // A trailing pointer or variadic the caller may leave off
+    optional: z.boolean().optional(),
+    // Rendered as a rest parameter
+    variadic: z.boolean().optional(),
 })
 export type ParamDef = z.infer<typeof ParamDefSchema>;
 
@@ -59,6 +66,8 @@ export const ExtensionMethodSchema = z.object({
     name: z.string(),
     paramCount: z.number(),
     paramTypes: z.array(z.string()),
+    required: z.number().optional(),
+    variadic: z.boolean().optional(),
 })
 export type ExtensionMethod = z.infer<typeof ExtensionMethodSchema>;
 
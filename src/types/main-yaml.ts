@@ -68,6 +68,14 @@ const DevServerSchema = z.object({
     fallback_hosts: z.array(DevFallbackHostSchema).optional(),
     use_mdns_on_client: z.boolean(),
     client_key: z.string(),
+    // WebSocket ping interval and pong timeout in seconds (interval 0 disables)
+    keepalive_interval: z.number().int().nonnegative().optional(),
+    keepalive_timeout: z.number().int().positive().optional(),
+    // Seconds between device vitals heartbeats (0 disables); the device is
+    // marked unhealthy after three are missed
+    heartbeat_interval: z.number().int().nonnegative().optional(),
+    // Compress log and exec traffic with permessage-deflate
+    compression: z.boolean().optional(),
 })
 
 // WebKit Inspector configuration schema
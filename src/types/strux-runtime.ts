@@ -9,5 +9,11 @@ interface Strux {
     Reboot(): Promise<void>;
     Shutdown(): Promise<void>;
   };
+  display: {
+    SetCursorVisible(visible: boolean): Promise<void>;
+    SetBlankTimeout(seconds: number): Promise<void>;
+    Blank(): Promise<void>;
+    Wake(): Promise<void>;
+  };
 }
 `